@@ -0,0 +1,524 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+	emailChangeTTL       = 24 * time.Hour
+
+	authTokenPasswordReset     = "password_reset"
+	authTokenEmailVerification = "email_verification"
+	authTokenEmailChange       = "email_change"
+)
+
+// Reset and verification mails are capped per address and per IP so they can't be used to
+// flood an inbox
+var (
+	accountMailEmailLimit = loginLimit{Prefix: "mail:email:", FreeAttempts: 3, LockoutAt: 3, Lockout: time.Hour, Window: time.Hour}
+	accountMailIPLimit    = loginLimit{Prefix: "mail:ip:", FreeAttempts: 20, LockoutAt: 20, Lockout: time.Hour, Window: time.Hour}
+)
+
+var errAuthTokenInvalid = errors.New("token is invalid, expired or already used")
+
+// accountTables maps each user type to its account table and display name column, in the
+// order Login looks accounts up
+var accountTables = []struct {
+	UserType  string
+	Table     string
+	NameField string
+}{
+	{"archer", "archers", "full_name"},
+	{"organization", "organizations", "name"},
+	{"club", "clubs", "name"},
+	{"seller", "sellers", "store_name"},
+}
+
+// accountTable returns the table holding accounts of the user type
+func accountTable(userType string) (string, bool) {
+	for _, t := range accountTables {
+		if t.UserType == userType {
+			return t.Table, true
+		}
+	}
+	return "", false
+}
+
+// accountNameField returns the display name column of the user type's account table
+func accountNameField(userType string) string {
+	for _, t := range accountTables {
+		if t.UserType == userType {
+			return t.NameField
+		}
+	}
+	return "''"
+}
+
+// emailAccount is an account found by its email address
+type emailAccount struct {
+	UUID          string `db:"uuid"`
+	Email         string `db:"email"`
+	Name          string `db:"name"`
+	EmailVerified bool   `db:"email_verified"`
+	UserType      string `db:"-"`
+}
+
+// findAccountByEmail finds the account using the email, looking through the tables like Login
+func findAccountByEmail(db *sqlx.DB, email string) (*emailAccount, error) {
+	for _, t := range accountTables {
+		var account emailAccount
+		err := db.Get(&account, `
+			SELECT uuid, email, COALESCE(`+t.NameField+`, '') as name, email_verified_at IS NOT NULL as email_verified
+			FROM `+t.Table+` WHERE email = ? LIMIT 1
+		`, email)
+		if err == nil {
+			account.UserType = t.UserType
+			return &account, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// emailVerificationRequired reports whether Login refuses accounts with an unverified email
+func emailVerificationRequired() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// emailVerificationSince is the day the requirement was switched on (EMAIL_VERIFICATION_SINCE,
+// YYYY-MM-DD). Logins created before then were never asked to verify and keep working.
+func emailVerificationSince() (time.Time, bool) {
+	since, err := time.Parse("2006-01-02", os.Getenv("EMAIL_VERIFICATION_SINCE"))
+	return since, err == nil
+}
+
+// mustVerifyEmail reports whether Login refuses the identity until its email is verified
+func mustVerifyEmail(identity *models.Identity) bool {
+	if !emailVerificationRequired() || identity.EmailVerifiedAt != nil {
+		return false
+	}
+	since, ok := emailVerificationSince()
+	return !ok || !identity.CreatedAt.Before(since)
+}
+
+// issueAuthToken creates a single-use token for the purpose, replacing any earlier unused one
+func issueAuthToken(db *sqlx.DB, userID, userType, purpose, email string, ttl time.Duration) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE auth_tokens SET expires_at = NOW()
+		WHERE user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()
+	`, userID, purpose); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO auth_tokens (uuid, user_id, user_type, purpose, email, token_hash, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), userID, userType, purpose, email, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// authTokenOwner is the account a consumed token belongs to
+type authTokenOwner struct {
+	UserID   string `db:"user_id"`
+	UserType string `db:"user_type"`
+	Email    string `db:"email"`
+}
+
+// consumeAuthToken marks a token used and returns its owner. Expired, used and unknown tokens all
+// return errAuthTokenInvalid.
+func consumeAuthToken(tx *sqlx.Tx, token, purpose string) (*authTokenOwner, error) {
+	var row struct {
+		authTokenOwner
+		UUID string `db:"uuid"`
+	}
+	err := tx.Get(&row, `
+		SELECT uuid, user_id, user_type, email FROM auth_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`, hashToken(token), purpose)
+	if err == sql.ErrNoRows {
+		return nil, errAuthTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE auth_tokens SET used_at = NOW() WHERE uuid = ?", row.UUID); err != nil {
+		return nil, err
+	}
+	return &row.authTokenOwner, nil
+}
+
+// appLink builds a link into the web app, carrying the token when there is one
+func appLink(path, token string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "https://archeryhub.id"
+	}
+	if token == "" {
+		return base + path
+	}
+	return base + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmail issues an email verification token and mails the link
+func sendVerificationEmail(db *sqlx.DB, userID, userType, email, name string) error {
+	token, err := issueAuthToken(db, userID, userType, authTokenEmailVerification, email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return utils.SendMail(utils.Mail{
+		To:      email,
+		Subject: "Verifikasi email Archery Hub",
+		Body: "Halo " + name + ",\n\n" +
+			"Klik tautan berikut untuk memverifikasi alamat email Anda:\n" +
+			appLink("/auth/verify-email", token) + "\n\n" +
+			"Tautan berlaku selama 48 jam. Abaikan email ini jika Anda tidak mendaftar di Archery Hub.",
+	})
+}
+
+// sendPasswordResetEmail issues a password reset token and mails the link
+func sendPasswordResetEmail(db *sqlx.DB, account *emailAccount) error {
+	token, err := issueAuthToken(db, account.UUID, account.UserType, authTokenPasswordReset, account.Email, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return utils.SendMail(utils.Mail{
+		To:      account.Email,
+		Subject: "Atur ulang password Archery Hub",
+		Body: "Halo " + account.Name + ",\n\n" +
+			"Kami menerima permintaan untuk mengatur ulang password akun Anda. Klik tautan berikut:\n" +
+			appLink("/auth/reset-password", token) + "\n\n" +
+			"Tautan berlaku selama 1 jam dan hanya dapat digunakan sekali. Abaikan email ini jika Anda tidak memintanya.",
+	})
+}
+
+// throttleAccountMail counts a reset or verification mail against the IP and the address. A busy
+// IP gets a 429 (written here, ok is false); a busy address is skipped quietly (send is false) so
+// the answer doesn't reveal whether it exists.
+func throttleAccountMail(c *gin.Context, email string) (send bool, ok bool) {
+	store := utils.Attempts()
+	now := time.Now()
+
+	ipKey := accountMailIPLimit.Prefix + c.ClientIP()
+	if a := store.Get(ipKey); a.LockedUntil.After(now) {
+		wait := int(math.Ceil(a.LockedUntil.Sub(now).Seconds()))
+		c.Header("Retry-After", fmt.Sprint(wait))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Terlalu banyak permintaan. Coba lagi nanti.", "code": "too_many_requests", "retry_after": wait})
+		return false, false
+	}
+	accountMailIPLimit.fail(ipKey)
+
+	emailKey := accountMailEmailLimit.Prefix + strings.ToLower(strings.TrimSpace(email))
+	if store.Get(emailKey).LockedUntil.After(now) {
+		return false, true
+	}
+	accountMailEmailLimit.fail(emailKey)
+	return true, true
+}
+
+// requestEmailChange asks the current address to confirm moving the account to newEmail. The
+// change only happens once the link in that mail is followed (ConfirmEmailChange).
+func requestEmailChange(db *sqlx.DB, userID, userType, oldEmail, newEmail, name string) error {
+	token, err := issueAuthToken(db, userID, userType, authTokenEmailChange, newEmail, emailChangeTTL)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := utils.SendMail(utils.Mail{
+			To:      oldEmail,
+			Subject: "Konfirmasi perubahan email Archery Hub",
+			Body: "Halo " + name + ",\n\n" +
+				"Kami menerima permintaan untuk mengganti email akun Anda menjadi " + newEmail + ". Klik tautan berikut untuk menyetujuinya:\n" +
+				appLink("/auth/confirm-email-change", token) + "\n\n" +
+				"Tautan berlaku selama 24 jam. Jika Anda tidak memintanya, abaikan email ini dan segera ganti password Anda.",
+		}); err != nil {
+			log.Printf("[WARN] Failed to send email change confirmation to user %s: %v", userID, err)
+		}
+	}()
+	return nil
+}
+
+// ForgotPassword mails a password reset link. It answers the same whether or not the email
+// exists so accounts can't be enumerated.
+func ForgotPassword(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		send, ok := throttleAccountMail(c, req.Email)
+		if !ok {
+			return
+		}
+
+		// Looked up and sent in the background so the response time doesn't reveal the account
+		if send {
+			ip, userAgent := c.ClientIP(), c.Request.UserAgent()
+			go func() {
+				if account, err := findAccountByEmail(db, req.Email); err == nil {
+					if err := sendPasswordResetEmail(db, account); err == nil {
+						utils.LogActivity(db, account.UUID, "", "password_reset_requested", account.UserType, account.UUID,
+							"Password reset requested", ip, userAgent)
+					}
+				}
+			}()
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a reset link has been sent"})
+	}
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out everywhere
+func ResetPassword(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Token    string `json:"token" binding:"required"`
+			Password string `json:"password" binding:"required,min=6"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password baru harus minimal 6 karakter", "details": err.Error()})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		owner, err := consumeAuthToken(tx, req.Token, authTokenPasswordReset)
+		if err == errAuthTokenInvalid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token"})
+			return
+		}
+
+		table, ok := accountTable(owner.UserType)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errAuthTokenInvalid.Error(), "code": "invalid_token"})
+			return
+		}
+
+		// Following the emailed link proves the address as well (store as plain text like UpdatePassword)
+		if _, err := tx.Exec(`
+			UPDATE `+table+` SET password = ?, email_verified_at = COALESCE(email_verified_at, IF(email = ?, NOW(), NULL)), updated_at = NOW()
+			WHERE uuid = ?
+		`, req.Password, owner.Email, owner.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+			return
+		}
+		if identityID := identityOf(tx, owner.UserID); identityID != "" {
+			if _, err := tx.Exec(`
+				UPDATE identities SET password = ?, email_verified_at = COALESCE(email_verified_at, IF(email = ?, NOW(), NULL)), updated_at = NOW()
+				WHERE uuid = ?
+			`, req.Password, owner.Email, identityID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit password reset"})
+			return
+		}
+
+		revokeUserSessions(db, owner.UserID, "password_reset")
+		utils.LogActivity(db, owner.UserID, "", "password_reset", owner.UserType, owner.UserID,
+			"Password reset by email link", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password berhasil diatur ulang. Silakan login kembali."})
+	}
+}
+
+// VerifyEmail marks the account's email as verified with a verification token. The token only
+// counts for the address it was sent to.
+func VerifyEmail(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		owner, err := consumeAuthToken(tx, req.Token, authTokenEmailVerification)
+		if err == errAuthTokenInvalid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token"})
+			return
+		}
+
+		table, ok := accountTable(owner.UserType)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errAuthTokenInvalid.Error(), "code": "invalid_token"})
+			return
+		}
+
+		result, err := tx.Exec(`
+			UPDATE `+table+` SET email_verified_at = COALESCE(email_verified_at, NOW())
+			WHERE uuid = ? AND email = ?
+		`, owner.UserID, owner.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The email address changed since this link was sent", "code": "email_changed"})
+			return
+		}
+		if identityID := identityOf(tx, owner.UserID); identityID != "" {
+			tx.Exec("UPDATE identities SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE uuid = ? AND email = ?", identityID, owner.Email)
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit verification"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email berhasil diverifikasi"})
+	}
+}
+
+// ResendVerificationEmail mails a new verification link. It works without a session so users
+// locked out by the verification requirement can ask for one, and never reveals whether the
+// email exists.
+func ResendVerificationEmail(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		send, ok := throttleAccountMail(c, req.Email)
+		if !ok {
+			return
+		}
+		if send {
+			go func() {
+				if account, err := findAccountByEmail(db, req.Email); err == nil && !account.EmailVerified {
+					sendVerificationEmail(db, account.UUID, account.UserType, account.Email, account.Name)
+				}
+			}()
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "If the email is registered and unverified, a verification link has been sent"})
+	}
+}
+
+// ConfirmEmailChange moves the account to the new address once the old address approved it. The
+// new address still has to be verified before it counts.
+func ConfirmEmailChange(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		owner, err := consumeAuthToken(tx, req.Token, authTokenEmailChange)
+		if err == errAuthTokenInvalid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token"})
+			return
+		}
+
+		table, ok := accountTable(owner.UserType)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errAuthTokenInvalid.Error(), "code": "invalid_token"})
+			return
+		}
+		if identityEmailTaken(tx, owner.UserID, owner.Email) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email is already used by another account"})
+			return
+		}
+
+		if _, err := tx.Exec("UPDATE "+table+" SET email = ?, email_verified_at = NULL, updated_at = NOW() WHERE uuid = ?", owner.Email, owner.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email"})
+			return
+		}
+		if err := syncIdentityEmail(tx, owner.UserID, owner.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update login email"})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit email change"})
+			return
+		}
+
+		var name string
+		db.Get(&name, "SELECT COALESCE("+accountNameField(owner.UserType)+", '') FROM "+table+" WHERE uuid = ?", owner.UserID)
+		go sendVerificationEmail(db, owner.UserID, owner.UserType, owner.Email, name)
+
+		utils.LogActivity(db, owner.UserID, "", "email_changed", owner.UserType, owner.UserID,
+			"Email changed to "+owner.Email, c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Email berhasil diganti. Silakan verifikasi alamat email baru Anda."})
+	}
+}
@@ -0,0 +1,406 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// sessionScoreSheet is a participant's complete scores for one qualification session
+type sessionScoreSheet struct {
+	ArcherID      string
+	EventID       string
+	BowTypeID     *string
+	TotalEnds     int
+	ArrowsPerEnd  int
+	Distance      *int
+	Total         int
+	EndsCompleted int
+	Ends          [][]sessionArrow
+}
+
+// sessionArrow is one arrow of a score sheet
+type sessionArrow struct {
+	Score int
+	IsX   bool
+}
+
+// loadSessionScoreSheet reads a participant's ends and arrows for a session in shooting order
+func loadSessionScoreSheet(db *sqlx.DB, sessionUUID, participantUUID string) (*sessionScoreSheet, error) {
+	var head struct {
+		ArcherID     string  `db:"archer_id"`
+		EventID      string  `db:"event_id"`
+		BowTypeID    *string `db:"bow_type_id"`
+		TotalEnds    int     `db:"total_ends"`
+		ArrowsPerEnd int     `db:"arrows_per_end"`
+		Distance     *int    `db:"distance"`
+	}
+	err := db.Get(&head, `
+		SELECT ep.archer_id, ep.event_id, ec.division_uuid as bow_type_id, qs.total_ends, qs.arrows_per_end, qs.distance
+		FROM event_participants ep
+		JOIN qualification_sessions qs ON qs.uuid = ?
+		LEFT JOIN event_categories ec ON ep.category_id = ec.uuid
+		WHERE ep.uuid = ?
+	`, sessionUUID, participantUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		EndNumber   int  `db:"end_number"`
+		EndTotal    int  `db:"total_score_end"`
+		ArrowNumber *int `db:"arrow_number"`
+		Score       *int `db:"score"`
+		IsX         *int `db:"is_x"`
+	}
+	err = db.Select(&rows, `
+		SELECT qes.end_number, qes.total_score_end, qas.arrow_number, qas.score, qas.is_x
+		FROM qualification_end_scores qes
+		LEFT JOIN qualification_arrow_scores qas ON qas.end_score_uuid = qes.uuid
+		WHERE qes.session_uuid = ? AND qes.participant_uuid = ?
+		ORDER BY qes.end_number ASC, qas.arrow_number ASC
+	`, sessionUUID, participantUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &sessionScoreSheet{
+		ArcherID:     head.ArcherID,
+		EventID:      head.EventID,
+		BowTypeID:    head.BowTypeID,
+		TotalEnds:    head.TotalEnds,
+		ArrowsPerEnd: head.ArrowsPerEnd,
+		Distance:     head.Distance,
+	}
+	lastEnd := -1
+	for _, r := range rows {
+		if r.EndNumber != lastEnd {
+			sheet.Ends = append(sheet.Ends, []sessionArrow{})
+			sheet.Total += r.EndTotal
+			lastEnd = r.EndNumber
+		}
+		if r.Score != nil {
+			i := len(sheet.Ends) - 1
+			sheet.Ends[i] = append(sheet.Ends[i], sessionArrow{Score: *r.Score, IsX: r.IsX != nil && *r.IsX == 1})
+		}
+	}
+
+	// An end only counts once every arrow of it is scored; blank arrows aren't stored
+	for _, end := range sheet.Ends {
+		if len(end) >= sheet.ArrowsPerEnd {
+			sheet.EndsCompleted++
+		}
+	}
+	return sheet, nil
+}
+
+// evaluateAchievementRule checks a rule against a finished score sheet and returns the evidence
+// when it's met
+func evaluateAchievementRule(rule models.AchievementRule, sheet *sessionScoreSheet) (map[string]interface{}, bool) {
+	if rule.TotalArrows != nil && *rule.TotalArrows != sheet.TotalEnds*sheet.ArrowsPerEnd {
+		return nil, false
+	}
+	if rule.Distance != nil && (sheet.Distance == nil || *rule.Distance != *sheet.Distance) {
+		return nil, false
+	}
+	if rule.BowTypeID != nil && (sheet.BowTypeID == nil || *rule.BowTypeID != *sheet.BowTypeID) {
+		return nil, false
+	}
+
+	switch rule.RuleType {
+	case "total_score":
+		if sheet.Total >= rule.Threshold {
+			return map[string]interface{}{"score": sheet.Total}, true
+		}
+	case "perfect_end":
+		minValue := 10
+		if rule.ArrowValue != nil {
+			minValue = *rule.ArrowValue
+		}
+		for i, end := range sheet.Ends {
+			if len(end) < rule.Threshold {
+				continue
+			}
+			perfect := true
+			for _, a := range end {
+				if a.Score < minValue {
+					perfect = false
+					break
+				}
+			}
+			if perfect {
+				return map[string]interface{}{"end_number": i + 1, "arrows": len(end)}, true
+			}
+		}
+	case "consecutive_x":
+		run := 0
+		for i, end := range sheet.Ends {
+			for j, a := range end {
+				if !a.IsX {
+					run = 0
+					continue
+				}
+				run++
+				if run >= rule.Threshold {
+					return map[string]interface{}{"end_number": i + 1, "arrow_number": j + 1, "run": run}, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// evaluateSessionAchievements awards every active badge the participant earned in a session.
+// Only finished sessions (all ends scored) are evaluated, and a badge is awarded once per archer.
+func evaluateSessionAchievements(db *sqlx.DB, sessionUUID, participantUUID string) ([]models.AchievementRule, error) {
+	sheet, err := loadSessionScoreSheet(db, sessionUUID, participantUUID)
+	if err != nil {
+		return nil, err
+	}
+	if sheet.TotalEnds == 0 || sheet.EndsCompleted < sheet.TotalEnds {
+		return nil, nil
+	}
+
+	// Saving the last end twice can evaluate the session concurrently. Locking the archer makes
+	// the second evaluation wait and then see the badges the first one awarded.
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked string
+	if err := tx.Get(&locked, "SELECT uuid FROM archers WHERE uuid = ? FOR UPDATE", sheet.ArcherID); err != nil {
+		return nil, err
+	}
+
+	var rules []models.AchievementRule
+	err = tx.Select(&rules, `
+		SELECT * FROM achievement_rules
+		WHERE status = 'active'
+			AND uuid NOT IN (SELECT rule_id FROM archer_achievements WHERE archer_id = ?)
+	`, sheet.ArcherID)
+	if err != nil {
+		return nil, err
+	}
+
+	awarded := []models.AchievementRule{}
+	for _, rule := range rules {
+		evidence, ok := evaluateAchievementRule(rule, sheet)
+		if !ok {
+			continue
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO archer_achievements (uuid, archer_id, rule_id, event_id, session_id, participant_id, evidence)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), sheet.ArcherID, rule.UUID, sheet.EventID, sessionUUID, participantUUID, models.ToJSON(evidence))
+		if err != nil {
+			return nil, err
+		}
+		awarded = append(awarded, rule)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range awarded {
+		utils.Notify(db, sheet.ArcherID, "archer", "success", "Pencapaian baru: "+rule.Name,
+			fmt.Sprintf("Selamat! Anda mendapatkan lencana %s.", rule.Name), "/archers/"+sheet.ArcherID+"/achievements")
+	}
+	return awarded, nil
+}
+
+// GetAchievementRules lists the badges archers can earn with how many archers hold each.
+// Admins see inactive rules too with ?all=true.
+func GetAchievementRules(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := `
+			SELECT r.*, (SELECT COUNT(*) FROM archer_achievements aa WHERE aa.rule_id = r.uuid) as holder_count
+			FROM achievement_rules r
+		`
+		role, _ := c.Get("role")
+		if !(role == "admin" && c.Query("all") == "true") {
+			query += " WHERE r.status = 'active'"
+		}
+		query += " ORDER BY r.rule_type, r.threshold"
+
+		var rules []struct {
+			models.AchievementRule
+			HolderCount int `json:"holder_count" db:"holder_count"`
+		}
+		if err := db.Select(&rules, query); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch achievements"})
+			return
+		}
+
+		if rules == nil {
+			rules = []struct {
+				models.AchievementRule
+				HolderCount int `json:"holder_count" db:"holder_count"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": rules})
+	}
+}
+
+// CreateAchievementRule adds a badge rule
+func CreateAchievementRule(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AchievementRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Status == "" {
+			req.Status = "active"
+		}
+
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM achievement_rules WHERE code = ?)", req.Code)
+		if exists {
+			c.JSON(http.StatusConflict, gin.H{"error": "Achievement code already exists"})
+			return
+		}
+
+		ruleID := uuid.New().String()
+		_, err := db.Exec(`
+			INSERT INTO achievement_rules (uuid, code, name, description, icon_url, rule_type, threshold, arrow_value, total_arrows, distance, bow_type_id, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, ruleID, req.Code, req.Name, req.Description, req.IconURL, req.RuleType, req.Threshold, req.ArrowValue,
+			req.TotalArrows, req.Distance, req.BowTypeID, req.Status)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create achievement"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Achievement created", "id": ruleID})
+	}
+}
+
+// UpdateAchievementRule changes a badge rule. Badges already awarded are kept.
+func UpdateAchievementRule(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ruleID := c.Param("ruleId")
+
+		var req models.AchievementRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Status == "" {
+			req.Status = "active"
+		}
+
+		result, err := db.Exec(`
+			UPDATE achievement_rules
+			SET code = ?, name = ?, description = ?, icon_url = ?, rule_type = ?, threshold = ?, arrow_value = ?,
+				total_arrows = ?, distance = ?, bow_type_id = ?, status = ?, updated_at = NOW()
+			WHERE uuid = ?
+		`, req.Code, req.Name, req.Description, req.IconURL, req.RuleType, req.Threshold, req.ArrowValue,
+			req.TotalArrows, req.Distance, req.BowTypeID, req.Status, ruleID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update achievement"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Achievement not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Achievement updated"})
+	}
+}
+
+// DeleteAchievementRule deactivates a badge rule so it stops being awarded, keeping earned badges
+func DeleteAchievementRule(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, err := db.Exec("UPDATE achievement_rules SET status = 'inactive', updated_at = NOW() WHERE uuid = ?", c.Param("ruleId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate achievement"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Achievement deactivated"})
+	}
+}
+
+// EvaluateSessionAchievements re-runs the achievement rules for every participant of a session,
+// e.g. after new rules were added
+func EvaluateSessionAchievements(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionId")
+
+		var eventID string
+		if err := db.Get(&eventID, "SELECT event_uuid FROM qualification_sessions WHERE uuid = ?", sessionID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		if _, ok := loadManagedEvent(db, c, eventID); !ok {
+			return
+		}
+
+		var participantIDs []string
+		db.Select(&participantIDs, "SELECT DISTINCT participant_uuid FROM qualification_target_assignments WHERE session_uuid = ?", sessionID)
+
+		awarded := 0
+		for _, participantID := range participantIDs {
+			rules, err := evaluateSessionAchievements(db, sessionID, participantID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate achievements", "details": err.Error()})
+				return
+			}
+			awarded += len(rules)
+		}
+
+		logEventActivity(db, c, eventID, "achievements_evaluated", "qualification_session", sessionID,
+			fmt.Sprintf("Evaluated achievements for %d participants, %d awarded", len(participantIDs), awarded), nil, nil)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Achievements evaluated", "participants": len(participantIDs), "awarded": awarded})
+	}
+}
+
+// archerAchievements returns the badges an archer earned, newest first
+func archerAchievements(db *sqlx.DB, archerID string) ([]models.ArcherAchievementDetail, error) {
+	var achievements []models.ArcherAchievementDetail
+	err := db.Select(&achievements, `
+		SELECT aa.*, r.code, r.name, r.icon_url, e.name as event_name, e.slug as event_slug
+		FROM archer_achievements aa
+		JOIN achievement_rules r ON aa.rule_id = r.uuid
+		JOIN events e ON aa.event_id = e.uuid
+		WHERE aa.archer_id = ?
+		ORDER BY aa.awarded_at DESC
+	`, archerID)
+	if achievements == nil {
+		achievements = []models.ArcherAchievementDetail{}
+	}
+	return achievements, err
+}
+
+// GetArcherAchievements returns the badges shown on an archer's profile
+func GetArcherAchievements(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var archerID string
+		err := db.Get(&archerID, "SELECT uuid FROM archers WHERE uuid = ? OR username = ? OR (id != '' AND id = ?) LIMIT 1", id, id, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archer not found"})
+			return
+		}
+
+		achievements, err := archerAchievements(db, archerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch achievements"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": achievements})
+	}
+}
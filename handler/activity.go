@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// activityIgnoredFields change on every write and would only clutter the before/after payloads
+var activityIgnoredFields = []string{"updated_at"}
+
+// activitySnapshot reads the first row of the query for a before/after payload, or nil when
+// there is none
+func activitySnapshot(db *sqlx.DB, query string, args ...interface{}) map[string]interface{} {
+	rows, err := exportRows(db, query, args...)
+	if err != nil || len(rows) == 0 {
+		return nil
+	}
+	return rows[0]
+}
+
+// activityDiff keeps only the fields whose value changed between two snapshots
+func activityDiff(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	for _, k := range activityIgnoredFields {
+		delete(before, k)
+		delete(after, k)
+	}
+	for k, v := range before {
+		if w, ok := after[k]; ok && reflect.DeepEqual(v, w) {
+			delete(before, k)
+			delete(after, k)
+		}
+	}
+	return before, after
+}
+
+// logEventActivity records a change to an event's data on the event's timeline. before and after
+// describe the entity; pass nil for the side that doesn't exist (creation, deletion). When both
+// are snapshots, only the fields that changed are kept. Changes made with an API key are marked
+// as such.
+func logEventActivity(db *sqlx.DB, c *gin.Context, eventID, action, entityType, entityID, description string, before, after interface{}) {
+	b, bok := before.(map[string]interface{})
+	a, aok := after.(map[string]interface{})
+	if bok && aok && b != nil && a != nil {
+		before, after = activityDiff(b, a)
+	}
+	if key := requestAPIKey(c); key != nil {
+		description += " (API key " + key.KeyID + ")"
+	}
+
+	utils.RecordActivity(db, utils.Activity{
+		UserID:      c.GetString("user_id"),
+		EventID:     eventID,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Description: description,
+		Before:      before,
+		After:       after,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+	})
+}
+
+// queryActivityLogs answers an activity log listing: the rows matching where plus the
+// user_id, action, entity_type, entity_id, from and to query filters, newest first, with
+// limit/offset paging
+func queryActivityLogs(db *sqlx.DB, c *gin.Context, where string, args []interface{}) {
+	for _, f := range []string{"user_id", "action", "entity_type", "entity_id"} {
+		if v := c.Query(f); v != "" {
+			where += " AND l." + f + " = ?"
+			args = append(args, v)
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		where += " AND l.created_at >= ?"
+		args = append(args, from)
+	}
+	if to := c.Query("to"); to != "" {
+		where += " AND l.created_at < ?"
+		args = append(args, to)
+	}
+
+	var total int
+	db.Get(&total, "SELECT COUNT(*) FROM activity_logs l "+where, args...)
+
+	limit, offset := listPage(c)
+	var logs []models.ActivityLog
+	err := db.Select(&logs, `
+		SELECT l.id, l.user_id, l.event_id, l.action, l.entity_type, l.entity_id, l.description,
+		       l.before_data, l.after_data, l.ip_address, l.user_agent, l.created_at,
+		       COALESCE(a.full_name, o.name, cl.name, s.store_name) as actor_name
+		FROM activity_logs l
+		LEFT JOIN archers a ON a.uuid = l.user_id
+		LEFT JOIN organizations o ON o.uuid = l.user_id
+		LEFT JOIN clubs cl ON cl.uuid = l.user_id
+		LEFT JOIN sellers s ON s.uuid = l.user_id
+		`+where+`
+		ORDER BY l.created_at DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity logs"})
+		return
+	}
+
+	for i := range logs {
+		logs[i].DecodePayloads()
+	}
+	if logs == nil {
+		logs = []models.ActivityLog{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": logs, "total": total, "limit": limit, "offset": offset})
+}
+
+// GetEventActivity returns the event's audit timeline: who changed what on the event, its
+// participants, categories, targets, sessions, brackets and scores, and from where
+func GetEventActivity(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+		queryActivityLogs(db, c, "WHERE l.event_id = ?", []interface{}{eventID})
+	}
+}
+
+// rowSnapshot reads the row of the table with the uuid for a before/after payload
+func rowSnapshot(db *sqlx.DB, table, id string) map[string]interface{} {
+	return activitySnapshot(db, "SELECT * FROM "+table+" WHERE uuid = ?", id)
+}
@@ -0,0 +1,522 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// adminRefTables maps the reference data kinds admins manage to their tables
+var adminRefTables = map[string]string{
+	"bow-types":        "ref_bow_types",
+	"age-groups":       "ref_age_groups",
+	"disciplines":      "ref_disciplines",
+	"event-types":      "ref_event_types",
+	"gender-divisions": "ref_gender_divisions",
+}
+
+// auditAdmin records an admin action in the audit log and the activity log
+func auditAdmin(db *sqlx.DB, c *gin.Context, action, targetType, targetID, reason string, payload interface{}) {
+	adminID := c.GetString("user_id")
+	var reasonArg, payloadArg interface{}
+	if reason != "" {
+		reasonArg = reason
+	}
+	if payload != nil {
+		payloadArg = models.ToJSON(payload)
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	db.Exec(`
+		INSERT INTO admin_audit_logs (uuid, admin_id, action, target_type, target_id, reason, payload, ip_address, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), adminID, action, targetType, targetID, reasonArg, payloadArg, c.ClientIP(), userAgent)
+
+	description := "Admin: " + action
+	if reason != "" {
+		description += " (" + reason + ")"
+	}
+	// Moderation of an event also shows on the event's own timeline
+	var eventID string
+	if targetType == "event" {
+		eventID = targetID
+	}
+	utils.LogActivity(db, adminID, eventID, "admin_"+action, targetType, targetID, description, c.ClientIP(), c.Request.UserAgent())
+}
+
+// profileType finds which kind of account a profile id belongs to
+func profileType(db *sqlx.DB, profileID string) string {
+	var userType string
+	if err := db.Get(&userType, "SELECT user_type FROM identity_profiles WHERE profile_id = ? LIMIT 1", profileID); err == nil {
+		return userType
+	}
+	for _, t := range accountTables {
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM "+t.Table+" WHERE uuid = ?)", profileID)
+		if exists {
+			return t.UserType
+		}
+	}
+	return ""
+}
+
+// PromoteAdminsFromEnv grants platform admin rights to the logins listed in ADMIN_EMAILS
+// (comma separated), so a fresh installation has someone who can use the back office
+func PromoteAdminsFromEnv(db *sqlx.DB) {
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+
+		if _, err := findIdentity(db, email, ""); err == sql.ErrNoRows {
+			if account, aerr := findAccountByEmail(db, email); aerr == nil {
+				ensureIdentityForProfile(db, account.UserType, account.UUID)
+			}
+		}
+
+		result, err := db.Exec("UPDATE identities SET platform_role = 'admin', updated_at = NOW() WHERE email = ? AND platform_role IS NULL", email)
+		if err != nil {
+			log.Printf("[admin] failed to promote %s: %v", email, err)
+			continue
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			log.Printf("[admin] granted platform admin to %s", email)
+		}
+	}
+}
+
+// setAccountStatus suspends or reinstates an account and returns false after writing the error
+func setAccountStatus(db *sqlx.DB, c *gin.Context, status string) (string, string, bool) {
+	userType := c.Param("type")
+	profileID := c.Param("profileId")
+	table, ok := accountTable(userType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user type"})
+		return "", "", false
+	}
+
+	result, err := db.Exec("UPDATE "+table+" SET status = ?, updated_at = NOW() WHERE uuid = ?", status, profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+		return "", "", false
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM "+table+" WHERE uuid = ?)", profileID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return "", "", false
+		}
+	}
+	return userType, profileID, true
+}
+
+// SuspendAccount blocks an account and signs it out everywhere
+func SuspendAccount(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AdminReasonRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Param("profileId") == c.GetString("user_id") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You can't suspend your own account"})
+			return
+		}
+
+		userType, profileID, ok := setAccountStatus(db, c, "suspended")
+		if !ok {
+			return
+		}
+		revoked, _ := revokeUserSessions(db, profileID, "suspended")
+
+		auditAdmin(db, c, "account_suspended", userType, profileID, req.Reason, gin.H{"sessions_revoked": revoked})
+		utils.SendMail(utils.Mail{
+			To:      accountEmail(db, userType, profileID),
+			Subject: "Akun Archery Hub ditangguhkan",
+			Body:    "Halo,\n\nAkun Anda telah ditangguhkan oleh tim Archery Hub dengan alasan berikut:\n\n" + req.Reason + "\n\nHubungi kami jika menurut Anda ini keliru.",
+		})
+
+		c.JSON(http.StatusOK, gin.H{"message": "Account suspended", "sessions_revoked": revoked})
+	}
+}
+
+// ReinstateAccount lifts a suspension
+func ReinstateAccount(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AdminReasonRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userType, profileID, ok := setAccountStatus(db, c, "active")
+		if !ok {
+			return
+		}
+
+		auditAdmin(db, c, "account_reinstated", userType, profileID, req.Reason, nil)
+		utils.Notify(db, profileID, userType, "success", "Akun diaktifkan kembali",
+			"Penangguhan akun Anda telah dicabut.", "/dashboard")
+
+		c.JSON(http.StatusOK, gin.H{"message": "Account reinstated"})
+	}
+}
+
+// accountEmail returns the email of an account, or "" when it has none
+func accountEmail(db *sqlx.DB, userType, profileID string) string {
+	table, ok := accountTable(userType)
+	if !ok {
+		return ""
+	}
+	var email string
+	db.Get(&email, "SELECT COALESCE(email, '') FROM "+table+" WHERE uuid = ?", profileID)
+	return email
+}
+
+// AdminUnpublishEvent takes an event off the public listings by moving it back to draft
+func AdminUnpublishEvent(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AdminReasonRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var event struct {
+			UUID        string  `db:"uuid"`
+			Name        string  `db:"name"`
+			Status      string  `db:"status"`
+			OrganizerID *string `db:"organizer_id"`
+		}
+		if err := db.Get(&event, "SELECT uuid, name, status, organizer_id FROM events WHERE uuid = ? OR slug = ?", c.Param("id"), c.Param("id")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+
+		if _, err := db.Exec("UPDATE events SET status = 'draft', updated_at = NOW() WHERE uuid = ?", event.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish event"})
+			return
+		}
+
+		auditAdmin(db, c, "event_unpublished", "event", event.UUID, req.Reason, gin.H{"previous_status": event.Status})
+		if event.OrganizerID != nil {
+			utils.Notify(db, *event.OrganizerID, profileType(db, *event.OrganizerID), "danger", "Event diturunkan",
+				"Event "+event.Name+" diturunkan dari publikasi oleh tim Archery Hub: "+req.Reason, "/events/"+event.UUID)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Event unpublished"})
+	}
+}
+
+// AdminUnpublishNews takes a news article off the public pages by moving it back to draft
+func AdminUnpublishNews(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AdminReasonRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var article struct {
+			UUID           string  `db:"uuid"`
+			Title          string  `db:"title"`
+			Status         string  `db:"status"`
+			OrganizationID *string `db:"organization_id"`
+			ClubID         *string `db:"club_id"`
+		}
+		if err := db.Get(&article, "SELECT uuid, title, status, organization_id, club_id FROM news WHERE uuid = ? OR slug = ?", c.Param("id"), c.Param("id")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "News not found"})
+			return
+		}
+
+		if _, err := db.Exec("UPDATE news SET status = 'draft', updated_at = NOW() WHERE uuid = ?", article.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish news"})
+			return
+		}
+
+		auditAdmin(db, c, "news_unpublished", "news", article.UUID, req.Reason, gin.H{"previous_status": article.Status})
+		message := "Berita \"" + article.Title + "\" diturunkan dari publikasi oleh tim Archery Hub: " + req.Reason
+		if article.OrganizationID != nil {
+			utils.Notify(db, *article.OrganizationID, "organization", "danger", "Berita diturunkan", message, "/news")
+		}
+		if article.ClubID != nil {
+			utils.Notify(db, *article.ClubID, "club", "danger", "Berita diturunkan", message, "/news")
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "News unpublished"})
+	}
+}
+
+// CreateRefData adds a reference data entry (bow type, age group, ...)
+func CreateRefData(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		table, ok := adminRefTables[c.Param("kind")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown reference data"})
+			return
+		}
+
+		var req models.AdminRefDataRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		id := uuid.New().String()
+		if _, err := db.Exec("INSERT INTO "+table+" (uuid, code, name) VALUES (?, ?, ?)", id, req.Code, req.Name); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Failed to create entry; the code may already exist", "details": err.Error()})
+			return
+		}
+
+		auditAdmin(db, c, "reference_created", c.Param("kind"), id, "", req)
+
+		c.JSON(http.StatusCreated, gin.H{"data": RefData{UUID: id, Code: req.Code, Name: req.Name}})
+	}
+}
+
+// UpdateRefData renames or recodes a reference data entry
+func UpdateRefData(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		table, ok := adminRefTables[c.Param("kind")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown reference data"})
+			return
+		}
+
+		var req models.AdminRefDataRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var before RefData
+		if err := db.Get(&before, "SELECT uuid, code, name FROM "+table+" WHERE uuid = ?", c.Param("refId")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+			return
+		}
+
+		if _, err := db.Exec("UPDATE "+table+" SET code = ?, name = ? WHERE uuid = ?", req.Code, req.Name, before.UUID); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Failed to update entry; the code may already exist", "details": err.Error()})
+			return
+		}
+
+		auditAdmin(db, c, "reference_updated", c.Param("kind"), before.UUID, "", gin.H{"before": before, "after": req})
+
+		c.JSON(http.StatusOK, gin.H{"data": RefData{UUID: before.UUID, Code: req.Code, Name: req.Name}})
+	}
+}
+
+// DeleteRefData removes a reference data entry that nothing uses
+func DeleteRefData(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		table, ok := adminRefTables[c.Param("kind")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown reference data"})
+			return
+		}
+
+		var before RefData
+		if err := db.Get(&before, "SELECT uuid, code, name FROM "+table+" WHERE uuid = ?", c.Param("refId")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+			return
+		}
+
+		if _, err := db.Exec("DELETE FROM "+table+" WHERE uuid = ?", before.UUID); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Entry is still in use", "details": err.Error()})
+			return
+		}
+
+		auditAdmin(db, c, "reference_deleted", c.Param("kind"), before.UUID, "", before)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Entry deleted"})
+	}
+}
+
+// listPage reads limit/offset with the defaults of the log listings
+func listPage(c *gin.Context) (int, int) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// GetAdminActivityLogs browses the platform activity log, newest first
+func GetAdminActivityLogs(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		where := "WHERE 1=1"
+		var args []interface{}
+		if eventID := c.Query("event_id"); eventID != "" {
+			where += " AND l.event_id = ?"
+			args = append(args, eventID)
+		}
+		queryActivityLogs(db, c, where, args)
+	}
+}
+
+// GetAdminAuditLogs lists what admins did, newest first
+func GetAdminAuditLogs(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		where := "WHERE 1=1"
+		var args []interface{}
+		for _, f := range []string{"admin_id", "action", "target_type", "target_id"} {
+			if v := c.Query(f); v != "" {
+				where += " AND l." + f + " = ?"
+				args = append(args, v)
+			}
+		}
+
+		var total int
+		db.Get(&total, "SELECT COUNT(*) FROM admin_audit_logs l "+where, args...)
+
+		limit, offset := listPage(c)
+		var logs []models.AdminAuditLog
+		err := db.Select(&logs, `
+			SELECT l.*, COALESCE(a.full_name, o.name, cl.name, s.store_name) as admin_name
+			FROM admin_audit_logs l
+			LEFT JOIN archers a ON a.uuid = l.admin_id
+			LEFT JOIN organizations o ON o.uuid = l.admin_id
+			LEFT JOIN clubs cl ON cl.uuid = l.admin_id
+			LEFT JOIN sellers s ON s.uuid = l.admin_id
+			`+where+`
+			ORDER BY l.created_at DESC
+			LIMIT ? OFFSET ?
+		`, append(args, limit, offset)...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+			return
+		}
+
+		if logs == nil {
+			logs = []models.AdminAuditLog{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": logs, "total": total, "limit": limit, "offset": offset})
+	}
+}
+
+// ImpersonateUser starts a short support session as another user's profile. The tokens are only
+// returned in the body, so the admin's own cookies stay intact; the session records who started
+// it and can't change the user's credentials.
+func ImpersonateUser(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ImpersonateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if c.GetString("impersonated_by") != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "End the current impersonation first"})
+			return
+		}
+
+		identityID, err := ensureIdentityForProfile(db, req.UserType, req.ProfileID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found or has no login"})
+			return
+		}
+		identity, err := loadIdentity(db, identityID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found or has no login"})
+			return
+		}
+		if sessionRole(identity, req.UserType) == "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admins can't be impersonated"})
+			return
+		}
+
+		profiles, err := identityProfiles(db, identityID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+			return
+		}
+		var profile *models.IdentityProfile
+		for i := range profiles {
+			if profiles[i].ProfileID == req.ProfileID {
+				profile = &profiles[i]
+				break
+			}
+		}
+		if profile == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found or has no login"})
+			return
+		}
+
+		session := profileSession(identity, *profile)
+		session.ImpersonatorID = c.GetString("user_id")
+		tokens, err := issueSession(db, c, session)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		auditAdmin(db, c, "impersonation_started", req.UserType, req.ProfileID, req.Reason, gin.H{"session_id": tokens.SessionID})
+
+		c.JSON(http.StatusOK, gin.H{
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    int(accessTokenTTL.Seconds()),
+			"session_id":    tokens.SessionID,
+			"session_ends":  int(impersonationTTL.Seconds()),
+			"user":          profile,
+		})
+	}
+}
+
+// SetPlatformRole grants or removes platform admin rights of a login
+func SetPlatformRole(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PlatformRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identityID := c.Param("identityId")
+		if identityID == c.GetString("identity_id") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You can't change your own admin rights"})
+			return
+		}
+
+		var role interface{}
+		action := "platform_admin_revoked"
+		if *req.Admin {
+			role = "admin"
+			action = "platform_admin_granted"
+		}
+
+		result, err := db.Exec("UPDATE identities SET platform_role = ?, updated_at = NOW() WHERE uuid = ?", role, identityID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+			return
+		}
+
+		// Existing sessions carry the old role; make the login sign in again
+		revokeUserSessions(db, identityID, "admin")
+		auditAdmin(db, c, action, "identity", identityID, "", nil)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Role updated"})
+	}
+}
@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// apiKeyPrefix marks Archery Hub API keys so they're recognisable in configs and secret scanners
+const apiKeyPrefix = "ahk_"
+
+// newAPIKey generates a key and the prefix shown in listings
+func newAPIKey() (key, prefix string, err error) {
+	secret, err := generateRandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + secret
+	return key, key[:len(apiKeyPrefix)+8], nil
+}
+
+// LookupAPIKey returns the lookup the API key middleware authenticates keys with. Last use is
+// recorded at most once a minute per key.
+func LookupAPIKey(db *sqlx.DB) func(key, ip string) *models.APIKeyPrincipal {
+	return func(key, ip string) *models.APIKeyPrincipal {
+		var k models.APIKey
+		err := db.Get(&k, `
+			SELECT * FROM api_keys
+			WHERE key_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		`, hashToken(key))
+		if err != nil {
+			return nil
+		}
+
+		db.Exec(`
+			UPDATE api_keys SET last_used_at = NOW(), last_used_ip = ?
+			WHERE uuid = ? AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL 1 MINUTE)
+		`, ip, k.UUID)
+
+		principal := &models.APIKeyPrincipal{KeyID: k.UUID, OwnerID: k.OwnerID}
+		json.Unmarshal([]byte(k.ScopesJSON), &principal.Scopes)
+		if k.OrganizationID != nil {
+			principal.OrganizationID = *k.OrganizationID
+		}
+		if k.EventID != nil {
+			principal.EventID = *k.EventID
+		}
+		return principal
+	}
+}
+
+// requestAPIKey returns the API key the request authenticated with, or nil for user sessions
+func requestAPIKey(c *gin.Context) *models.APIKeyPrincipal {
+	if v, ok := c.Get("api_key"); ok {
+		if p, ok := v.(*models.APIKeyPrincipal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// apiKeyCoversEvent checks that a request made with an API key stays within the key's event or
+// organization. Requests without a key pass. It writes the error response and returns false.
+func apiKeyCoversEvent(db sqlx.Queryer, c *gin.Context, eventID string) bool {
+	key := requestAPIKey(c)
+	if key == nil {
+		return true
+	}
+
+	var covered bool
+	sqlx.Get(db, &covered, `
+		SELECT EXISTS(
+			SELECT 1 FROM events
+			WHERE (uuid = ? OR slug = ?) AND (uuid = NULLIF(?, '') OR organizer_id = NULLIF(?, ''))
+		)
+	`, eventID, eventID, key.EventID, key.OrganizationID)
+	if !covered {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is not valid for this event", "code": "api_key_scope"})
+		return false
+	}
+	return true
+}
+
+// canScoreEvent lets a request edit the event's scores: API keys must cover the event, users must
+// meet the event's 2FA requirement. A key can't answer a second factor, so events that require
+// 2FA take no score writes from API keys at all. It writes the error response and returns false.
+func canScoreEvent(db sqlx.Queryer, c *gin.Context, eventID string) bool {
+	if requestAPIKey(c) != nil {
+		if !apiKeyCoversEvent(db, c, eventID) {
+			return false
+		}
+		var required bool
+		sqlx.Get(db, &required, "SELECT COALESCE(require_two_factor, false) FROM events WHERE uuid = ? OR slug = ?", eventID, eventID)
+		if required {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "This event requires two-factor authentication for scoring, so API keys can't write its scores",
+				"code":  "two_factor_required",
+			})
+			return false
+		}
+		return true
+	}
+	return requireEventTwoFactor(db, c, eventID)
+}
+
+// loadOwnedAPIKey fetches a key the caller created (admins may manage any key)
+func loadOwnedAPIKey(db *sqlx.DB, c *gin.Context) (*models.APIKey, bool) {
+	var k models.APIKey
+	if err := db.Get(&k, "SELECT * FROM api_keys WHERE uuid = ?", c.Param("keyId")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return nil, false
+	}
+	if c.GetString("role") != "admin" && k.OwnerID != c.GetString("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return nil, false
+	}
+	json.Unmarshal([]byte(k.ScopesJSON), &k.Scopes)
+	return &k, true
+}
+
+// CreateAPIKey creates a key for an event the caller organizes, or for all events of the
+// caller's organization. The key is returned only this once.
+func CreateAPIKey(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID := c.GetString("user_id")
+		var organizationID, eventID *string
+		if req.EventID != "" {
+			managed, ok := loadManagedEvent(db, c, req.EventID)
+			if !ok {
+				return
+			}
+			eventID = &managed
+		} else {
+			if c.GetString("user_type") != "organization" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only organizations can create keys for all their events; pass event_id"})
+				return
+			}
+			organizationID = &userID
+		}
+
+		key, prefix, err := newAPIKey()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+			return
+		}
+
+		var expiresAt *time.Time
+		if req.ExpiresInDays > 0 {
+			t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+			expiresAt = &t
+		}
+
+		keyID := uuid.New().String()
+		_, err = db.Exec(`
+			INSERT INTO api_keys (uuid, name, prefix, key_hash, owner_id, organization_id, event_id, scopes, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, keyID, req.Name, prefix, hashToken(key), userID, organizationID, eventID, models.ToJSON(req.Scopes), expiresAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+			return
+		}
+
+		logEventID := ""
+		if eventID != nil {
+			logEventID = *eventID
+		}
+		utils.LogActivity(db, userID, logEventID, "api_key_created", "api_key", keyID,
+			"API key created: "+req.Name, c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "API key created. Copy it now; it won't be shown again.",
+			"key":     key,
+			"data": models.APIKey{
+				UUID:           keyID,
+				Name:           req.Name,
+				Prefix:         prefix,
+				OwnerID:        userID,
+				OrganizationID: organizationID,
+				EventID:        eventID,
+				Scopes:         req.Scopes,
+				ExpiresAt:      expiresAt,
+				CreatedAt:      time.Now(),
+			},
+		})
+	}
+}
+
+// GetAPIKeys lists the caller's keys, optionally for one event
+func GetAPIKeys(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT * FROM api_keys WHERE owner_id = ?"
+		args := []interface{}{c.GetString("user_id")}
+		if eventID := c.Query("event_id"); eventID != "" {
+			query += " AND event_id = ?"
+			args = append(args, eventID)
+		}
+		if c.Query("include_revoked") != "true" {
+			query += " AND revoked_at IS NULL"
+		}
+		query += " ORDER BY created_at DESC"
+
+		var keys []models.APIKey
+		if err := db.Select(&keys, query, args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+			return
+		}
+		for i := range keys {
+			json.Unmarshal([]byte(keys[i].ScopesJSON), &keys[i].Scopes)
+		}
+
+		if keys == nil {
+			keys = []models.APIKey{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": keys})
+	}
+}
+
+// RotateAPIKey issues a replacement key with the same scope and expiry. The old key stops working
+// after the grace period, so displays can be reconfigured without downtime.
+func RotateAPIKey(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RotateAPIKeyRequest
+		c.ShouldBindJSON(&req)
+		if req.GraceMinutes < 0 || req.GraceMinutes > 24*60 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "grace_minutes must be between 0 and 1440"})
+			return
+		}
+
+		old, ok := loadOwnedAPIKey(db, c)
+		if !ok {
+			return
+		}
+		if old.RevokedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "API key is revoked"})
+			return
+		}
+
+		key, prefix, err := newAPIKey()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		keyID := uuid.New().String()
+		if _, err := tx.Exec(`
+			INSERT INTO api_keys (uuid, name, prefix, key_hash, owner_id, organization_id, event_id, scopes, expires_at, rotated_from)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, keyID, old.Name, prefix, hashToken(key), old.OwnerID, old.OrganizationID, old.EventID, old.ScopesJSON, old.ExpiresAt, old.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate API key"})
+			return
+		}
+
+		// The old key expires after the grace period, or right away without one
+		graceEnd := time.Now().Add(time.Duration(req.GraceMinutes) * time.Minute)
+		if old.ExpiresAt == nil || graceEnd.Before(*old.ExpiresAt) {
+			if _, err := tx.Exec("UPDATE api_keys SET expires_at = ? WHERE uuid = ?", graceEnd, old.UUID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate API key"})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit rotation"})
+			return
+		}
+
+		utils.LogActivity(db, c.GetString("user_id"), "", "api_key_rotated", "api_key", keyID,
+			"API key rotated: "+old.Name, c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":            "API key rotated. Copy the new key now; it won't be shown again.",
+			"key":                key,
+			"id":                 keyID,
+			"prefix":             prefix,
+			"old_key_expires_at": graceEnd,
+		})
+	}
+}
+
+// RevokeAPIKey stops a key at once
+func RevokeAPIKey(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k, ok := loadOwnedAPIKey(db, c)
+		if !ok {
+			return
+		}
+
+		if _, err := db.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE uuid = ? AND revoked_at IS NULL", k.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+			return
+		}
+
+		utils.LogActivity(db, c.GetString("user_id"), "", "api_key_revoked", "api_key", k.UUID,
+			"API key revoked: "+k.Name, c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+	}
+}
@@ -51,12 +51,21 @@ func GetArchers(db *sqlx.DB) gin.HandlerFunc {
 		if status != "" {
 			query += " AND a.status = ?"
 			args = append(args, status)
+		} else {
+			query += " AND a.status NOT IN ('merged', 'deleted')"
 		}
 
+		// Only admins may look archers up by email, so the public list can't confirm addresses
+		searchEmail := c.GetString("role") == "admin"
 		if search != "" {
-			query += " AND (a.full_name LIKE ? OR a.email LIKE ? OR a.club_id LIKE ?)"
 			searchTerm := "%" + search + "%"
-			args = append(args, searchTerm, searchTerm, searchTerm)
+			if searchEmail {
+				query += " AND (a.full_name LIKE ? OR a.email LIKE ? OR a.club_id LIKE ?)"
+				args = append(args, searchTerm, searchTerm, searchTerm)
+			} else {
+				query += " AND (a.full_name LIKE ? OR a.club_id LIKE ?)"
+				args = append(args, searchTerm, searchTerm)
+			}
 		}
 
 		if city != "" {
@@ -92,12 +101,19 @@ func GetArchers(db *sqlx.DB) gin.HandlerFunc {
 		if status != "" {
 			countQuery += " AND status = ?"
 			countArgs = append(countArgs, status)
+		} else {
+			countQuery += " AND status NOT IN ('merged', 'deleted')"
 		}
 
 		if search != "" {
-			countQuery += " AND (full_name LIKE ? OR email LIKE ? OR club_id LIKE ?)"
 			searchTerm := "%" + search + "%"
-			countArgs = append(countArgs, searchTerm, searchTerm, searchTerm)
+			if searchEmail {
+				countQuery += " AND (full_name LIKE ? OR email LIKE ? OR club_id LIKE ?)"
+				countArgs = append(countArgs, searchTerm, searchTerm, searchTerm)
+			} else {
+				countQuery += " AND (full_name LIKE ? OR club_id LIKE ?)"
+				countArgs = append(countArgs, searchTerm, searchTerm)
+			}
 		}
 
 		if city != "" {
@@ -118,6 +134,7 @@ func GetArchers(db *sqlx.DB) gin.HandlerFunc {
 
 		// Mask URLs
 		for i := range archers {
+			hideMinorContact(&archers[i].Archer)
 			if archers[i].AvatarURL != nil {
 				masked := utils.MaskMediaURL(*archers[i].AvatarURL)
 				archers[i].AvatarURL = &masked
@@ -148,12 +165,20 @@ func GetArcherByID(db *sqlx.DB) gin.HandlerFunc {
 				c.slug as club_slug,
 				COUNT(DISTINCT tp.uuid) as total_events,
 				COUNT(DISTINCT CASE WHEN t.status = 'completed' THEN tp.uuid END) as completed_events,
-				MAX(t.end_date) as last_event_date
+				MAX(t.end_date) as last_event_date,
+				(
+					SELECT ROUND(SUM(qes.total_score_end) / NULLIF(SUM(qs.arrows_per_end), 0), 2)
+					FROM qualification_end_scores qes
+					JOIN qualification_sessions qs ON qes.session_uuid = qs.uuid
+					JOIN event_participants ep ON qes.participant_uuid = ep.uuid
+					WHERE ep.archer_id = a.uuid
+				) as avg_per_arrow
 			FROM archers a
 			LEFT JOIN clubs c ON a.club_id = c.uuid
 			LEFT JOIN event_participants tp ON a.uuid = tp.archer_id
 			LEFT JOIN events t ON tp.event_id = t.uuid
-			WHERE a.uuid = ? OR a.username = ? OR (a.id != '' AND a.id = ?)
+			WHERE (a.uuid = ? OR a.username = ? OR (a.id != '' AND a.id = ?))
+				AND a.status NOT IN ('merged', 'deleted')
 			GROUP BY a.uuid
 			LIMIT 1
 		`
@@ -172,6 +197,14 @@ func GetArcherByID(db *sqlx.DB) gin.HandlerFunc {
 			archer.AvatarURL = &masked
 		}
 
+		// Only the minor, their guardians and admins see a minor's contact details
+		viewerID := c.GetString("user_id")
+		if viewerID != archer.UUID && c.GetString("role") != "admin" && !isActiveGuardian(db, viewerID, archer.UUID) {
+			hideMinorContact(&archer.Archer)
+		}
+
+		archer.Achievements, _ = archerAchievements(db, archer.UUID)
+
 		c.JSON(http.StatusOK, archer)
 	}
 }
@@ -515,8 +548,8 @@ func CreateArcher(db *sqlx.DB) gin.HandlerFunc {
 		if clubID != nil {
 			memberID := uuid.New().String()
 			_, err = db.Exec(`
-				INSERT INTO club_members (uuid, club_id, archer_id, status, role, created_at)
-				VALUES (?, ?, ?, 'active', 'member', NOW())
+				INSERT INTO club_members (uuid, club_id, archer_id, status, role, joined_at, created_at)
+				VALUES (?, ?, ?, 'active', 'member', NOW(), NOW())
 			`, memberID, *clubID, archerID)
 			if err != nil {
 				// Log error but don't fail the request
@@ -524,6 +557,16 @@ func CreateArcher(db *sqlx.DB) gin.HandlerFunc {
 			}
 		}
 
+		// A guardian creating a profile for their child is linked (and consents) right away
+		if req.GuardianRelationship != nil && userID != nil {
+			userType, _ := c.Get("user_type")
+			utype, _ := userType.(string)
+			if _, err := createGuardianLink(db, archerID, userID.(string), utype, *req.GuardianRelationship, "active", userID.(string), c.ClientIP()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Archer created but failed to link guardian", "archer_id": archerID})
+				return
+			}
+		}
+
 		// Log activity
 		if userID != nil {
 			utils.LogActivity(db, userID.(string), "", "archer_created", "archer", archerID, "Created new archer: "+req.FullName, c.ClientIP(), c.Request.UserAgent())
@@ -549,14 +592,56 @@ func UpdateArcher(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Archers edit their own profile; anyone else needs to be an admin
+		if c.GetString("user_id") != id && c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own profile"})
+			return
+		}
+
+		// Only an admin may suspend or deactivate an account
+		if req.Status != nil && c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only an admin can change the archer status"})
+			return
+		}
+
 		// Check if archer exists
-		var exists bool
-		err := db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM archers WHERE uuid = ?)", id)
-		if err != nil || !exists {
+		var current struct {
+			Email    string `db:"email"`
+			FullName string `db:"full_name"`
+		}
+		err := db.Get(&current, "SELECT COALESCE(email, '') as email, COALESCE(full_name, '') as full_name FROM archers WHERE uuid = ?", id)
+		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Archer not found"})
 			return
 		}
 
+		// Only the clubs involved (or an admin) may change the club directly
+		if req.ClubID != nil && !canAssignClub(db, c, id, *req.ClubID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Club changes must go through a membership or transfer request"})
+			return
+		}
+
+		// The email is also the login, so it can't be one another login already uses
+		if req.Email != nil && *req.Email != "" && identityEmailTaken(db, id, *req.Email) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email is already used by another account"})
+			return
+		}
+
+		// Replacing an existing address has to be approved from that address first
+		emailChangePending := false
+		if req.Email != nil && current.Email != "" && !strings.EqualFold(*req.Email, current.Email) {
+			if *req.Email == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Email can't be removed"})
+				return
+			}
+			if err := requestEmailChange(db, id, "archer", current.Email, *req.Email, current.FullName); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request email change", "details": err.Error()})
+				return
+			}
+			emailChangePending = true
+			req.Email = nil
+		}
+
 		// Build dynamic update query
 		query := "UPDATE archers SET updated_at = NOW()"
 		args := []interface{}{}
@@ -591,14 +676,11 @@ func UpdateArcher(db *sqlx.DB) gin.HandlerFunc {
 			query += ", school = ?"
 			args = append(args, *req.School)
 		}
-		if req.ClubID != nil {
-			query += ", club_id = ?"
-			args = append(args, *req.ClubID)
-		}
 		if req.Email != nil {
 			truncateStr(req.Email, archerEmailLen)
-			query += ", email = ?"
-			args = append(args, *req.Email)
+			// A new address has to be verified again
+			query += ", email_verified_at = IF(email <=> ?, email_verified_at, NULL), email = ?"
+			args = append(args, *req.Email, *req.Email)
 		}
 		if req.Phone != nil {
 			truncateStr(req.Phone, archerPhoneLen)
@@ -637,12 +719,36 @@ func UpdateArcher(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		if req.Email != nil {
+			if err := syncIdentityEmail(db, id, *req.Email); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update login email", "details": err.Error()})
+				return
+			}
+		}
+
+		// A suspended or deactivated archer is signed out everywhere
+		if req.Status != nil && *req.Status != "active" {
+			revokeUserSessions(db, id, "suspended")
+		}
+
+		// club_id follows the active membership, so a club change goes through club_members
+		if req.ClubID != nil {
+			if err := assignClubMembership(db, id, *req.ClubID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update club membership", "details": err.Error()})
+				return
+			}
+		}
+
 		// Log activity
 		userID, _ := c.Get("user_id")
 		if userID != nil {
 			utils.LogActivity(db, userID.(string), "", "archer_updated", "archer", id, "Updated archer", c.ClientIP(), c.Request.UserAgent())
 		}
 
+		if emailChangePending {
+			c.JSON(http.StatusOK, gin.H{"message": "Archer updated. The email change has to be confirmed from the current address.", "email_change_pending": true})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"message": "Archer updated successfully"})
 	}
 }
@@ -652,6 +758,11 @@ func DeleteArcher(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
+		if c.GetString("user_id") != id && c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own profile"})
+			return
+		}
+
 		// Check if archer has any event participations
 		var participationCount int
 		db.Get(&participationCount, "SELECT COUNT(*) FROM event_participants WHERE archer_id = ?", id)
@@ -789,6 +900,7 @@ func GetArcherRegistrationProfile(db *sqlx.DB) gin.HandlerFunc {
 			BowType     *string `json:"bow_type" db:"bow_type"`
 			ClubID      *string `json:"club_id" db:"club_id"`
 			ClubName    *string `json:"club_name" db:"club_name"`
+			IsMinor     bool    `json:"is_minor" db:"is_minor"`
 		}
 
 		query := `
@@ -796,19 +908,26 @@ func GetArcherRegistrationProfile(db *sqlx.DB) gin.HandlerFunc {
 				a.uuid, a.id, a.full_name, a.email, a.avatar_url,
 				a.gender, a.date_of_birth, a.phone,
 				a.city, a.bow_type,
-				a.club_id, c.name as club_name
+				a.club_id, c.name as club_name,
+				COALESCE(TIMESTAMPDIFF(YEAR, a.date_of_birth, CURDATE()) < ?, FALSE) as is_minor
 			FROM archers a
 			LEFT JOIN clubs c ON a.club_id = c.uuid
 			WHERE a.uuid = ?
 		`
 
-		err := db.Get(&archer, query, uuid)
+		err := db.Get(&archer, query, guardianConsentAge, uuid)
 		if err != nil {
 			logrus.WithError(err).Warnf("Archer registration profile not found: %s", uuid)
 			c.JSON(http.StatusNotFound, gin.H{"error": "Archer not found"})
 			return
 		}
 
+		// Minors' contact details stay private on this public endpoint
+		if archer.IsMinor {
+			archer.Email = nil
+			archer.Phone = nil
+		}
+
 		c.JSON(http.StatusOK, gin.H{"data": archer})
 	}
 }
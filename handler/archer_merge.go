@@ -0,0 +1,602 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// archerMergeMoves lists every reference to an archer that a merge repoints. Scores hang off
+// event_participants and follow the participant rows. The source's logins move with
+// identity_profiles so they sign in as the target; its sessions are revoked instead of moved.
+var archerMergeMoves = []struct {
+	Table  string
+	Column string
+	Key    string
+	Where  string
+}{
+	{"event_participants", "archer_id", "uuid", ""},
+	{"club_members", "archer_id", "uuid", ""},
+	{"elimination_entries", "participant_uuid", "uuid", "participant_type = 'archer'"},
+	{"club_training_attendance", "archer_id", "uuid", ""},
+	{"practice_rounds", "archer_id", "uuid", ""},
+	{"club_dues_invoices", "archer_id", "uuid", ""},
+	{"archer_achievements", "archer_id", "uuid", ""},
+	{"national_ranking_results", "archer_id", "uuid", ""},
+	{"refunds", "archer_id", "uuid", ""},
+	{"credit_notes", "archer_id", "uuid", ""},
+	{"media", "user_id", "uuid", "user_type = 'archer'"},
+	{"notifications", "user_id", "id", "user_role = 'archer'"},
+	{"payment_transactions", "user_id", "uuid", ""},
+	{"cart_items", "user_id", "uuid", ""},
+	{"orders", "buyer_id", "uuid", ""},
+	{"club_staff", "user_id", "uuid", "user_type = 'archer'"},
+	{"identity_profiles", "profile_id", "uuid", "user_type = 'archer'"},
+	{"archer_guardians", "archer_id", "uuid", ""},
+	{"archer_guardians", "guardian_id", "uuid", "guardian_type = 'archer'"},
+}
+
+// archerMergeDuplicates lists rows of the source archer that would duplicate a row the target
+// already has (same Unique columns); they're removed (and kept in the undo log) instead of moved
+var archerMergeDuplicates = []struct {
+	Table  string
+	Column string
+	Unique string
+	Where  string
+}{
+	{"club_members", "archer_id", "club_id", ""},
+	{"club_training_attendance", "archer_id", "session_id", ""},
+	{"archer_achievements", "archer_id", "rule_id", ""},
+	{"club_staff", "user_id", "club_id, role", "user_type = 'archer'"},
+	{"club_dues_invoices", "archer_id", "club_id, period_start", ""},
+	{"identity_profiles", "profile_id", "identity_id", "user_type = 'archer'"},
+	{"archer_guardians", "archer_id", "guardian_id", ""},
+	{"archer_guardians", "guardian_id", "archer_id", "guardian_type = 'archer'"},
+}
+
+// archerMergeFillColumns are profile fields the target takes from the source when it has none
+var archerMergeFillColumns = []string{
+	"email", "phone", "date_of_birth", "gender", "avatar_url", "address", "bio",
+	"bow_type", "city", "school", "google_id", "password", "user_id",
+}
+
+// archerMergeLog is the undo log stored with a merge
+type archerMergeLog struct {
+	Moved   []archerMergeMoved     `json:"moved"`
+	Deleted []archerMergeDeleted   `json:"deleted"`
+	Source  map[string]interface{} `json:"source"`
+	Target  map[string]interface{} `json:"target"`
+}
+
+type archerMergeMoved struct {
+	Table  string   `json:"table"`
+	Column string   `json:"column"`
+	Key    string   `json:"key"`
+	IDs    []string `json:"ids"`
+}
+
+type archerMergeDeleted struct {
+	Table string                   `json:"table"`
+	Rows  []map[string]interface{} `json:"rows"`
+}
+
+// selectRowMaps reads rows as column maps with text values so they survive a JSON round trip
+func selectRowMaps(tx *sqlx.Tx, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := tx.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		row := map[string]interface{}{}
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// archerMergeSnapshotColumns are the archer columns a merge may change on either record
+func archerMergeSnapshotColumns() []string {
+	return append([]string{"status", "merged_into"}, archerMergeFillColumns...)
+}
+
+// snapshotArcher reads the columns of an archer a merge may change
+func snapshotArcher(tx *sqlx.Tx, archerID string) (map[string]interface{}, error) {
+	rows, err := selectRowMaps(tx, "SELECT "+strings.Join(archerMergeSnapshotColumns(), ", ")+" FROM archers WHERE uuid = ?", archerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("archer %s not found", archerID)
+	}
+	return rows[0], nil
+}
+
+// restoreArcher writes a snapshot taken by snapshotArcher back
+func restoreArcher(tx *sqlx.Tx, archerID string, snapshot map[string]interface{}) error {
+	sets := []string{}
+	args := []interface{}{}
+	for _, col := range archerMergeSnapshotColumns() {
+		sets = append(sets, col+" = ?")
+		args = append(args, snapshot[col])
+	}
+	args = append(args, archerID)
+	_, err := tx.Exec("UPDATE archers SET "+strings.Join(sets, ", ")+", updated_at = NOW() WHERE uuid = ?", args...)
+	return err
+}
+
+// mergeArchers moves everything of the source archer to the target inside tx and returns the
+// undo log. The source record stays behind with status 'merged' and its login fields cleared.
+func mergeArchers(tx *sqlx.Tx, sourceID, targetID string) (*archerMergeLog, error) {
+	log := &archerMergeLog{}
+
+	var err error
+	if log.Source, err = snapshotArcher(tx, sourceID); err != nil {
+		return nil, err
+	}
+	if log.Target, err = snapshotArcher(tx, targetID); err != nil {
+		return nil, err
+	}
+
+	for _, d := range archerMergeDuplicates {
+		filter := ""
+		if d.Where != "" {
+			filter = " AND " + d.Where
+		}
+		where := fmt.Sprintf("%s = ?%s AND (%s) IN (SELECT %s FROM (SELECT %s FROM %s WHERE %s = ?%s) t)",
+			d.Column, filter, d.Unique, d.Unique, d.Unique, d.Table, d.Column, filter)
+		rows, err := selectRowMaps(tx, "SELECT * FROM "+d.Table+" WHERE "+where, sourceID, targetID)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM "+d.Table+" WHERE "+where, sourceID, targetID); err != nil {
+			return nil, err
+		}
+		log.Deleted = append(log.Deleted, archerMergeDeleted{Table: d.Table, Rows: rows})
+	}
+
+	for _, m := range archerMergeMoves {
+		where := m.Column + " = ?"
+		if m.Where != "" {
+			where += " AND " + m.Where
+		}
+
+		var ids []string
+		if err := tx.Select(&ids, fmt.Sprintf("SELECT CAST(%s AS CHAR) FROM %s WHERE %s", m.Key, m.Table, where), sourceID); err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s", m.Table, m.Column, where), targetID, sourceID); err != nil {
+			return nil, err
+		}
+		log.Moved = append(log.Moved, archerMergeMoved{Table: m.Table, Column: m.Column, Key: m.Key, IDs: ids})
+	}
+
+	// Unique login fields are released by the source before the target takes them
+	if _, err := tx.Exec(`
+		UPDATE archers SET status = 'merged', merged_into = ?, email = NULL, google_id = NULL, password = NULL, user_id = NULL, updated_at = NOW()
+		WHERE uuid = ?
+	`, targetID, sourceID); err != nil {
+		return nil, err
+	}
+
+	sets := []string{}
+	args := []interface{}{}
+	for _, col := range archerMergeFillColumns {
+		if log.Target[col] == nil && log.Source[col] != nil {
+			sets = append(sets, col+" = ?")
+			args = append(args, log.Source[col])
+		}
+	}
+	if len(sets) > 0 {
+		args = append(args, targetID)
+		if _, err := tx.Exec("UPDATE archers SET "+strings.Join(sets, ", ")+", updated_at = NOW() WHERE uuid = ?", args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := syncArcherClub(tx, targetID); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// undoArcherMerge reverses a merge from its undo log inside tx
+func undoArcherMerge(tx *sqlx.Tx, merge models.ArcherMerge) error {
+	var log archerMergeLog
+	if err := json.Unmarshal([]byte(merge.UndoLog), &log); err != nil {
+		return err
+	}
+
+	// The target goes first so the source can take its unique login fields back
+	if err := restoreArcher(tx, merge.TargetArcherID, log.Target); err != nil {
+		return err
+	}
+	if err := restoreArcher(tx, merge.SourceArcherID, log.Source); err != nil {
+		return err
+	}
+
+	for _, m := range log.Moved {
+		query, args, err := sqlx.In(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s IN (?)", m.Table, m.Column, m.Key), merge.SourceArcherID, m.IDs)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range log.Deleted {
+		for _, row := range d.Rows {
+			cols := make([]string, 0, len(row))
+			for col := range row {
+				cols = append(cols, col)
+			}
+			sort.Strings(cols)
+			args := make([]interface{}, 0, len(cols))
+			for _, col := range cols {
+				args = append(args, row[col])
+			}
+			query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", d.Table, strings.Join(cols, ", "),
+				strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+			if _, err := tx.Exec(query, args...); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := syncArcherClub(tx, merge.SourceArcherID); err != nil {
+		return err
+	}
+	return syncArcherClub(tx, merge.TargetArcherID)
+}
+
+// normalizePhone reduces a phone number to digits with the Indonesian 62 prefix
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if strings.HasPrefix(digits, "0") {
+		digits = "62" + digits[1:]
+	}
+	if len(digits) < 8 {
+		return ""
+	}
+	return digits
+}
+
+// GetArcherDuplicateCandidates lists pairs of archers sharing name and date of birth, email or
+// phone number, skipping pairs an admin dismissed
+func GetArcherDuplicateCandidates(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var archers []models.ArcherDuplicateProfile
+		err := db.Select(&archers, `
+			SELECT a.uuid, a.full_name, a.username, a.email, a.phone, a.date_of_birth, a.club_id,
+				a.google_id IS NOT NULL as has_google,
+				(SELECT COUNT(*) FROM event_participants ep WHERE ep.archer_id = a.uuid) as event_count,
+				a.created_at
+			FROM archers a
+			WHERE a.status != 'merged'
+		`)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archers", "details": err.Error()})
+			return
+		}
+
+		var dismissed []struct {
+			ArcherA string `db:"archer_a"`
+			ArcherB string `db:"archer_b"`
+		}
+		db.Select(&dismissed, "SELECT archer_a, archer_b FROM archer_duplicate_dismissals")
+
+		pairKey := func(a, b string) string {
+			if a > b {
+				a, b = b, a
+			}
+			return a + "|" + b
+		}
+		skip := map[string]bool{}
+		for _, d := range dismissed {
+			skip[pairKey(d.ArcherA, d.ArcherB)] = true
+		}
+
+		// Bucket archers by each matching key, then pair up every bucket
+		buckets := map[string][]int{}
+		for i, a := range archers {
+			if a.DateOfBirth != nil {
+				name := strings.Join(strings.Fields(strings.ToLower(a.FullName)), " ")
+				buckets["name_dob|"+name+"|"+a.DateOfBirth.Format("2006-01-02")] = append(buckets["name_dob|"+name+"|"+a.DateOfBirth.Format("2006-01-02")], i)
+			}
+			if a.Email != nil && strings.TrimSpace(*a.Email) != "" {
+				key := "email|" + strings.ToLower(strings.TrimSpace(*a.Email))
+				buckets[key] = append(buckets[key], i)
+			}
+			if a.Phone != nil {
+				if phone := normalizePhone(*a.Phone); phone != "" {
+					buckets["phone|"+phone] = append(buckets["phone|"+phone], i)
+				}
+			}
+		}
+
+		pairs := map[string]*models.ArcherDuplicateCandidate{}
+		order := []string{}
+		for key, members := range buckets {
+			reason := key[:strings.Index(key, "|")]
+			for x := 0; x < len(members); x++ {
+				for y := x + 1; y < len(members); y++ {
+					a, b := archers[members[x]], archers[members[y]]
+					pk := pairKey(a.UUID, b.UUID)
+					if skip[pk] {
+						continue
+					}
+					candidate, ok := pairs[pk]
+					if !ok {
+						candidate = &models.ArcherDuplicateCandidate{Archers: []models.ArcherDuplicateProfile{a, b}}
+						pairs[pk] = candidate
+						order = append(order, pk)
+					}
+					candidate.Reasons = append(candidate.Reasons, reason)
+				}
+			}
+		}
+
+		sort.Strings(order)
+		result := make([]models.ArcherDuplicateCandidate, 0, len(order))
+		for _, pk := range order {
+			sort.Strings(pairs[pk].Reasons)
+			result = append(result, *pairs[pk])
+		}
+		// Pairs matching on more signals first
+		sort.SliceStable(result, func(i, j int) bool { return len(result[i].Reasons) > len(result[j].Reasons) })
+
+		c.JSON(http.StatusOK, gin.H{"data": result, "total": len(result)})
+	}
+}
+
+// DismissArcherDuplicate marks a candidate pair as different people
+func DismissArcherDuplicate(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ArcherA string `json:"archer_a" binding:"required"`
+			ArcherB string `json:"archer_b" binding:"required,nefield=ArcherA"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.ArcherA > req.ArcherB {
+			req.ArcherA, req.ArcherB = req.ArcherB, req.ArcherA
+		}
+
+		userID, _ := c.Get("user_id")
+		_, err := db.Exec(`
+			INSERT INTO archer_duplicate_dismissals (uuid, archer_a, archer_b, dismissed_by)
+			SELECT ?, ?, ?, ?
+			FROM DUAL
+			WHERE NOT EXISTS (SELECT 1 FROM archer_duplicate_dismissals WHERE archer_a = ? AND archer_b = ?)
+		`, uuid.New().String(), req.ArcherA, req.ArcherB, userID, req.ArcherA, req.ArcherB)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss candidate"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Candidate dismissed"})
+	}
+}
+
+// MergeArchers merges a duplicate archer into the surviving record in one transaction
+func MergeArchers(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MergeArchersRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.SourceArcherID == req.TargetArcherID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An archer can't be merged into itself"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		// Both archers stay locked until the merge commits, so a concurrent merge or registration
+		// can't slip in between the checks and the move
+		var statuses []struct {
+			UUID   string `db:"uuid"`
+			Status string `db:"status"`
+		}
+		if err := tx.Select(&statuses, "SELECT uuid, status FROM archers WHERE uuid IN (?, ?) FOR UPDATE", req.SourceArcherID, req.TargetArcherID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load archers"})
+			return
+		}
+		if len(statuses) != 2 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archer not found"})
+			return
+		}
+		for _, s := range statuses {
+			if s.Status == "merged" {
+				c.JSON(http.StatusConflict, gin.H{"error": "Archer " + s.UUID + " was already merged"})
+				return
+			}
+		}
+
+		// Two registrations for the same event can't be folded into one automatically
+		var conflicts []struct {
+			EventID   string `json:"event_id" db:"event_id"`
+			EventName string `json:"event_name" db:"event_name"`
+		}
+		if err := tx.Select(&conflicts, `
+			SELECT DISTINCT e.uuid as event_id, e.name as event_name
+			FROM event_participants s
+			JOIN event_participants t ON t.event_id = s.event_id AND t.archer_id = ?
+			JOIN events e ON e.uuid = s.event_id
+			WHERE s.archer_id = ?
+			FOR UPDATE
+		`, req.TargetArcherID, req.SourceArcherID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check registrations"})
+			return
+		}
+		if len(conflicts) > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":     "Both archers are registered for the same event; remove one registration first",
+				"conflicts": conflicts,
+			})
+			return
+		}
+
+		undo, err := mergeArchers(tx, req.SourceArcherID, req.TargetArcherID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge archers", "details": err.Error()})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		mergeID := uuid.New().String()
+		_, err = tx.Exec(`
+			INSERT INTO archer_merges (uuid, source_archer_id, target_archer_id, merged_by, undo_log, status)
+			VALUES (?, ?, ?, ?, ?, 'merged')
+		`, mergeID, req.SourceArcherID, req.TargetArcherID, userID, models.ToJSON(undo))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record merge"})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit merge"})
+			return
+		}
+
+		// Sessions still carry the source as their user; sign them out
+		revokeUserSessions(db, req.SourceArcherID, "merged")
+
+		utils.LogActivity(db, userID.(string), "", "archer_merged", "archer", req.TargetArcherID,
+			fmt.Sprintf("Merged archer %s into %s", req.SourceArcherID, req.TargetArcherID), c.ClientIP(), c.Request.UserAgent())
+
+		moved := 0
+		for _, m := range undo.Moved {
+			moved += len(m.IDs)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Archers merged", "merge_id": mergeID, "moved_rows": moved})
+	}
+}
+
+// GetArcherMerges lists past merges, newest first
+func GetArcherMerges(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var merges []struct {
+			models.ArcherMerge
+			SourceName string `json:"source_name" db:"source_name"`
+			TargetName string `json:"target_name" db:"target_name"`
+		}
+		err := db.Select(&merges, `
+			SELECT m.*, s.full_name as source_name, t.full_name as target_name
+			FROM archer_merges m
+			JOIN archers s ON m.source_archer_id = s.uuid
+			JOIN archers t ON m.target_archer_id = t.uuid
+			ORDER BY m.created_at DESC
+		`)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch merges"})
+			return
+		}
+
+		if merges == nil {
+			merges = []struct {
+				models.ArcherMerge
+				SourceName string `json:"source_name" db:"source_name"`
+				TargetName string `json:"target_name" db:"target_name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": merges})
+	}
+}
+
+// UndoArcherMerge restores both archers to their state before a merge. Only the latest merge
+// touching the surviving archer can be undone.
+func UndoArcherMerge(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var merge models.ArcherMerge
+		if err := db.Get(&merge, "SELECT * FROM archer_merges WHERE uuid = ?", c.Param("mergeId")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Merge not found"})
+			return
+		}
+		if merge.Status != "merged" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Merge was already undone"})
+			return
+		}
+
+		var later bool
+		db.Get(&later, `
+			SELECT EXISTS(
+				SELECT 1 FROM archer_merges
+				WHERE status = 'merged' AND created_at > ? AND uuid != ?
+					AND (target_archer_id IN (?, ?) OR source_archer_id IN (?, ?))
+			)
+		`, merge.CreatedAt, merge.UUID, merge.SourceArcherID, merge.TargetArcherID, merge.SourceArcherID, merge.TargetArcherID)
+		if later {
+			c.JSON(http.StatusConflict, gin.H{"error": "Undo the later merges of these archers first"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if err := undoArcherMerge(tx, merge); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo merge", "details": err.Error()})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		if _, err := tx.Exec("UPDATE archer_merges SET status = 'undone', undone_at = NOW(), undone_by = ? WHERE uuid = ?", userID, merge.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update merge"})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit undo"})
+			return
+		}
+
+		utils.LogActivity(db, userID.(string), "", "archer_merge_undone", "archer", merge.TargetArcherID,
+			fmt.Sprintf("Undid merge of archer %s into %s", merge.SourceArcherID, merge.TargetArcherID), c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Merge undone"})
+	}
+}
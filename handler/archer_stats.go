@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// archerQualificationRounds returns every qualification session an archer scored in, oldest first
+func archerQualificationRounds(db sqlx.Queryer, archerID string) ([]models.ArcherRound, error) {
+	var rounds []models.ArcherRound
+	err := sqlx.Select(db, &rounds, `
+		SELECT
+			e.uuid as event_id, e.name as event_name,
+			DATE_FORMAT(e.start_date, '%Y-%m-%d') as event_date,
+			YEAR(COALESCE(e.start_date, e.created_at)) as season,
+			qs.name as session_name, rbt.name as bow_type, e.location_type,
+			qs.distance, qs.total_ends, qs.arrows_per_end,
+			COUNT(qes.uuid) as ends_completed,
+			(
+				SELECT COUNT(*) FROM qualification_arrow_scores qas
+				JOIN qualification_end_scores q2 ON qas.end_score_uuid = q2.uuid
+				WHERE q2.participant_uuid = ep.uuid AND q2.session_uuid = qs.uuid
+			) as arrows_shot,
+			COALESCE(SUM(qes.total_score_end), 0) as score,
+			COALESCE(SUM(qes.ten_count_end), 0) as ten_count,
+			COALESCE(SUM(qes.x_count_end), 0) as x_count
+		FROM event_participants ep
+		JOIN events e ON ep.event_id = e.uuid
+		JOIN qualification_end_scores qes ON qes.participant_uuid = ep.uuid
+		JOIN qualification_sessions qs ON qes.session_uuid = qs.uuid
+		LEFT JOIN event_categories ec ON ep.category_id = ec.uuid
+		LEFT JOIN ref_bow_types rbt ON ec.division_uuid = rbt.uuid
+		WHERE ep.archer_id = ?
+		GROUP BY ep.uuid, qs.uuid, e.uuid, ec.uuid, rbt.uuid
+		ORDER BY e.start_date ASC, qs.created_at ASC
+	`, archerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rounds {
+		r := &rounds[i]
+		// Ends entered as totals only have no arrow rows
+		if r.ArrowsShot == 0 {
+			r.ArrowsShot = r.EndsCompleted * r.ArrowsPerEnd
+		}
+		r.Complete = r.TotalEnds > 0 && r.EndsCompleted >= r.TotalEnds
+	}
+	return rounds, nil
+}
+
+// summarizeArcherRounds aggregates rounds into season statistics. Pass season 0 for all rounds.
+func summarizeArcherRounds(rounds []models.ArcherRound, season int) *models.ArcherSeasonStats {
+	stats := &models.ArcherSeasonStats{Season: season}
+	events := map[string]bool{}
+	tens, xs, completeScore := 0, 0, 0
+
+	for _, r := range rounds {
+		if season != 0 && r.Season != season {
+			continue
+		}
+		events[r.EventID] = true
+		stats.Rounds++
+		stats.EndsCompleted += r.EndsCompleted
+		stats.ArrowsShot += r.ArrowsShot
+		stats.TotalScore += r.Score
+		tens += r.TenCount
+		xs += r.XCount
+		if r.Complete {
+			stats.CompleteRounds++
+			completeScore += r.Score
+			if r.Score > stats.BestRoundScore {
+				stats.BestRoundScore = r.Score
+			}
+		}
+	}
+
+	stats.Events = len(events)
+	if stats.ArrowsShot > 0 {
+		stats.AvgPerArrow = roundTo(float64(stats.TotalScore)/float64(stats.ArrowsShot), 2)
+		stats.TenRate = roundTo(float64(tens)/float64(stats.ArrowsShot)*100, 1)
+		stats.XRate = roundTo(float64(xs)/float64(stats.ArrowsShot)*100, 1)
+	}
+	if stats.CompleteRounds > 0 {
+		stats.AvgRoundScore = roundTo(float64(completeScore)/float64(stats.CompleteRounds), 1)
+	}
+	return stats
+}
+
+// archerPersonalBests picks the best complete round for each round length, distance and bow
+// type, breaking ties on 10s then Xs
+func archerPersonalBests(rounds []models.ArcherRound) []models.ArcherPersonalBest {
+	bests := map[string]*models.ArcherPersonalBest{}
+	keys := []string{}
+
+	for i := range rounds {
+		r := &rounds[i]
+		if !r.Complete {
+			continue
+		}
+
+		arrows := r.TotalEnds * r.ArrowsPerEnd
+		distance, bowType := "", ""
+		if r.Distance != nil {
+			distance = strconv.Itoa(*r.Distance)
+		}
+		if r.BowType != nil {
+			bowType = *r.BowType
+		}
+		key := fmt.Sprintf("%d|%s|%s", arrows, distance, bowType)
+
+		best, ok := bests[key]
+		if !ok {
+			roundType := fmt.Sprintf("%d arrows", arrows)
+			if r.LocationType != nil && *r.LocationType != "" {
+				roundType += " " + *r.LocationType
+			}
+			bests[key] = &models.ArcherPersonalBest{
+				RoundType: roundType,
+				Arrows:    arrows,
+				Distance:  r.Distance,
+				BowType:   r.BowType,
+				Round:     r,
+			}
+			keys = append(keys, key)
+			continue
+		}
+
+		b := best.Round
+		if r.Score > b.Score ||
+			(r.Score == b.Score && r.TenCount > b.TenCount) ||
+			(r.Score == b.Score && r.TenCount == b.TenCount && r.XCount > b.XCount) {
+			best.Round = r
+		}
+	}
+
+	result := make([]models.ArcherPersonalBest, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *bests[key])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Arrows != result[j].Arrows {
+			return result[i].Arrows > result[j].Arrows
+		}
+		di, dj := 0, 0
+		if result[i].Distance != nil {
+			di = *result[i].Distance
+		}
+		if result[j].Distance != nil {
+			dj = *result[j].Distance
+		}
+		return di > dj
+	})
+	return result
+}
+
+// roundTo rounds v to the given number of decimals
+func roundTo(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// GetArcherStats returns an archer's personal bests, season statistics compared with the
+// previous season, and the history of ends completed per round. Pick the season with ?season=.
+func GetArcherStats(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var archerID string
+		err := db.Get(&archerID, "SELECT uuid FROM archers WHERE uuid = ? OR username = ? OR (id != '' AND id = ?) LIMIT 1", id, id, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archer not found"})
+			return
+		}
+
+		season := time.Now().Year()
+		if s := strings.TrimSpace(c.Query("season")); s != "" {
+			season, err = strconv.Atoi(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "season must be a year"})
+				return
+			}
+		}
+
+		rounds, err := archerQualificationRounds(db, archerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scores", "details": err.Error()})
+			return
+		}
+		if rounds == nil {
+			rounds = []models.ArcherRound{}
+		}
+
+		current := summarizeArcherRounds(rounds, season)
+		previous := summarizeArcherRounds(rounds, season-1)
+
+		c.JSON(http.StatusOK, gin.H{"data": models.ArcherStats{
+			ArcherID:       archerID,
+			Season:         current,
+			PreviousSeason: previous,
+			Change: &models.ArcherSeasonComparison{
+				AvgPerArrow:   roundTo(current.AvgPerArrow-previous.AvgPerArrow, 2),
+				AvgRoundScore: roundTo(current.AvgRoundScore-previous.AvgRoundScore, 1),
+				TenRate:       roundTo(current.TenRate-previous.TenRate, 1),
+				XRate:         roundTo(current.XRate-previous.XRate, 1),
+				ArrowsShot:    current.ArrowsShot - previous.ArrowsShot,
+			},
+			Career:        summarizeArcherRounds(rounds, 0),
+			PersonalBests: archerPersonalBests(rounds),
+			History:       rounds,
+		}})
+	}
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"archeryhub-api/models"
 	"archeryhub-api/utils"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"fmt"
 	"log"
@@ -57,11 +59,15 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	UserType string `json:"user_type"` // optional: which profile to sign in as
 }
 
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  interface{} `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+	ExpiresIn    int         `json:"expires_in,omitempty"` // access token lifetime in seconds
+	User         interface{} `json:"user,omitempty"`
+	Profiles     interface{} `json:"profiles,omitempty"` // every profile the login can switch to
 }
 
 // Register handles user registration
@@ -121,6 +127,18 @@ func Register(db *sqlx.DB) gin.HandlerFunc {
 			}
 		}
 
+		// The email may belong to a login whose profiles are all of other types (e.g. a seller);
+		// adding a profile to it goes through /auth/profiles instead
+		if !found {
+			if _, err := findIdentity(db, req.Email, ""); err == nil {
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "User with this email or username already exists",
+					"type":  "identity",
+				})
+				return
+			}
+		}
+
 		userID := ""
 		isUpdate := false
 
@@ -221,23 +239,56 @@ func Register(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Generate JWT token
-		name := req.FullName
-		avatar := "" // New registration has no avatar yet
-		token, err := generateJWT(userID, req.Email, role, req.UserType, name, avatar)
+		// Every login belongs to an identity; claiming an unverified archer also sets its password
+		identityID, err := ensureIdentityForProfile(db, req.UserType, userID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user: " + err.Error()})
 			return
 		}
+		db.Exec("UPDATE identities SET password = ?, updated_at = NOW() WHERE uuid = ? AND (password IS NULL OR password = '')", req.Password, identityID)
 
-		// Set Cookie using helper
-		setAuthCookie(c, token, 60*60*24*60) // 60 days
+		// Confirm the address; when Login requires it the user signs in after following the link
+		name := req.FullName
+		sendVerificationEmail(db, userID, req.UserType, req.Email, name)
 
 		// Log activity (silently fail if log table doesn't exist yet)
 		utils.LogActivity(db, userID, "", "user_registered", req.UserType, userID, "User registered: "+req.Username, c.ClientIP(), c.Request.UserAgent())
 
+		if emailVerificationRequired() {
+			c.JSON(http.StatusCreated, gin.H{
+				"message":                     "Registration successful. Check your email to verify your address before logging in.",
+				"email_verification_required": true,
+				"user": gin.H{
+					"id":        userID,
+					"email":     req.Email,
+					"full_name": req.FullName,
+					"role":      role,
+					"user_type": req.UserType,
+				},
+			})
+			return
+		}
+
+		// Start a session (sets the access and refresh cookies)
+		avatar := "" // New registration has no avatar yet
+		tokens, err := issueSession(db, c, sessionProfile{
+			IdentityID: identityID,
+			UserID:     userID,
+			Email:      req.Email,
+			Role:       role,
+			UserType:   req.UserType,
+			Name:       name,
+			Avatar:     avatar,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
 		c.JSON(http.StatusCreated, AuthResponse{
-			Token: token,
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int(accessTokenTTL.Seconds()),
 			User: gin.H{
 				"id":         userID,
 				"username":   req.FullName, // Use FullName as identifier in response if username is gone
@@ -304,80 +355,40 @@ func Login(db *sqlx.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if os.Getenv("ENV") == "development" {
-			log.Printf("[auth] login attempt email=%q password_len=%d", req.Email, len(req.Password))
-		}
-
-		type UserResult struct {
-			UUID      string  `db:"uuid"`
-			ID        string  `db:"id"`
-			Username  string  `db:"slug"` // Use slug for frontend username field
-			Email     string  `db:"email"`
-			Password  string  `db:"password"`
-			FullName  string  `db:"full_name"`
-			AvatarURL *string `db:"avatar_url"`
-			Role      string  `db:"role"`
-			Status    string  `db:"status"`
-			Type      string
-		}
-
-		var user UserResult
-		found := false
-
-		// COALESCE(password,'') so NULL (e.g. Google-created org/club/seller) is handled as empty
-		// Check archers
-		err := db.Get(&user, "SELECT uuid, id, username as slug, email, COALESCE(password,'') as password, full_name, avatar_url, 'archer' as role, COALESCE(status,'') as status FROM archers WHERE email = ?", req.Email)
-		if err == nil {
-			user.Type = "archer"
-			found = true
-		}
-
-		// Check organizations (Google sign-up does not set password; only Register does)
-		// Use column alias "slug" so result matches UserResult (db:"slug" for Username)
-		if !found {
-			err = db.Get(&user, "SELECT uuid, uuid as id, slug, email, COALESCE(password,'') as password, name as full_name, avatar_url, 'organization' as role, COALESCE(status,'') as status FROM organizations WHERE email = ?", req.Email)
-			if err == nil {
-				user.Type = "organization"
-				found = true
-			} else if os.Getenv("ENV") == "development" {
-				log.Printf("[auth] organizations lookup failed for %q: %v", req.Email, err)
-			}
-		}
-
-		// Check clubs (use slug so result matches UserResult)
-		if !found {
-			err = db.Get(&user, "SELECT uuid, uuid as id, slug, email, COALESCE(password,'') as password, name as full_name, avatar_url, 'club' as role, COALESCE(status,'') as status FROM clubs WHERE email = ?", req.Email)
-			if err == nil {
-				user.Type = "club"
-				found = true
-			}
+		if !checkLoginThrottle(c, req.Email) {
+			recordLoginAttempt(db, c, req.Email, "", false, "throttled")
+			return
 		}
 
-		// Check sellers
-		if !found {
-			err = db.Get(&user, "SELECT uuid, uuid as id, slug, email, COALESCE(password,'') as password, store_name as full_name, avatar_url, 'seller' as role, COALESCE(status,'') as status FROM sellers WHERE email = ?", req.Email)
-			if err == nil {
-				user.Type = "seller"
-				found = true
+		// Accounts created since the identity migration (e.g. archers added by a club) are linked
+		// to an identity on their first login
+		identity, err := findIdentity(db, req.Email, "")
+		if err == sql.ErrNoRows {
+			if account, aerr := findAccountByEmail(db, req.Email); aerr == nil {
+				if _, lerr := ensureIdentityForProfile(db, account.UserType, account.UUID); lerr == nil {
+					identity, err = findIdentity(db, req.Email, "")
+				}
 			}
 		}
-
-		if !found {
+		if err != nil {
 			if os.Getenv("ENV") == "development" {
 				log.Printf("[auth] login user not found email=%q", req.Email)
 			}
+			recordLoginFailure(db, c, req.Email, nil, "unknown_email")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password", "code": "invalid_credentials"})
 			return
 		}
 
-		// Check if account is active (NULL or empty status treated as inactive)
-		if user.Status != "active" {
+		// Check if the login is active (profiles are checked when one is picked)
+		if identity.Status != "active" {
+			recordLoginAttempt(db, c, req.Email, identity.UUID, false, "account_inactive")
 			c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active", "code": "account_inactive"})
 			return
 		}
 
 		// Account created via Google has no password; tell user to use Google sign-in
-		if user.Password == "" {
+		if identity.Password == nil || *identity.Password == "" {
+			recordLoginAttempt(db, c, req.Email, identity.UUID, false, "use_google_signin")
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "This account uses Google sign-in. Please sign in with Google.",
 				"code":  "use_google_signin",
@@ -386,52 +397,100 @@ func Login(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		// Verify password (plain text comparison)
-		if user.Password != req.Password {
-			if os.Getenv("ENV") == "development" {
-				log.Printf("[auth] login password mismatch email=%q (db_len=%d req_len=%d)", req.Email, len(user.Password), len(req.Password))
-			}
+		if *identity.Password != req.Password {
+			recordLoginFailure(db, c, req.Email, identity, "invalid_credentials")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password", "code": "invalid_credentials"})
 			return
 		}
+		clearLoginFailures(req.Email)
 
-		// Generate JWT token
-		avatar := ""
-		if user.AvatarURL != nil {
-			avatar = utils.MaskMediaURL(*user.AvatarURL)
+		if mustVerifyEmail(identity) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before logging in", "code": "email_not_verified"})
+			return
 		}
-		token, err := generateJWT(user.UUID, user.Email, user.Role, user.Type, user.FullName, avatar)
+
+		// Sign in as the requested profile type, or the most recently used active profile
+		profiles, err := identityProfiles(db, identity.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+			return
+		}
+		profile, ok := pickProfile(profiles, req.UserType)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active", "code": "account_inactive"})
+			return
+		}
+
+		// With 2FA on, the password only earns a challenge; VerifyTwoFactorChallenge starts the session
+		if twoFactorEnabled(identity) {
+			challenge, err := startTwoFactorChallenge(db, identity, profile)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor challenge"})
+				return
+			}
+			c.JSON(http.StatusOK, twoFactorChallengeBody(challenge))
+			return
+		}
+
+		// Generate JWT token
+		session := profileSession(identity, profile)
+		tokens, err := issueSession(db, c, session)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
 		}
+		touchProfile(db, profile.UUID)
+		recordLoginSuccess(db, c, identity, profile.ProfileID, profile.UserType, "password")
 
-		// Set Cookie using helper
-		setAuthCookie(c, token, 60*60*24*60) // 60 days
+		username := ""
+		if profile.Username != nil {
+			username = *profile.Username
+		}
 
 		// Log activity
-		utils.LogActivity(db, user.UUID, "", "user_logged_in", user.Type, user.UUID, "User logged in: "+user.Username, c.ClientIP(), c.Request.UserAgent())
+		utils.LogActivity(db, profile.ProfileID, "", "user_logged_in", profile.UserType, profile.ProfileID, "User logged in: "+username, c.ClientIP(), c.Request.UserAgent())
 
 		c.JSON(http.StatusOK, AuthResponse{
-			Token: token,
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int(accessTokenTTL.Seconds()),
 			User: gin.H{
-				"uuid":       user.UUID,
-				"id":         user.ID,
-				"username":   user.Username,
-				"full_name":  user.FullName,
-				"email":      user.Email,
-				"avatar_url": avatar,
-				"role":       user.Role,
-				"user_type":  user.Type,
+				"uuid":       profile.ProfileID,
+				"id":         profile.Code,
+				"username":   username,
+				"full_name":  profile.Name,
+				"email":      identity.Email,
+				"avatar_url": session.Avatar,
+				"role":       profile.UserType,
+				"user_type":  profile.UserType,
 			},
+			Profiles: profiles,
 		})
 	}
 }
 
-// Logout handles user logout by clearing the auth cookie
-func Logout() gin.HandlerFunc {
+// Logout revokes the current session and clears the auth cookies
+func Logout(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Clear cookie using helper (-1 maxAge means delete)
-		setAuthCookie(c, "", -1)
+		sessionID := c.GetString("session_id")
+		if sessionID == "" {
+			// Access token already expired: find the session through the refresh token
+			refreshToken, _ := c.Cookie("refresh_token")
+			if refreshToken == "" {
+				var req models.RefreshTokenRequest
+				c.ShouldBindJSON(&req)
+				refreshToken = req.RefreshToken
+			}
+			if refreshToken != "" {
+				db.Get(&sessionID, "SELECT session_id FROM auth_refresh_tokens WHERE token_hash = ?", hashToken(refreshToken))
+			}
+		}
+		if sessionID != "" {
+			revokeSession(db, sessionID, "logout")
+		}
+
+		// Clear cookies (-1 maxAge means delete)
+		clearSessionCookies(c)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 	}
@@ -522,22 +581,26 @@ func GetCurrentUser(db *sqlx.DB) gin.HandlerFunc {
 	}
 }
 
-// generateJWT generates a JWT token for the user
-func generateJWT(userID, email, role, userType, name, avatar string) (string, error) {
+// generateJWT generates a short-lived access token for a session of the profile
+func generateJWT(p sessionProfile, sessionID string) (string, error) {
 	secret := []byte(os.Getenv("JWT_SECRET"))
 	if len(secret) == 0 {
 		secret = []byte("archeryhub-secret-key-change-in-production")
 	}
 
 	claims := jwt.MapClaims{
-		"user_id":   userID,
-		"email":     email,
-		"name":      name,
-		"avatar":    avatar,
-		"role":      role,
-		"user_type": userType,
-		"exp":       time.Now().Add(time.Hour * 24 * 60).Unix(), // 60 days
-		"iat":       time.Now().Unix(),
+		"user_id":     p.UserID,
+		"identity_id": p.IdentityID,
+		"email":       p.Email,
+		"name":        p.Name,
+		"avatar":      p.Avatar,
+		"role":        p.Role,
+		"user_type":   p.UserType,
+		"sid":         sessionID,
+		"tfa":         p.TwoFactor,
+		"imp":         p.ImpersonatorID,
+		"exp":         time.Now().Add(accessTokenTTL).Unix(),
+		"iat":         time.Now().Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(secret)
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"math"
 	"net/http"
 	"strconv"
@@ -51,7 +52,10 @@ func CheckSlugAvailability(db *sqlx.DB) gin.HandlerFunc {
 // GetClubMe returns the club profile for the authenticated user
 func GetClubMe(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, _ := c.Get("user_id")
+		clubID, ok := requireClubPermission(db, c, clubPermEditProfile)
+		if !ok {
+			return
+		}
 
 		var club struct {
 			UUID             string  `json:"uuid" db:"uuid"`
@@ -81,7 +85,7 @@ func GetClubMe(db *sqlx.DB) gin.HandlerFunc {
 		err := db.Get(&club, `
 			SELECT uuid, name, slug, COALESCE(slug_changed, 0) as slug_changed, description, avatar_url, banner_url, logo_url, address, city, province, phone, email, website, social_facebook, social_instagram, established_date, facilities, training_schedule, social_media, page_settings 
 			FROM clubs 
-			WHERE uuid = ?`, userID)
+			WHERE uuid = ?`, clubID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
 			return
@@ -135,7 +139,11 @@ func GetClubMe(db *sqlx.DB) gin.HandlerFunc {
 // UpdateClubMe updates the club profile for the authenticated user
 func UpdateClubMe(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, _ := c.Get("user_id")
+		// Managers edit the profile on behalf of their club
+		clubID, ok := requireClubPermission(db, c, clubPermEditProfile)
+		if !ok {
+			return
+		}
 
 		var req struct {
 			Name         string        `json:"name"`
@@ -167,12 +175,12 @@ func UpdateClubMe(db *sqlx.DB) gin.HandlerFunc {
 		// Check if slug has already been changed
 		var currentSlug string
 		var slugChanged bool
-		err := db.Get(&currentSlug, "SELECT COALESCE(slug, '') FROM clubs WHERE uuid = ?", userID)
+		err := db.Get(&currentSlug, "SELECT COALESCE(slug, '') FROM clubs WHERE uuid = ?", clubID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
 			return
 		}
-		db.Get(&slugChanged, "SELECT COALESCE(slug_changed, 0) FROM clubs WHERE uuid = ?", userID)
+		db.Get(&slugChanged, "SELECT COALESCE(slug_changed, 0) FROM clubs WHERE uuid = ?", clubID)
 
 		// Determine if we should update the slug
 		newSlug := currentSlug
@@ -184,7 +192,7 @@ func UpdateClubMe(db *sqlx.DB) gin.HandlerFunc {
 			} else {
 				// Check if new slug is available
 				var count int
-				err := db.Get(&count, "SELECT COUNT(*) FROM clubs WHERE slug = ? AND uuid != ?", req.Slug, userID)
+				err := db.Get(&count, "SELECT COUNT(*) FROM clubs WHERE slug = ? AND uuid != ?", req.Slug, clubID)
 				if err == nil && count == 0 {
 					newSlug = req.Slug
 					newSlugChanged = true
@@ -226,7 +234,7 @@ func UpdateClubMe(db *sqlx.DB) gin.HandlerFunc {
 			req.Name, newSlug, newSlugChanged, req.Description, utils.ExtractFilename(req.BannerURL), utils.ExtractFilename(req.LogoURL), utils.ExtractFilename(req.LogoURL),
 			req.City, req.Province, establishedDate, req.Phone, req.Email,
 			req.Facebook, req.Instagram, req.Website, req.Address,
-			string(facilitiesJSON), string(schedulesJSON), string(socialMediaJSON), string(pageSettingsJSON), userID)
+			string(facilitiesJSON), string(schedulesJSON), string(socialMediaJSON), string(pageSettingsJSON), clubID)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update club: " + err.Error()})
@@ -334,6 +342,10 @@ func GetClubDashboardStats(db *sqlx.DB) gin.HandlerFunc {
 			ActiveArchers  int `json:"activeArchers"`
 			UpcomingEvents int `json:"upcomingEvents"`
 			TotalAwards    int `json:"totalAwards"`
+			// Dues for the current and past periods
+			DuesCollected   float64 `json:"duesCollected"`
+			DuesOutstanding float64 `json:"duesOutstanding"`
+			OverdueMembers  int     `json:"overdueMembers"`
 		}
 
 		// Total Members
@@ -348,6 +360,11 @@ func GetClubDashboardStats(db *sqlx.DB) gin.HandlerFunc {
 		// Total Awards (Generic count for now)
 		stats.TotalAwards = 0
 
+		// Dues
+		db.Get(&stats.DuesCollected, "SELECT COALESCE(SUM(amount), 0) FROM club_dues_invoices WHERE club_id = ? AND status = 'paid'", clubID)
+		db.Get(&stats.DuesOutstanding, "SELECT COALESCE(SUM(amount), 0) FROM club_dues_invoices WHERE club_id = ? AND status = 'unpaid'", clubID)
+		db.Get(&stats.OverdueMembers, "SELECT COUNT(*) FROM club_members WHERE club_id = ? AND (status = 'overdue' OR (status = 'suspended' AND suspension_reason = 'dues'))", clubID)
+
 		// Recent Members
 		var recentMembers []struct {
 			Name     string `json:"name" db:"name"`
@@ -396,8 +413,15 @@ type ClubMember struct {
 	Status    string     `json:"status" db:"status"`
 	Role      string     `json:"role" db:"role"`
 	JoinedAt  *time.Time `json:"joined_at" db:"joined_at"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	LeftAt    *time.Time `json:"left_at" db:"left_at"`
+	// SuspensionReason is "dues" when the member was suspended automatically for unpaid dues
+	SuspensionReason *string `json:"suspension_reason" db:"suspension_reason"`
+	// TransferFromClubID is set on a pending request that moves the archer from another club
+	TransferFromClubID *string   `json:"transfer_from_club_id" db:"transfer_from_club_id"`
+	// QRRaw is the membership code scanned at training check-in
+	QRRaw              *string   `json:"-" db:"qr_raw"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // GetClubs returns all clubs (public) with pagination and filtering
@@ -446,6 +470,7 @@ func GetClubs(db *sqlx.DB) gin.HandlerFunc {
 		// Fetch data
 		query := `
 			SELECT c.uuid, c.name, c.slug, c.avatar_url, c.banner_url, c.logo_url, c.city, c.province, c.phone, c.social_instagram,
+				   COALESCE(c.verification_status = 'verified', 0) as is_verified,
 				   (SELECT COUNT(*) FROM club_members WHERE club_id = c.uuid AND status = 'active') as member_count
 		` + baseQuery + ` ORDER BY c.name ASC LIMIT ? OFFSET ?`
 
@@ -462,6 +487,7 @@ func GetClubs(db *sqlx.DB) gin.HandlerFunc {
 			Province        *string  `json:"province" db:"province"`
 			Phone           *string  `json:"phone" db:"phone"`
 			SocialInstagram *string  `json:"social_instagram" db:"social_instagram"`
+			IsVerified      bool     `json:"is_verified" db:"is_verified"`
 			MemberCount     int      `json:"member_count" db:"member_count"`
 			MemberAvatars   []string `json:"member_avatars" db:"-"`
 		}
@@ -550,13 +576,15 @@ func GetClubBySlug(db *sqlx.DB) gin.HandlerFunc {
 			TrainingSchedule *string `json:"training_schedule" db:"training_schedule"`
 			SocialMedia      *string `json:"social_media" db:"social_media"`
 			PageSettings     *string `json:"page_settings" db:"page_settings"`
+			IsVerified       bool    `json:"is_verified" db:"is_verified"`
 			CreatedAt        string  `json:"created_at" db:"created_at"`
 		}
 
 		err := db.Get(&club, `
 			SELECT uuid, name, slug, description, avatar_url, banner_url, avatar_url as logo_url, 
 			       address, city, province, phone, email, website, social_facebook, social_instagram, 
-			       established_date, facilities, training_schedule, social_media, page_settings, created_at 
+			       established_date, facilities, training_schedule, social_media, page_settings,
+			       COALESCE(verification_status = 'verified', 0) as is_verified, created_at 
 			FROM clubs 
 			WHERE slug = ? OR uuid = ?`, slug, slug)
 		if err != nil {
@@ -663,6 +691,7 @@ func GetClubBySlug(db *sqlx.DB) gin.HandlerFunc {
 			"uuid":          club.UUID,
 			"name":          club.Name,
 			"slug":          club.Slug,
+			"is_verified":   club.IsVerified,
 			"description":   club.Description,
 			"avatar_url":    avatarURL,
 			"logo_url":      logoURL,
@@ -728,12 +757,14 @@ func JoinClub(db *sqlx.DB) gin.HandlerFunc {
 			ClubID string `db:"club_id"`
 			Status string `db:"status"`
 		}
-		err = db.Get(&existing, "SELECT club_id, status FROM club_members WHERE archer_id = ? AND status IN ('pending', 'active')", userID)
+		err = db.Get(&existing, "SELECT club_id, status FROM club_members WHERE archer_id = ? AND status IN ('pending', 'active', 'overdue') LIMIT 1", userID)
 		if err == nil {
 			if existing.ClubID == clubID {
 				c.JSON(http.StatusConflict, gin.H{"error": "You already have a membership request for this club"})
+			} else if existing.Status == "active" || existing.Status == "overdue" {
+				c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of another club. Request a transfer instead."})
 			} else {
-				c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of another club"})
+				c.JSON(http.StatusConflict, gin.H{"error": "You already have a pending membership request"})
 			}
 			return
 		}
@@ -750,6 +781,9 @@ func JoinClub(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		utils.Notify(db, clubID, "club", "info", "Permintaan keanggotaan baru",
+			fmt.Sprintf("%s ingin bergabung ke klub.", archerName(db, userID.(string))), "")
+
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "Membership request submitted successfully",
 			"id":      memberID,
@@ -771,7 +805,9 @@ func GetMyClubMembership(db *sqlx.DB) gin.HandlerFunc {
 			SELECT cm.*, c.name as club_name 
 			FROM club_members cm 
 			JOIN clubs c ON cm.club_id = c.uuid 
-			WHERE cm.archer_id = ? AND cm.status IN ('pending', 'active')
+			WHERE cm.archer_id = ? AND cm.status IN ('pending', 'active', 'overdue', 'suspended')
+			ORDER BY FIELD(cm.status, 'active', 'overdue', 'suspended', 'pending')
+			LIMIT 1
 		`, userID)
 
 		if err != nil {
@@ -788,62 +824,106 @@ func LeaveClub(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, _ := c.Get("user_id")
 
-		result, err := db.Exec(`
-			UPDATE club_members SET status = 'left', updated_at = NOW() 
-			WHERE archer_id = ? AND status = 'active'
-		`, userID)
-
+		var clubID string
+		err := db.Get(&clubID, "SELECT club_id FROM club_members WHERE archer_id = ? AND status IN ('active', 'overdue', 'suspended') LIMIT 1", userID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave club"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "No active membership found"})
 			return
 		}
 
-		rows, _ := result.RowsAffected()
-		if rows == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "No active membership found"})
+		_, err = db.Exec(`
+			UPDATE club_members SET status = 'left', left_at = NOW(), updated_at = NOW() 
+			WHERE archer_id = ? AND status IN ('active', 'overdue', 'suspended')
+		`, userID)
+		if err == nil {
+			err = endClubStaffRoles(db, userID.(string), "")
+		}
+		if err == nil {
+			err = syncArcherClub(db, userID.(string))
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave club"})
 			return
 		}
 
+		utils.Notify(db, clubID, "club", "info", "Anggota keluar",
+			fmt.Sprintf("%s telah keluar dari klub.", archerName(db, userID.(string))), "")
+
 		c.JSON(http.StatusOK, gin.H{"message": "Successfully left the club"})
 	}
 }
 
-// ApproveClubMember allows club admin to approve a membership request
+// ApproveClubMember allows club admin to approve a membership request. Approving a transfer
+// request ends the archer's membership at their previous club.
 func ApproveClubMember(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		memberID := c.Param("memberId")
-		userID, _ := c.Get("user_id")
-		userType, _ := c.Get("user_type")
-
-		// Only club admins can approve
-		if userType != "club" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Only club admins can approve members"})
+		clubID, ok := requireClubPermission(db, c, clubPermManageMembers)
+		if !ok {
+			return
+		}
+		member, ok := loadClubMembership(db, c, c.Param("memberId"), clubID)
+		if !ok {
+			return
+		}
+		if member.Status != "pending" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Membership request not found"})
 			return
 		}
 
-		// Verify the member belongs to the user's club
-		var clubID string
-		err := db.Get(&clubID, "SELECT uuid FROM clubs WHERE owner_id = ?", userID)
+		tx, err := db.Beginx()
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 			return
 		}
+		defer tx.Rollback()
 
-		now := time.Now()
-		result, err := db.Exec(`
-			UPDATE club_members SET status = 'active', joined_at = ?, updated_at = NOW() 
-			WHERE uuid = ? AND club_id = ? AND status = 'pending'
-		`, now, memberID, clubID)
+		// Any current membership ends when the new one starts
+		var previousClubID string
+		tx.Get(&previousClubID, "SELECT club_id FROM club_members WHERE archer_id = ? AND status IN ('active', 'overdue', 'suspended') LIMIT 1", member.ArcherID)
+		if previousClubID != "" && member.TransferFromClubID == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Archer is already a member of another club"})
+			return
+		}
 
+		_, err = tx.Exec(`
+			UPDATE club_members SET status = 'transferred', left_at = NOW(), updated_at = NOW()
+			WHERE archer_id = ? AND status IN ('active', 'overdue', 'suspended')
+		`, member.ArcherID)
+		if err == nil {
+			_, err = tx.Exec(`
+				UPDATE club_members SET status = 'active', joined_at = ?, updated_at = NOW() 
+				WHERE uuid = ?
+			`, time.Now(), member.UUID)
+		}
+		if err == nil {
+			_, err = tx.Exec(`
+				UPDATE club_members SET status = 'declined', updated_at = NOW()
+				WHERE archer_id = ? AND uuid != ? AND status = 'invited'
+			`, member.ArcherID, member.UUID)
+		}
+		if err == nil {
+			err = endClubStaffRoles(tx, member.ArcherID, clubID)
+		}
+		if err == nil && isClubStaffRole(member.Role) {
+			err = grantClubStaffRole(tx, clubID, member.ArcherID, "archer", member.Role, clubID)
+		}
+		if err == nil {
+			err = syncArcherClub(tx, member.ArcherID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve member"})
 			return
 		}
 
-		rows, _ := result.RowsAffected()
-		if rows == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Membership request not found"})
-			return
+		name := clubName(db, clubID)
+		utils.Notify(db, member.ArcherID, "archer", "success", "Keanggotaan disetujui",
+			fmt.Sprintf("Anda sekarang anggota %s.", name), "")
+		if previousClubID != "" {
+			utils.Notify(db, previousClubID, "club", "info", "Transfer anggota selesai",
+				fmt.Sprintf("%s telah pindah ke %s.", archerName(db, member.ArcherID), name), "")
 		}
 
 		c.JSON(http.StatusOK, gin.H{"message": "Member approved successfully"})
@@ -855,18 +935,31 @@ func GetClubMembers(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		clubID := c.Param("clubId")
 
+		// The public only sees active members; the club and its staff see requests and history too
+		query := `
+			SELECT cm.*, u.full_name as archer_name
+			FROM club_members cm
+			JOIN archers u ON cm.archer_id = u.uuid
+			WHERE cm.club_id = ?`
+		args := []interface{}{clubID}
+		userID, _ := c.Get("user_id")
+		userType, _ := c.Get("user_type")
+		uid, _ := userID.(string)
+		utype, _ := userType.(string)
+		if !hasClubPermission(db, uid, utype, clubID, clubPermManageMembers) {
+			query += " AND cm.status = 'active'"
+		} else if status := c.Query("status"); status != "" {
+			query += " AND cm.status = ?"
+			args = append(args, status)
+		}
+		query += " ORDER BY cm.status ASC, cm.created_at DESC"
+
 		var members []struct {
 			ClubMember
 			ArcherName string `json:"archer_name" db:"archer_name"`
 		}
 
-		err := db.Select(&members, `
-			SELECT cm.*, u.full_name as archer_name
-			FROM club_members cm
-			JOIN archers u ON cm.archer_id = u.uuid
-			WHERE cm.club_id = ?
-			ORDER BY cm.status ASC, cm.created_at DESC
-		`, clubID)
+		err := db.Select(&members, query, args...)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
@@ -887,18 +980,14 @@ func GetClubMembers(db *sqlx.DB) gin.HandlerFunc {
 // InviteToClub allows club admin to invite an archer
 func InviteToClub(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, _ := c.Get("user_id")
-		userType, _ := c.Get("user_type")
-
-		// Only club owners can invite
-		if userType != "club" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Only club owners can invite members"})
+		clubID, ok := requireClubPermission(db, c, clubPermManageMembers)
+		if !ok {
 			return
 		}
 
 		var req struct {
 			ArcherID string `json:"archer_id" binding:"required"`
-			Role     string `json:"role"`
+			Role     string `json:"role" binding:"omitempty,oneof=member head_coach coach manager"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -906,17 +995,9 @@ func InviteToClub(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Get club owned by user
-		var clubID string
-		err := db.Get(&clubID, "SELECT uuid FROM clubs WHERE owner_id = ?", userID)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
-			return
-		}
-
 		// Check if archer exists
 		var archerExists bool
-		err = db.Get(&archerExists, "SELECT EXISTS(SELECT 1 FROM archers WHERE uuid = ?)", req.ArcherID)
+		err := db.Get(&archerExists, "SELECT EXISTS(SELECT 1 FROM archers WHERE uuid = ?)", req.ArcherID)
 		if err != nil || !archerExists {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Archer not found"})
 			return
@@ -924,9 +1005,9 @@ func InviteToClub(db *sqlx.DB) gin.HandlerFunc {
 
 		// Check if archer already has membership
 		var existingMembership string
-		err = db.Get(&existingMembership, "SELECT club_id FROM club_members WHERE archer_id = ? AND status IN ('pending', 'active', 'invited')", req.ArcherID)
+		err = db.Get(&existingMembership, "SELECT club_id FROM club_members WHERE archer_id = ? AND club_id = ? AND status IN ('pending', 'active', 'overdue', 'invited', 'suspended') LIMIT 1", req.ArcherID, clubID)
 		if err == nil && existingMembership != "" {
-			c.JSON(http.StatusConflict, gin.H{"error": "Archer already has an active membership"})
+			c.JSON(http.StatusConflict, gin.H{"error": "Archer already has a membership or open invitation for this club"})
 			return
 		}
 
@@ -934,6 +1015,12 @@ func InviteToClub(db *sqlx.DB) gin.HandlerFunc {
 			req.Role = "member"
 		}
 
+		// Only the club account itself may hand out staff roles
+		if userType, _ := c.Get("user_type"); req.Role != "member" && userType != "club" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the club account can invite staff"})
+			return
+		}
+
 		// Create invitation
 		memberID := uuid.New().String()
 		_, err = db.Exec(`
@@ -946,6 +1033,9 @@ func InviteToClub(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		utils.Notify(db, req.ArcherID, "archer", "info", "Undangan klub",
+			fmt.Sprintf("%s mengundang Anda untuk bergabung ke klub.", clubName(db, clubID)), "")
+
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "Invitation sent successfully",
 			"id":      memberID,
@@ -0,0 +1,549 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// defaultDuesPaymentTermDays is how long members have to pay when the plan doesn't say
+const defaultDuesPaymentTermDays = 14
+
+// duesPeriod returns the billing period containing t
+func duesPeriod(period string, t time.Time) (start, end time.Time) {
+	if period == "annual" {
+		start = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(1, 0, -1)
+	}
+	start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 1, -1)
+}
+
+// generateClubDuesInvoices creates the current period's invoice for every billable member of
+// the plan's club. Members that already have an invoice for the period are skipped.
+func generateClubDuesInvoices(db *sqlx.DB, plan models.ClubDuesPlan, now time.Time) (int, error) {
+	start, end := duesPeriod(plan.Period, now)
+
+	// Members get the payment term from the period start, or from today when the invoice is
+	// issued later in the period (e.g. a plan created mid-period)
+	term := plan.PaymentTermDays
+	if term <= 0 {
+		term = defaultDuesPaymentTermDays
+	}
+	issued := start
+	if today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()); today.After(issued) {
+		issued = today
+	}
+	dueDate := issued.AddDate(0, 0, term)
+
+	var members []struct {
+		UUID     string `db:"uuid"`
+		ArcherID string `db:"archer_id"`
+	}
+	err := db.Select(&members, `
+		SELECT cm.uuid, cm.archer_id
+		FROM club_members cm
+		WHERE cm.club_id = ?
+		  AND (cm.status IN ('active', 'overdue') OR (cm.status = 'suspended' AND cm.suspension_reason = 'dues'))
+		  AND NOT EXISTS (
+			SELECT 1 FROM club_dues_invoices i WHERE i.member_id = cm.uuid AND i.period_start = ?
+		  )
+	`, plan.ClubID, start)
+	if err != nil {
+		return 0, err
+	}
+
+	name := clubName(db, plan.ClubID)
+	created := 0
+	for _, m := range members {
+		_, err := db.Exec(`
+			INSERT INTO club_dues_invoices (uuid, club_id, plan_id, member_id, archer_id, period_start, period_end, amount, due_date, grace_days, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'unpaid')
+		`, uuid.New().String(), plan.ClubID, plan.UUID, m.UUID, m.ArcherID, start, end, plan.Amount, dueDate, plan.GraceDays)
+		if err != nil {
+			return created, err
+		}
+		created++
+		utils.Notify(db, m.ArcherID, "archer", "info", "Tagihan iuran klub",
+			fmt.Sprintf("Iuran %s periode %s sebesar Rp %.0f telah terbit.", name, start.Format("Jan 2006"), plan.Amount), "")
+	}
+
+	return created, nil
+}
+
+// applyDuesDelinquency marks members with unpaid dues past the due date as overdue, and
+// suspends them once the grace period has also passed
+func applyDuesDelinquency(db *sqlx.DB) error {
+	var delinquent []struct {
+		MemberID string `db:"member_id"`
+		ClubID   string `db:"club_id"`
+		ArcherID string `db:"archer_id"`
+		Status   string `db:"status"`
+		Suspend  bool   `db:"suspend"`
+	}
+	err := db.Select(&delinquent, `
+		SELECT cm.uuid as member_id, cm.club_id, cm.archer_id, cm.status,
+			MAX(DATE_ADD(i.due_date, INTERVAL i.grace_days DAY) < CURDATE()) as suspend
+		FROM club_members cm
+		JOIN club_dues_invoices i ON i.member_id = cm.uuid AND i.status = 'unpaid' AND i.due_date < CURDATE()
+		WHERE cm.status IN ('active', 'overdue')
+		GROUP BY cm.uuid, cm.club_id, cm.archer_id, cm.status
+	`)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range delinquent {
+		name := clubName(db, d.ClubID)
+		if d.Suspend {
+			_, err := db.Exec("UPDATE club_members SET status = 'suspended', suspension_reason = 'dues', updated_at = NOW() WHERE uuid = ?", d.MemberID)
+			if err != nil {
+				return err
+			}
+			syncArcherClub(db, d.ArcherID)
+			utils.Notify(db, d.ArcherID, "archer", "danger", "Keanggotaan ditangguhkan",
+				fmt.Sprintf("Keanggotaan Anda di %s ditangguhkan karena iuran belum dibayar.", name), "")
+			utils.Notify(db, d.ClubID, "club", "warning", "Anggota ditangguhkan",
+				fmt.Sprintf("%s ditangguhkan karena iuran belum dibayar.", archerName(db, d.ArcherID)), "")
+			continue
+		}
+
+		if d.Status == "active" {
+			_, err := db.Exec("UPDATE club_members SET status = 'overdue', updated_at = NOW() WHERE uuid = ?", d.MemberID)
+			if err != nil {
+				return err
+			}
+			utils.Notify(db, d.ArcherID, "archer", "warning", "Iuran klub terlambat",
+				fmt.Sprintf("Iuran Anda di %s sudah jatuh tempo. Segera lakukan pembayaran.", name), "")
+		}
+	}
+
+	return nil
+}
+
+// settleDuesInvoice marks an invoice as paid and restores the membership when no other
+// overdue dues remain. Manual suspensions are left untouched.
+func settleDuesInvoice(tx *sqlx.Tx, invoiceID, transactionID string) error {
+	var invoice models.ClubDuesInvoice
+	if err := tx.Get(&invoice, "SELECT * FROM club_dues_invoices WHERE uuid = ? FOR UPDATE", invoiceID); err != nil {
+		return err
+	}
+	if invoice.Status == "paid" {
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		UPDATE club_dues_invoices SET status = 'paid', paid_at = NOW(), transaction_id = COALESCE(?, transaction_id)
+		WHERE uuid = ?
+	`, nullIfEmpty(transactionID), invoiceID)
+	if err != nil {
+		return err
+	}
+
+	var stillOverdue bool
+	err = tx.Get(&stillOverdue, `
+		SELECT EXISTS(SELECT 1 FROM club_dues_invoices WHERE member_id = ? AND status = 'unpaid' AND due_date < CURDATE())
+	`, invoice.MemberID)
+	if err != nil || stillOverdue {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE club_members SET status = 'active', suspension_reason = NULL, updated_at = NOW()
+		WHERE uuid = ? AND (status = 'overdue' OR (status = 'suspended' AND suspension_reason = 'dues'))
+	`, invoice.MemberID)
+	if err != nil {
+		return err
+	}
+	return syncArcherClub(tx, invoice.ArcherID)
+}
+
+// nullIfEmpty converts an empty string to a NULL parameter
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// RunClubDuesCycle generates invoices for all active dues plans and updates delinquent members
+func RunClubDuesCycle(db *sqlx.DB) (int, error) {
+	var plans []models.ClubDuesPlan
+	if err := db.Select(&plans, "SELECT * FROM club_dues_plans WHERE status = 'active'"); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	created := 0
+	for _, plan := range plans {
+		n, err := generateClubDuesInvoices(db, plan, now)
+		created += n
+		if err != nil {
+			log.Printf("[dues] invoice generation failed club=%s: %v", plan.ClubID, err)
+		}
+	}
+
+	return created, applyDuesDelinquency(db)
+}
+
+// StartClubDuesScheduler runs RunClubDuesCycle on a fixed interval in the background
+func StartClubDuesScheduler(db *sqlx.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			created, err := RunClubDuesCycle(db)
+			if err != nil {
+				log.Printf("[dues] cycle failed: %v", err)
+				continue
+			}
+			if created > 0 {
+				log.Printf("[dues] generated %d invoices", created)
+			}
+		}
+	}()
+}
+
+// GetClubDuesPlan returns the dues plan of the current club
+func GetClubDuesPlan(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageDues)
+		if !ok {
+			return
+		}
+
+		var plan models.ClubDuesPlan
+		err := db.Get(&plan, "SELECT * FROM club_dues_plans WHERE club_id = ?", clubID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusOK, gin.H{"data": nil})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dues plan"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": plan})
+	}
+}
+
+// UpdateClubDuesPlan creates or replaces the dues plan of the current club. Changes apply
+// from the next invoice on; existing invoices keep their amount.
+func UpdateClubDuesPlan(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageDues)
+		if !ok {
+			return
+		}
+
+		var req models.ClubDuesPlanRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Status == "" {
+			req.Status = "active"
+		}
+		if req.PaymentTermDays == 0 {
+			req.PaymentTermDays = defaultDuesPaymentTermDays
+		}
+
+		var planID string
+		err := db.Get(&planID, "SELECT uuid FROM club_dues_plans WHERE club_id = ?", clubID)
+		if err == sql.ErrNoRows {
+			planID = uuid.New().String()
+			_, err = db.Exec(`
+				INSERT INTO club_dues_plans (uuid, club_id, amount, period, payment_term_days, grace_days, status)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, planID, clubID, req.Amount, req.Period, req.PaymentTermDays, req.GraceDays, req.Status)
+		} else if err == nil {
+			_, err = db.Exec(`
+				UPDATE club_dues_plans SET amount = ?, period = ?, payment_term_days = ?, grace_days = ?, status = ?, updated_at = NOW()
+				WHERE uuid = ?
+			`, req.Amount, req.Period, req.PaymentTermDays, req.GraceDays, req.Status, planID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save dues plan"})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		utils.LogActivity(db, userID.(string), "", "club_dues_plan_updated", "club_dues_plan", planID, fmt.Sprintf("Dues plan set to %.0f %s", req.Amount, req.Period), c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Dues plan saved", "id": planID})
+	}
+}
+
+// GenerateClubDuesInvoices issues the current period's invoices of the current club right away
+func GenerateClubDuesInvoices(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageDues)
+		if !ok {
+			return
+		}
+
+		var plan models.ClubDuesPlan
+		if err := db.Get(&plan, "SELECT * FROM club_dues_plans WHERE club_id = ? AND status = 'active'", clubID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Club has no active dues plan"})
+			return
+		}
+
+		created, err := generateClubDuesInvoices(db, plan, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invoices"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Invoices generated", "created": created})
+	}
+}
+
+// GetClubDuesInvoices lists the dues invoices of the current club
+func GetClubDuesInvoices(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageDues)
+		if !ok {
+			return
+		}
+
+		query := `
+			SELECT i.*, COALESCE(a.full_name, '') as archer_name
+			FROM club_dues_invoices i
+			LEFT JOIN archers a ON i.archer_id = a.uuid
+			WHERE i.club_id = ?
+		`
+		args := []interface{}{clubID}
+		if status := c.Query("status"); status != "" {
+			query += " AND i.status = ?"
+			args = append(args, status)
+		}
+		query += " ORDER BY i.period_start DESC, archer_name ASC"
+
+		var invoices []struct {
+			models.ClubDuesInvoice
+			ArcherName string `json:"archer_name" db:"archer_name"`
+		}
+		if err := db.Select(&invoices, query, args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+			return
+		}
+
+		if invoices == nil {
+			invoices = []struct {
+				models.ClubDuesInvoice
+				ArcherName string `json:"archer_name" db:"archer_name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": invoices})
+	}
+}
+
+// MarkClubDuesPaid records an offline (cash or transfer) payment of an invoice
+func MarkClubDuesPaid(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageDues)
+		if !ok {
+			return
+		}
+		invoiceID := c.Param("invoiceId")
+
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM club_dues_invoices WHERE uuid = ? AND club_id = ? AND status = 'unpaid')", invoiceID, clubID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unpaid invoice not found"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if err := settleDuesInvoice(tx, invoiceID, ""); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark invoice as paid"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark invoice as paid"})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		utils.LogActivity(db, userID.(string), "", "club_dues_paid_offline", "club_dues_invoice", invoiceID, "Marked dues invoice as paid", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Invoice marked as paid"})
+	}
+}
+
+// GetMyDuesInvoices lists the dues invoices of the current archer
+func GetMyDuesInvoices(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var invoices []struct {
+			models.ClubDuesInvoice
+			ClubName string `json:"club_name" db:"club_name"`
+		}
+		err := db.Select(&invoices, `
+			SELECT i.*, c.name as club_name
+			FROM club_dues_invoices i
+			JOIN clubs c ON i.club_id = c.uuid
+			WHERE i.archer_id = ?
+			ORDER BY i.status = 'unpaid' DESC, i.period_start DESC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+			return
+		}
+
+		if invoices == nil {
+			invoices = []struct {
+				models.ClubDuesInvoice
+				ClubName string `json:"club_name" db:"club_name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": invoices})
+	}
+}
+
+// PayClubDuesInvoice creates a Tripay transaction for one of the current archer's invoices.
+// The callback settles the invoice through the payment ledger.
+func PayClubDuesInvoice(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoiceID := c.Param("invoiceId")
+		userID, _ := c.Get("user_id")
+
+		var req models.PayDuesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var invoice struct {
+			models.ClubDuesInvoice
+			ClubName    string  `db:"club_name"`
+			ArcherName  string  `db:"archer_name"`
+			ArcherEmail *string `db:"archer_email"`
+			ArcherPhone *string `db:"archer_phone"`
+		}
+		err := db.Get(&invoice, `
+			SELECT i.*, c.name as club_name, a.full_name as archer_name, a.email as archer_email, a.phone as archer_phone
+			FROM club_dues_invoices i
+			JOIN clubs c ON i.club_id = c.uuid
+			JOIN archers a ON i.archer_id = a.uuid
+			WHERE i.uuid = ? AND i.archer_id = ?
+		`, invoiceID, userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+			return
+		}
+		if invoice.Status != "unpaid" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice is not payable"})
+			return
+		}
+
+		// An open payment for the same channel is handed out again instead of starting another one
+		var open models.PaymentTransaction
+		err = db.Get(&open, `
+			SELECT * FROM payment_transactions
+			WHERE dues_invoice_id = ? AND status = 'pending' AND payment_method = ? AND expired_at > NOW()
+			ORDER BY created_at DESC LIMIT 1
+		`, invoiceID, req.Method)
+		if err == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"reference":    open.TripayReference,
+				"merchant_ref": open.Reference,
+				"amount":       open.TotalAmount,
+				"pay_code":     open.PayCode,
+				"qr_url":       open.QRURL,
+				"checkout_url": open.CheckoutURL,
+				"expiry_date":  open.ExpiredAt.Unix(),
+			})
+			return
+		}
+
+		amount := int(invoice.Amount)
+		tripay := utils.NewTripayClient()
+		merchantRef := fmt.Sprintf("DUES-%s", uuid.New().String()[:12])
+
+		tripayResult, err := tripay.CreateTransaction(gin.H{
+			"method":         req.Method,
+			"merchant_ref":   merchantRef,
+			"amount":         amount,
+			"customer_name":  invoice.ArcherName,
+			"customer_email": utils.StringValue(invoice.ArcherEmail, "user@archeryhub.id"),
+			"customer_phone": utils.StringValue(invoice.ArcherPhone, ""),
+			"order_items": []gin.H{
+				{
+					"sku":      "CLUB-DUES",
+					"name":     fmt.Sprintf("Iuran %s - %s", invoice.ClubName, invoice.PeriodStart.Format("Jan 2006")),
+					"price":    amount,
+					"quantity": 1,
+				},
+			},
+			"signature":    tripay.GenerateSignature(merchantRef, amount),
+			"expired_time": time.Now().Add(24 * time.Hour).Unix(),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Tripay transaction: " + err.Error()})
+			return
+		}
+
+		tripayRef, _ := tripayResult["reference"].(string)
+		expiredAt := time.Now().Add(24 * time.Hour)
+		if exp, ok := tripayResult["expiry_date"].(float64); ok {
+			expiredAt = time.Unix(int64(exp), 0)
+		}
+		feeMerchant, _ := tripayResult["fee_merchant"].(float64)
+		feeCustomer, _ := tripayResult["fee_customer"].(float64)
+
+		transaction := models.PaymentTransaction{
+			UUID:            uuid.New().String(),
+			Reference:       merchantRef,
+			TripayReference: &tripayRef,
+			UserID:          userID.(string),
+			DuesInvoiceID:   &invoiceID,
+			Amount:          float64(amount),
+			FeeAmount:       feeMerchant + feeCustomer,
+			FeeMerchant:     feeMerchant,
+			FeeCustomer:     feeCustomer,
+			TotalAmount:     float64(amount) + feeCustomer,
+			PaymentMethod:   utils.StringPtr(req.Method),
+			VANumber:        utils.InterfaceToStringPtr(tripayResult["pay_code"]),
+			QRURL:           utils.InterfaceToStringPtr(tripayResult["qr_url"]),
+			CheckoutURL:     utils.InterfaceToStringPtr(tripayResult["checkout_url"]),
+			PayCode:         utils.InterfaceToStringPtr(tripayResult["pay_code"]),
+			Status:          "pending",
+			ExpiredAt:       expiredAt,
+		}
+		_, err = db.NamedExec(`
+			INSERT INTO payment_transactions (
+				uuid, reference, tripay_reference, user_id, dues_invoice_id,
+				amount, fee_amount, fee_merchant, fee_customer, total_amount, payment_method, va_number, qr_url,
+				checkout_url, pay_code, status, expired_at
+			) VALUES (
+				:uuid, :reference, :tripay_reference, :user_id, :dues_invoice_id,
+				:amount, :fee_amount, :fee_merchant, :fee_customer, :total_amount, :payment_method, :va_number, :qr_url,
+				:checkout_url, :pay_code, :status, :expired_at
+			)
+		`, transaction)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save transaction: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tripayResult)
+	}
+}
@@ -0,0 +1,428 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"archeryhub-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// clubTransferCoolingOff is how long an archer must stay with a club before transferring again
+const clubTransferCoolingOff = 90 * 24 * time.Hour
+
+// syncArcherClub derives archers.club_id from the archer's active membership (NULL when none).
+// Members with overdue dues still belong to the club until they are suspended.
+func syncArcherClub(q sqlx.Execer, archerID string) error {
+	_, err := q.Exec(`
+		UPDATE archers SET club_id = (
+			SELECT club_id FROM club_members
+			WHERE archer_id = ? AND status IN ('active', 'overdue')
+			ORDER BY joined_at DESC
+			LIMIT 1
+		), updated_at = NOW()
+		WHERE uuid = ?
+	`, archerID, archerID)
+	return err
+}
+
+// canAssignClub reports whether the current user may move the archer into clubID directly,
+// bypassing the join request. Admins always may; otherwise the user must manage members of the
+// archer's current club (if any) and of the new club (if any). Everyone else goes through
+// JoinClub or a transfer request.
+func canAssignClub(db *sqlx.DB, c *gin.Context, archerID, clubID string) bool {
+	if c.GetString("role") == "admin" {
+		return true
+	}
+	uid := c.GetString("user_id")
+	utype := c.GetString("user_type")
+
+	var current sql.NullString
+	db.Get(&current, "SELECT club_id FROM archers WHERE uuid = ?", archerID)
+	if current.Valid && current.String != "" && current.String != clubID &&
+		!hasClubPermission(db, uid, utype, current.String, clubPermManageMembers) {
+		return false
+	}
+	return clubID == "" || hasClubPermission(db, uid, utype, clubID, clubPermManageMembers)
+}
+
+// assignClubMembership makes clubID the archer's only active club, ending any other active
+// membership. An empty clubID removes the archer from their club. Used for direct edits by staff.
+func assignClubMembership(db *sqlx.DB, archerID, clubID string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE club_members SET status = 'left', left_at = NOW(), updated_at = NOW()
+		WHERE archer_id = ? AND status IN ('active', 'overdue') AND club_id != ?
+	`, archerID, clubID)
+	if err != nil {
+		return err
+	}
+
+	if clubID != "" {
+		var existing string
+		err = tx.Get(&existing, "SELECT uuid FROM club_members WHERE archer_id = ? AND club_id = ? AND status IN ('active', 'overdue')", archerID, clubID)
+		if err == sql.ErrNoRows {
+			_, err = tx.Exec(`
+				INSERT INTO club_members (uuid, club_id, archer_id, status, role, joined_at)
+				VALUES (?, ?, ?, 'active', 'member', NOW())
+			`, uuid.New().String(), clubID, archerID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := endClubStaffRoles(tx, archerID, clubID); err != nil {
+		return err
+	}
+	if err := syncArcherClub(tx, archerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// transferEligibleAt returns when the archer may leave the given membership for another club,
+// and whether that moment has passed
+func transferEligibleAt(current ClubMember) (time.Time, bool) {
+	if current.JoinedAt == nil {
+		return time.Time{}, true
+	}
+	eligibleAt := current.JoinedAt.Add(clubTransferCoolingOff)
+	return eligibleAt, !time.Now().Before(eligibleAt)
+}
+
+// currentClubID returns the club managed by the current user, writing 403 when the user is not a club
+func currentClubID(c *gin.Context) (string, bool) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+	if userType != "club" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only club admins can manage members"})
+		return "", false
+	}
+	return userID.(string), true
+}
+
+// loadClubMembership fetches a membership of the given club, writing 404 when it doesn't exist
+func loadClubMembership(db *sqlx.DB, c *gin.Context, memberID, clubID string) (*ClubMember, bool) {
+	var member ClubMember
+	err := db.Get(&member, "SELECT * FROM club_members WHERE uuid = ? AND club_id = ?", memberID, clubID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Membership not found"})
+		return nil, false
+	}
+	return &member, true
+}
+
+// clubName returns the display name of a club for notifications
+func clubName(db sqlx.Queryer, clubID string) string {
+	var name string
+	sqlx.Get(db, &name, "SELECT name FROM clubs WHERE uuid = ?", clubID)
+	return name
+}
+
+// archerName returns the display name of an archer for notifications
+func archerName(db sqlx.Queryer, archerID string) string {
+	var name string
+	sqlx.Get(db, &name, "SELECT full_name FROM archers WHERE uuid = ?", archerID)
+	return name
+}
+
+// respondToClubInvitation accepts or declines an invitation addressed to the current archer
+func respondToClubInvitation(db *sqlx.DB, c *gin.Context, accept bool) {
+	memberID := c.Param("memberId")
+	userID, _ := c.Get("user_id")
+
+	var member ClubMember
+	err := db.Get(&member, "SELECT * FROM club_members WHERE uuid = ? AND archer_id = ? AND status = 'invited'", memberID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	name := archerName(db, member.ArcherID)
+	if !accept {
+		db.Exec("UPDATE club_members SET status = 'declined', updated_at = NOW() WHERE uuid = ?", memberID)
+		utils.Notify(db, member.ClubID, "club", "info", "Undangan ditolak",
+			fmt.Sprintf("%s menolak undangan bergabung ke klub.", name), "")
+		c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+		return
+	}
+
+	// Accepting an invitation from another club while being a member is a transfer
+	var current ClubMember
+	hasCurrent := db.Get(&current, "SELECT * FROM club_members WHERE archer_id = ? AND status IN ('active', 'overdue', 'suspended') LIMIT 1", userID) == nil
+	if hasCurrent {
+		if eligibleAt, ok := transferEligibleAt(current); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":       "Transfer is not allowed during the cooling-off period",
+				"eligible_at": eligibleAt,
+			})
+			return
+		}
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE club_members SET status = 'transferred', left_at = NOW(), updated_at = NOW()
+		WHERE archer_id = ? AND status IN ('active', 'overdue', 'suspended')
+	`, member.ArcherID)
+	if err == nil {
+		_, err = tx.Exec("UPDATE club_members SET status = 'active', joined_at = NOW(), updated_at = NOW() WHERE uuid = ?", memberID)
+	}
+	if err == nil {
+		// Other outstanding requests and invitations are no longer relevant
+		_, err = tx.Exec(`
+			UPDATE club_members SET status = 'declined', updated_at = NOW()
+			WHERE archer_id = ? AND uuid != ? AND status IN ('pending', 'invited')
+		`, member.ArcherID, memberID)
+	}
+	if err == nil {
+		err = endClubStaffRoles(tx, member.ArcherID, member.ClubID)
+	}
+	if err == nil && isClubStaffRole(member.Role) {
+		// Staff invitations carry their role into the club's staff list
+		err = grantClubStaffRole(tx, member.ClubID, member.ArcherID, "archer", member.Role, member.ClubID)
+	}
+	if err == nil {
+		err = syncArcherClub(tx, member.ArcherID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	utils.Notify(db, member.ClubID, "club", "success", "Undangan diterima",
+		fmt.Sprintf("%s telah bergabung ke klub.", name), "")
+	if hasCurrent {
+		utils.Notify(db, current.ClubID, "club", "info", "Transfer anggota selesai",
+			fmt.Sprintf("%s telah pindah ke %s.", name, clubName(db, member.ClubID)), "")
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted"})
+}
+
+// AcceptClubInvitation lets an archer accept an invitation to join a club
+func AcceptClubInvitation(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		respondToClubInvitation(db, c, true)
+	}
+}
+
+// DeclineClubInvitation lets an archer decline an invitation to join a club
+func DeclineClubInvitation(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		respondToClubInvitation(db, c, false)
+	}
+}
+
+// RejectClubMember lets a club reject a pending membership or transfer request
+func RejectClubMember(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageMembers)
+		if !ok {
+			return
+		}
+		member, ok := loadClubMembership(db, c, c.Param("memberId"), clubID)
+		if !ok {
+			return
+		}
+		if member.Status != "pending" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending requests can be rejected"})
+			return
+		}
+
+		if _, err := db.Exec("UPDATE club_members SET status = 'rejected', updated_at = NOW() WHERE uuid = ?", member.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject member"})
+			return
+		}
+
+		utils.Notify(db, member.ArcherID, "archer", "danger", "Permintaan keanggotaan ditolak",
+			fmt.Sprintf("Permintaan Anda untuk bergabung ke %s ditolak.", clubName(db, clubID)), "")
+		c.JSON(http.StatusOK, gin.H{"message": "Membership request rejected"})
+	}
+}
+
+// setClubMemberSuspended suspends an active member or reinstates a suspended one
+func setClubMemberSuspended(db *sqlx.DB, c *gin.Context, suspend bool) {
+	clubID, ok := requireClubPermission(db, c, clubPermManageMembers)
+	if !ok {
+		return
+	}
+	member, ok := loadClubMembership(db, c, c.Param("memberId"), clubID)
+	if !ok {
+		return
+	}
+
+	// Members behind on dues can be suspended too; lifting a suspension makes them active again
+	from, to := "suspended", "active"
+	if suspend {
+		from, to = "active", "suspended"
+	}
+	if member.Status != from && !(suspend && member.Status == "overdue") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Member is not %s", from)})
+		return
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE club_members SET status = ?, suspension_reason = NULL, updated_at = NOW() WHERE uuid = ?", to, member.UUID)
+	if err == nil {
+		err = syncArcherClub(tx, member.ArcherID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update membership"})
+		return
+	}
+
+	name := clubName(db, clubID)
+	if suspend {
+		utils.Notify(db, member.ArcherID, "archer", "warning", "Keanggotaan ditangguhkan",
+			fmt.Sprintf("Keanggotaan Anda di %s ditangguhkan.", name), "")
+		c.JSON(http.StatusOK, gin.H{"message": "Member suspended"})
+		return
+	}
+	utils.Notify(db, member.ArcherID, "archer", "success", "Keanggotaan diaktifkan kembali",
+		fmt.Sprintf("Keanggotaan Anda di %s telah diaktifkan kembali.", name), "")
+	c.JSON(http.StatusOK, gin.H{"message": "Member reinstated"})
+}
+
+// SuspendClubMember suspends an active member of the current club
+func SuspendClubMember(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setClubMemberSuspended(db, c, true)
+	}
+}
+
+// ReinstateClubMember reactivates a suspended member of the current club
+func ReinstateClubMember(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setClubMemberSuspended(db, c, false)
+	}
+}
+
+// RequestClubTransfer lets an active member ask to move to another club. The new club approves
+// it like a join request; the archer must have been with the current club for the cooling-off period.
+func RequestClubTransfer(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetClubID := c.Param("clubId")
+		userID, _ := c.Get("user_id")
+		userType, _ := c.Get("user_type")
+
+		if userType != "archer" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only archers can transfer clubs"})
+			return
+		}
+
+		var clubExists bool
+		err := db.Get(&clubExists, "SELECT EXISTS(SELECT 1 FROM clubs WHERE uuid = ?)", targetClubID)
+		if err != nil || !clubExists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+			return
+		}
+
+		var current ClubMember
+		err = db.Get(&current, "SELECT * FROM club_members WHERE archer_id = ? AND status IN ('active', 'overdue')", userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You don't have an active club membership. Join the club instead."})
+			return
+		}
+		if current.ClubID == targetClubID {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of this club"})
+			return
+		}
+
+		if eligibleAt, ok := transferEligibleAt(current); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":       "Transfer is not allowed during the cooling-off period",
+				"eligible_at": eligibleAt,
+			})
+			return
+		}
+
+		var pending bool
+		db.Get(&pending, "SELECT EXISTS(SELECT 1 FROM club_members WHERE archer_id = ? AND status = 'pending')", userID)
+		if pending {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a pending membership request"})
+			return
+		}
+
+		memberID := uuid.New().String()
+		_, err = db.Exec(`
+			INSERT INTO club_members (uuid, club_id, archer_id, status, role, transfer_from_club_id)
+			VALUES (?, ?, ?, 'pending', 'member', ?)
+		`, memberID, targetClubID, userID, current.ClubID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer request"})
+			return
+		}
+
+		name := archerName(db, current.ArcherID)
+		utils.Notify(db, targetClubID, "club", "info", "Permintaan transfer",
+			fmt.Sprintf("%s mengajukan transfer dari %s.", name, clubName(db, current.ClubID)), "")
+		utils.Notify(db, current.ClubID, "club", "warning", "Anggota mengajukan transfer",
+			fmt.Sprintf("%s mengajukan transfer ke %s.", name, clubName(db, targetClubID)), "")
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Transfer request submitted successfully",
+			"id":      memberID,
+		})
+	}
+}
+
+// GetMyClubInvitations returns the open invitations of the current archer
+func GetMyClubInvitations(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var invitations []struct {
+			ClubMember
+			ClubName string `json:"club_name" db:"club_name"`
+		}
+		err := db.Select(&invitations, `
+			SELECT cm.*, c.name as club_name
+			FROM club_members cm
+			JOIN clubs c ON cm.club_id = c.uuid
+			WHERE cm.archer_id = ? AND cm.status = 'invited'
+			ORDER BY cm.created_at DESC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+			return
+		}
+
+		if invitations == nil {
+			invitations = []struct {
+				ClubMember
+				ClubName string `json:"club_name" db:"club_name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": invitations})
+	}
+}
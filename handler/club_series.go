@@ -0,0 +1,523 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// defaultSeriesPointsTable scores the top 15 placings when a series doesn't set its own table
+var defaultSeriesPointsTable = []int{25, 20, 16, 13, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+// defaultSeriesBestPerCategory counts a club's best 3 archers in each event category
+const defaultSeriesBestPerCategory = 3
+
+// loadManagedSeries fetches a series the current user may manage: its organization or an admin
+func loadManagedSeries(db *sqlx.DB, c *gin.Context, seriesID string) (*models.ClubSeries, bool) {
+	var series models.ClubSeries
+	if err := db.Get(&series, "SELECT * FROM club_series WHERE uuid = ?", seriesID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return nil, false
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	if role != "admin" && (series.OrganizationID == nil || *series.OrganizationID != userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the series organizer can perform this action"})
+		return nil, false
+	}
+	return &series, true
+}
+
+// loadPublishedSeries fetches a published series for the public endpoints
+func loadPublishedSeries(db *sqlx.DB, c *gin.Context, seriesID string) (*models.ClubSeries, bool) {
+	var series models.ClubSeries
+	if err := db.Get(&series, "SELECT * FROM club_series WHERE uuid = ? AND status = 'published'", seriesID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return nil, false
+	}
+	return &series, true
+}
+
+// seriesPointsJSON encodes a points table, falling back to the default table
+func seriesPointsJSON(points []int) string {
+	if len(points) == 0 {
+		points = defaultSeriesPointsTable
+	}
+	b, _ := json.Marshal(points)
+	return string(b)
+}
+
+// buildClubStandings scores every category of the series events and aggregates the points per
+// club. Only completed events count. Each archer's placing scores from the points table under
+// the club they belonged to at the event; only a club's best N placings in each event category count.
+func buildClubStandings(db *sqlx.DB, series *models.ClubSeries) ([]*models.ClubStanding, error) {
+	var points []int
+	if err := json.Unmarshal([]byte(series.PointsTable), &points); err != nil || len(points) == 0 {
+		points = defaultSeriesPointsTable
+	}
+
+	var categories []struct {
+		EventID      string `db:"event_id"`
+		EventName    string `db:"event_name"`
+		CategoryID   string `db:"category_id"`
+		CategoryName string `db:"category_name"`
+	}
+	err := db.Select(&categories, `
+		SELECT e.uuid as event_id, e.name as event_name, ec.uuid as category_id,
+			COALESCE(NULLIF(TRIM(CONCAT(COALESCE(rbt.name, ''), ' ', COALESCE(rag.name, ''), ' ', COALESCE(rgd.name, ''))), ''), 'Unknown Category') as category_name
+		FROM club_series_events cse
+		JOIN events e ON cse.event_id = e.uuid
+		JOIN event_categories ec ON ec.event_id = e.uuid
+		LEFT JOIN ref_bow_types rbt ON ec.division_uuid = rbt.uuid
+		LEFT JOIN ref_age_groups rag ON ec.category_uuid = rag.uuid
+		LEFT JOIN ref_gender_divisions rgd ON ec.gender_division_uuid = rgd.uuid
+		WHERE cse.series_id = ? AND e.status = 'completed'
+		ORDER BY e.start_date ASC, category_name ASC
+	`, series.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	standings := map[string]*models.ClubStanding{}
+	clubEvents := map[string]map[string]bool{}
+	clubArchers := map[string]map[string]bool{}
+
+	for _, cat := range categories {
+		placings, err := categoryFinalPlacings(db, cat.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		counted := map[string]int{}
+		for _, p := range placings {
+			if p.ClubID == nil || *p.ClubID == "" || p.Place > len(points) || points[p.Place-1] <= 0 {
+				continue
+			}
+			clubID := *p.ClubID
+
+			standing, ok := standings[clubID]
+			if !ok {
+				standing = &models.ClubStanding{ClubID: clubID, Placings: []models.ClubStandingPlacing{}}
+				standings[clubID] = standing
+				clubEvents[clubID] = map[string]bool{}
+				clubArchers[clubID] = map[string]bool{}
+			}
+
+			// Placings are sorted, so the first N of a club are its best
+			isCounted := series.BestPerCategory <= 0 || counted[clubID] < series.BestPerCategory
+			placing := models.ClubStandingPlacing{
+				EventID:      cat.EventID,
+				EventName:    cat.EventName,
+				CategoryID:   cat.CategoryID,
+				CategoryName: cat.CategoryName,
+				ArcherID:     p.ArcherID,
+				ArcherName:   p.ArcherName,
+				Place:        p.Place,
+				Points:       float64(points[p.Place-1]),
+				Counted:      isCounted,
+			}
+			standing.Placings = append(standing.Placings, placing)
+			if !isCounted {
+				continue
+			}
+
+			counted[clubID]++
+			standing.Points += placing.Points
+			switch p.Place {
+			case 1:
+				standing.Gold++
+			case 2:
+				standing.Silver++
+			case 3:
+				standing.Bronze++
+			}
+			clubEvents[clubID][cat.EventID] = true
+			clubArchers[clubID][p.ArcherID] = true
+		}
+	}
+
+	result := make([]*models.ClubStanding, 0, len(standings))
+	for clubID, standing := range standings {
+		var club struct {
+			Name      string  `db:"name"`
+			AvatarURL *string `db:"avatar_url"`
+		}
+		db.Get(&club, "SELECT name, avatar_url FROM clubs WHERE uuid = ?", clubID)
+		standing.ClubName = club.Name
+		standing.ClubAvatarURL = club.AvatarURL
+		standing.EventsScored = len(clubEvents[clubID])
+		standing.ArchersScored = len(clubArchers[clubID])
+		result = append(result, standing)
+	}
+
+	// Ties on points are broken by medal count, gold first
+	less := func(a, b *models.ClubStanding) bool {
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Gold != b.Gold {
+			return a.Gold > b.Gold
+		}
+		if a.Silver != b.Silver {
+			return a.Silver > b.Silver
+		}
+		return a.Bronze > b.Bronze
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if less(result[i], result[j]) {
+			return true
+		}
+		if less(result[j], result[i]) {
+			return false
+		}
+		return result[i].ClubName < result[j].ClubName
+	})
+	for i := range result {
+		if i > 0 && !less(result[i-1], result[i]) {
+			result[i].Rank = result[i-1].Rank
+		} else {
+			result[i].Rank = i + 1
+		}
+	}
+
+	return result, nil
+}
+
+// GetClubSeriesList returns published series, optionally filtered by ?season=
+func GetClubSeriesList(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := `
+			SELECT s.*, (SELECT COUNT(*) FROM club_series_events cse WHERE cse.series_id = s.uuid) as event_count
+			FROM club_series s
+			WHERE s.status = 'published'
+		`
+		args := []interface{}{}
+		if season, err := strconv.Atoi(c.Query("season")); err == nil {
+			query += " AND s.season = ?"
+			args = append(args, season)
+		}
+		query += " ORDER BY s.season DESC, s.name ASC"
+
+		var series []struct {
+			models.ClubSeries
+			EventCount int `json:"event_count" db:"event_count"`
+		}
+		if err := db.Select(&series, query, args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+			return
+		}
+
+		if series == nil {
+			series = []struct {
+				models.ClubSeries
+				EventCount int `json:"event_count" db:"event_count"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": series})
+	}
+}
+
+// GetMyClubSeries returns every series managed by the current organization, drafts included
+func GetMyClubSeries(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		role, _ := c.Get("role")
+
+		query := "SELECT * FROM club_series"
+		args := []interface{}{}
+		if role != "admin" {
+			query += " WHERE organization_id = ?"
+			args = append(args, userID)
+		}
+		query += " ORDER BY season DESC, name ASC"
+
+		var series []models.ClubSeries
+		if err := db.Select(&series, query, args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+			return
+		}
+
+		if series == nil {
+			series = []models.ClubSeries{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": series})
+	}
+}
+
+// GetClubSeries returns a published series with its events
+func GetClubSeries(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		series, ok := loadPublishedSeries(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var events []struct {
+			EventID   string  `json:"event_id" db:"event_id"`
+			Name      string  `json:"name" db:"name"`
+			Slug      string  `json:"slug" db:"slug"`
+			City      *string `json:"city" db:"city"`
+			StartDate *string `json:"start_date" db:"start_date"`
+			Status    string  `json:"status" db:"status"`
+		}
+		db.Select(&events, `
+			SELECT e.uuid as event_id, e.name, e.slug, e.city, DATE_FORMAT(e.start_date, '%Y-%m-%d') as start_date, e.status
+			FROM club_series_events cse
+			JOIN events e ON cse.event_id = e.uuid
+			WHERE cse.series_id = ?
+			ORDER BY e.start_date ASC
+		`, series.UUID)
+
+		if events == nil {
+			events = []struct {
+				EventID   string  `json:"event_id" db:"event_id"`
+				Name      string  `json:"name" db:"name"`
+				Slug      string  `json:"slug" db:"slug"`
+				City      *string `json:"city" db:"city"`
+				StartDate *string `json:"start_date" db:"start_date"`
+				Status    string  `json:"status" db:"status"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": series, "events": events})
+	}
+}
+
+// CreateClubSeries creates a series owned by the current organization
+func CreateClubSeries(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		userType, _ := c.Get("user_type")
+		role, _ := c.Get("role")
+		if role != "admin" && userType != "organization" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only organizations can create a series"})
+			return
+		}
+
+		var req models.ClubSeriesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.BestPerCategory == 0 {
+			req.BestPerCategory = defaultSeriesBestPerCategory
+		}
+		if req.Status == "" {
+			req.Status = "draft"
+		}
+
+		var organizationID interface{}
+		if userType == "organization" {
+			organizationID = userID
+		}
+
+		seriesID := uuid.New().String()
+		_, err := db.Exec(`
+			INSERT INTO club_series (uuid, name, season, description, points_table, best_per_category, status, organization_id, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, seriesID, req.Name, req.Season, req.Description, seriesPointsJSON(req.PointsTable), req.BestPerCategory, req.Status, organizationID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Series created", "id": seriesID})
+	}
+}
+
+// UpdateClubSeries changes a series' details, points table and counting rule
+func UpdateClubSeries(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		series, ok := loadManagedSeries(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req models.ClubSeriesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.BestPerCategory == 0 {
+			req.BestPerCategory = series.BestPerCategory
+		}
+		if req.Status == "" {
+			req.Status = series.Status
+		}
+		pointsTable := series.PointsTable
+		if len(req.PointsTable) > 0 {
+			pointsTable = seriesPointsJSON(req.PointsTable)
+		}
+
+		_, err := db.Exec(`
+			UPDATE club_series
+			SET name = ?, season = ?, description = ?, points_table = ?, best_per_category = ?, status = ?, updated_at = NOW()
+			WHERE uuid = ?
+		`, req.Name, req.Season, req.Description, pointsTable, req.BestPerCategory, req.Status, series.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Series updated"})
+	}
+}
+
+// DeleteClubSeries removes a series and its event list
+func DeleteClubSeries(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		series, ok := loadManagedSeries(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		_, err = tx.Exec("DELETE FROM club_series_events WHERE series_id = ?", series.UUID)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM club_series WHERE uuid = ?", series.UUID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Series deleted"})
+	}
+}
+
+// AddClubSeriesEvent adds an event to a series
+func AddClubSeriesEvent(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		series, ok := loadManagedSeries(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req struct {
+			EventID string `json:"event_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var event struct {
+			UUID   string `db:"uuid"`
+			Status string `db:"status"`
+		}
+		if err := db.Get(&event, "SELECT uuid, COALESCE(status, '') as status FROM events WHERE uuid = ? OR slug = ?", req.EventID, req.EventID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		if event.Status != "completed" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only completed events can be added to a series"})
+			return
+		}
+		eventUUID := event.UUID
+
+		_, err := db.Exec(`
+			INSERT INTO club_series_events (uuid, series_id, event_id)
+			SELECT ?, ?, ?
+			FROM DUAL
+			WHERE NOT EXISTS (SELECT 1 FROM club_series_events WHERE series_id = ? AND event_id = ?)
+		`, uuid.New().String(), series.UUID, eventUUID, series.UUID, eventUUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add event to series"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Event added to series"})
+	}
+}
+
+// RemoveClubSeriesEvent removes an event from a series
+func RemoveClubSeriesEvent(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		series, ok := loadManagedSeries(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		_, err := db.Exec("DELETE FROM club_series_events WHERE series_id = ? AND event_id = ?", series.UUID, c.Param("eventId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove event from series"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Event removed from series"})
+	}
+}
+
+// GetClubSeriesStandings returns the public club ranking of a series
+func GetClubSeriesStandings(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		series, ok := loadPublishedSeries(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		standings, err := buildClubStandings(db, series)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate standings", "details": err.Error()})
+			return
+		}
+
+		// The list view leaves the per-archer breakdown to the club endpoint
+		for _, s := range standings {
+			s.Placings = nil
+		}
+
+		c.JSON(http.StatusOK, gin.H{"series": series, "data": standings})
+	}
+}
+
+// GetClubSeriesBreakdown returns one club's standing with every placing that scored for it
+func GetClubSeriesBreakdown(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		series, ok := loadPublishedSeries(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		standings, err := buildClubStandings(db, series)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate standings", "details": err.Error()})
+			return
+		}
+
+		clubID := c.Param("clubId")
+		for _, s := range standings {
+			if s.ClubID == clubID {
+				c.JSON(http.StatusOK, gin.H{"series": series, "data": s})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"series": series,
+			"data": models.ClubStanding{
+				ClubID:   clubID,
+				ClubName: clubName(db, clubID),
+				Placings: []models.ClubStandingPlacing{},
+			},
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"archeryhub-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Club permissions that can be delegated to staff. The club account itself holds all of them.
+const (
+	clubPermEditProfile      = "edit_profile"
+	clubPermManageMembers    = "manage_members"
+	clubPermViewScores       = "view_scores"
+	clubPermRegisterAthletes = "register_athletes"
+	clubPermManageDues       = "manage_dues"
+	clubPermManageTraining   = "manage_training"
+)
+
+// clubRolePermissions lists what each staff role may do on behalf of its club
+var clubRolePermissions = map[string][]string{
+	"head_coach": {clubPermViewScores, clubPermRegisterAthletes, clubPermManageMembers, clubPermManageTraining},
+	"coach":      {clubPermViewScores, clubPermRegisterAthletes, clubPermManageTraining},
+	"manager":    {clubPermEditProfile, clubPermManageMembers, clubPermManageDues},
+}
+
+// ClubStaff grants a staff role in a club to an account (usually an archer)
+type ClubStaff struct {
+	UUID      string    `json:"uuid" db:"uuid"`
+	ClubID    string    `json:"club_id" db:"club_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	UserType  string    `json:"user_type" db:"user_type"`
+	Role      string    `json:"role" db:"role"` // head_coach, coach, manager
+	GrantedBy string    `json:"granted_by" db:"granted_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// isClubStaffRole reports whether role is a delegated staff role
+func isClubStaffRole(role string) bool {
+	_, ok := clubRolePermissions[role]
+	return ok
+}
+
+// hasClubPermission reports whether the user holds perm in the club, either as the club
+// account itself or through a staff role
+func hasClubPermission(db sqlx.Queryer, userID, userType, clubID, perm string) bool {
+	if userType == "club" && userID == clubID {
+		return true
+	}
+
+	// Archer staff only act for the club while they are a current member of it
+	var roles []string
+	sqlx.Select(db, &roles, `
+		SELECT cs.role FROM club_staff cs
+		WHERE cs.club_id = ? AND cs.user_id = ?
+			AND (cs.user_type != 'archer' OR EXISTS(
+				SELECT 1 FROM club_members cm
+				WHERE cm.club_id = cs.club_id AND cm.archer_id = cs.user_id AND cm.status IN ('active', 'overdue')
+			))
+	`, clubID, userID)
+	for _, role := range roles {
+		for _, p := range clubRolePermissions[role] {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requireClubPermission resolves the club the current user acts for and checks perm, writing
+// 403 on failure. Club accounts act for themselves; staff pick the club with ?club_id=, which
+// may be omitted when they only serve one club.
+func requireClubPermission(db *sqlx.DB, c *gin.Context, perm string) (string, bool) {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+	uid, _ := userID.(string)
+	utype, _ := userType.(string)
+
+	clubID := c.Query("club_id")
+	if utype == "club" {
+		clubID = uid
+	} else if clubID == "" {
+		var clubIDs []string
+		db.Select(&clubIDs, "SELECT DISTINCT club_id FROM club_staff WHERE user_id = ?", uid)
+		if len(clubIDs) == 1 {
+			clubID = clubIDs[0]
+		}
+	}
+
+	if clubID == "" || !hasClubPermission(db, uid, utype, clubID, perm) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to do this for the club"})
+		return "", false
+	}
+	return clubID, true
+}
+
+// grantClubStaffRole records a staff role, ignoring grants that already exist
+func grantClubStaffRole(q sqlx.Execer, clubID, userID, userType, role, grantedBy string) error {
+	_, err := q.Exec(`
+		INSERT INTO club_staff (uuid, club_id, user_id, user_type, role, granted_by)
+		SELECT ?, ?, ?, ?, ?, ?
+		FROM DUAL
+		WHERE NOT EXISTS (SELECT 1 FROM club_staff WHERE club_id = ? AND user_id = ? AND role = ?)
+	`, uuid.New().String(), clubID, userID, userType, role, grantedBy, clubID, userID, role)
+	return err
+}
+
+// endClubStaffRoles drops the staff roles an archer holds in clubs other than keepClubID (all
+// clubs when it's empty), for when the archer leaves or transfers
+func endClubStaffRoles(q sqlx.Execer, archerID, keepClubID string) error {
+	_, err := q.Exec("DELETE FROM club_staff WHERE user_type = 'archer' AND user_id = ? AND club_id != ?", archerID, keepClubID)
+	return err
+}
+
+// canRegisterAthlete reports whether the current user may register the archer for the event:
+// the archer themselves, their guardian, the event organizer, an admin, or the archer's club and
+// its coaches
+func canRegisterAthlete(db *sqlx.DB, c *gin.Context, eventID, archerID string) bool {
+	userID, _ := c.Get("user_id")
+	userType, _ := c.Get("user_type")
+	role, _ := c.Get("role")
+	uid, _ := userID.(string)
+	utype, _ := userType.(string)
+
+	if uid == archerID || role == "admin" {
+		return true
+	}
+
+	var isSelf bool
+	db.Get(&isSelf, "SELECT EXISTS(SELECT 1 FROM archers WHERE uuid = ? AND user_id = ?)", archerID, uid)
+	if isSelf || isActiveGuardian(db, uid, archerID) {
+		return true
+	}
+
+	var organizerID *string
+	db.Get(&organizerID, "SELECT organizer_id FROM events WHERE uuid = ?", eventID)
+	if organizerID != nil && *organizerID == uid {
+		return true
+	}
+
+	var clubID string
+	if err := db.Get(&clubID, "SELECT club_id FROM club_members WHERE archer_id = ? AND status IN ('active', 'overdue') LIMIT 1", archerID); err != nil {
+		return false
+	}
+	return hasClubPermission(db, uid, utype, clubID, clubPermRegisterAthletes)
+}
+
+// GetClubStaff lists the staff of the current club
+func GetClubStaff(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageMembers)
+		if !ok {
+			return
+		}
+
+		var staff []struct {
+			ClubStaff
+			Name string `json:"name" db:"name"`
+		}
+		err := db.Select(&staff, `
+			SELECT cs.*, COALESCE(a.full_name, o.name, '') as name
+			FROM club_staff cs
+			LEFT JOIN archers a ON cs.user_type = 'archer' AND cs.user_id = a.uuid
+			LEFT JOIN organizations o ON cs.user_type = 'organization' AND cs.user_id = o.uuid
+			WHERE cs.club_id = ?
+			ORDER BY FIELD(cs.role, 'head_coach', 'manager', 'coach'), cs.created_at ASC
+		`, clubID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch club staff"})
+			return
+		}
+
+		if staff == nil {
+			staff = []struct {
+				ClubStaff
+				Name string `json:"name" db:"name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": staff})
+	}
+}
+
+// AddClubStaff grants a staff role. Only the club account may appoint staff.
+func AddClubStaff(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := currentClubID(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			UserID   string `json:"user_id" binding:"required"`
+			UserType string `json:"user_type"`
+			Role     string `json:"role" binding:"required,oneof=head_coach coach manager"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.UserType == "" {
+			req.UserType = "archer"
+		}
+
+		table := map[string]string{"archer": "archers", "organization": "organizations"}[req.UserType]
+		if table == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_type must be archer or organization"})
+			return
+		}
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM "+table+" WHERE uuid = ?)", req.UserID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		// Archers coach or manage the club they belong to
+		if req.UserType == "archer" {
+			var isMember bool
+			db.Get(&isMember, "SELECT EXISTS(SELECT 1 FROM club_members WHERE club_id = ? AND archer_id = ? AND status IN ('active', 'overdue'))", clubID, req.UserID)
+			if !isMember {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Archer is not an active member of this club"})
+				return
+			}
+		}
+
+		if err := grantClubStaffRole(db, clubID, req.UserID, req.UserType, req.Role, clubID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add staff"})
+			return
+		}
+
+		utils.Notify(db, req.UserID, req.UserType, "info", "Peran klub baru",
+			fmt.Sprintf("Anda ditunjuk sebagai %s di %s.", req.Role, clubName(db, clubID)), "")
+		utils.LogActivity(db, clubID, "", "club_staff_added", "club_staff", req.UserID, "Granted club role "+req.Role, c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Staff role granted"})
+	}
+}
+
+// RemoveClubStaff revokes a staff role. Only the club account may remove staff.
+func RemoveClubStaff(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := currentClubID(c)
+		if !ok {
+			return
+		}
+
+		var staff ClubStaff
+		err := db.Get(&staff, "SELECT * FROM club_staff WHERE uuid = ? AND club_id = ?", c.Param("staffId"), clubID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Staff not found"})
+			return
+		}
+
+		if _, err := db.Exec("DELETE FROM club_staff WHERE uuid = ?", staff.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove staff"})
+			return
+		}
+
+		utils.Notify(db, staff.UserID, staff.UserType, "info", "Peran klub dicabut",
+			fmt.Sprintf("Peran %s Anda di %s telah dicabut.", staff.Role, clubName(db, clubID)), "")
+		utils.LogActivity(db, clubID, "", "club_staff_removed", "club_staff", staff.UserID, "Revoked club role "+staff.Role, c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Staff role revoked"})
+	}
+}
+
+// GetMyClubRoles returns the staff roles held by the current user
+func GetMyClubRoles(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var roles []struct {
+			ClubStaff
+			ClubName string `json:"club_name" db:"club_name"`
+		}
+		err := db.Select(&roles, `
+			SELECT cs.*, c.name as club_name
+			FROM club_staff cs
+			JOIN clubs c ON cs.club_id = c.uuid
+			WHERE cs.user_id = ?
+			ORDER BY c.name ASC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch club roles"})
+			return
+		}
+
+		result := make([]gin.H, 0, len(roles))
+		for _, r := range roles {
+			result = append(result, gin.H{
+				"id":          r.UUID,
+				"club_id":     r.ClubID,
+				"club_name":   r.ClubName,
+				"role":        r.Role,
+				"permissions": clubRolePermissions[r.Role],
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": result})
+	}
+}
+
+// GetClubAthleteScores returns the competition score history of an active member (coaches and club)
+func GetClubAthleteScores(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermViewScores)
+		if !ok {
+			return
+		}
+		archerID := c.Param("archerId")
+
+		var isMember bool
+		db.Get(&isMember, "SELECT EXISTS(SELECT 1 FROM club_members WHERE club_id = ? AND archer_id = ? AND status IN ('active', 'overdue'))", clubID, archerID)
+		if !isMember {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Athlete is not an active member of this club"})
+			return
+		}
+
+		var history []struct {
+			EventID      string  `json:"event_id" db:"event_id"`
+			EventName    string  `json:"event_name" db:"event_name"`
+			StartDate    *string `json:"start_date" db:"start_date"`
+			CategoryName string  `json:"category_name" db:"category_name"`
+			TotalScore   int     `json:"total_score" db:"total_score"`
+			TotalX       int     `json:"total_x" db:"total_x"`
+			TotalTen     int     `json:"total_10" db:"total_ten"`
+			Ends         int     `json:"ends" db:"ends"`
+		}
+		err := db.Select(&history, `
+			SELECT e.uuid as event_id, e.name as event_name, DATE_FORMAT(e.start_date, '%Y-%m-%d') as start_date,
+				COALESCE(NULLIF(TRIM(CONCAT(COALESCE(d.name, ''), ' ', COALESCE(ag.name, ''))), ''), '') as category_name,
+				COALESCE(SUM(qes.total_score_end), 0) as total_score,
+				COALESCE(SUM(qes.x_count_end), 0) as total_x,
+				COALESCE(SUM(qes.ten_count_end), 0) as total_ten,
+				COUNT(qes.uuid) as ends
+			FROM event_participants tp
+			JOIN events e ON tp.event_id = e.uuid
+			LEFT JOIN event_categories te ON tp.category_id = te.uuid
+			LEFT JOIN ref_bow_types d ON te.division_uuid = d.uuid
+			LEFT JOIN ref_age_groups ag ON te.category_uuid = ag.uuid
+			LEFT JOIN qualification_end_scores qes ON qes.participant_uuid = tp.uuid
+			WHERE tp.archer_id = ?
+			GROUP BY tp.uuid, e.uuid, e.name, e.start_date, d.name, ag.name
+			ORDER BY e.start_date DESC
+		`, archerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch score history", "details": err.Error()})
+			return
+		}
+
+		if history == nil {
+			c.JSON(http.StatusOK, gin.H{"data": []interface{}{}, "archer_name": archerName(db, archerID)})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": history, "archer_name": archerName(db, archerID)})
+	}
+}
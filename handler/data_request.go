@@ -0,0 +1,459 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// accountDeletionGrace is how long a deletion request can be cancelled before the account is
+// anonymised
+const accountDeletionGrace = 14 * 24 * time.Hour
+
+// deletedArcherName replaces the name of a deleted archer on results that stay published
+const deletedArcherName = "Pemanah Terhapus"
+
+// exportHiddenColumns are never written to a data export
+var exportHiddenColumns = []string{"password", "google_id", "totp_secret", "totp_last_step", "key_hash", "token_hash"}
+
+// exportSection is one JSON file of a data export
+type exportSection struct {
+	File  string
+	Query string
+}
+
+// archerExportSections are the files of an archer's data export; each query takes the archer id
+var archerExportSections = []exportSection{
+	{"profile.json", "SELECT * FROM archers WHERE uuid = ?"},
+	{"registrations.json", `
+		SELECT ep.*, e.name as event_name, ec.name as category_name
+		FROM event_participants ep
+		LEFT JOIN events e ON e.uuid = ep.event_id
+		LEFT JOIN event_categories ec ON ec.uuid = ep.category_id
+		WHERE ep.archer_id = ?
+		ORDER BY ep.registration_date`},
+	{"qualification_scores.json", `
+		SELECT qes.*, e.name as event_name
+		FROM qualification_end_scores qes
+		JOIN event_participants ep ON ep.uuid = qes.participant_uuid
+		LEFT JOIN events e ON e.uuid = ep.event_id
+		WHERE ep.archer_id = ?
+		ORDER BY qes.session_uuid, qes.end_number`},
+	{"elimination_entries.json", `
+		SELECT * FROM elimination_entries
+		WHERE participant_type = 'archer' AND participant_uuid = ?`},
+	{"practice_rounds.json", `
+		SELECT r.*, e.end_number, e.total_score_end, e.x_count_end, e.ten_count_end
+		FROM practice_rounds r
+		LEFT JOIN practice_end_scores e ON e.round_uuid = r.uuid
+		WHERE r.archer_id = ?
+		ORDER BY r.practice_date, e.end_number`},
+	{"achievements.json", "SELECT * FROM archer_achievements WHERE archer_id = ?"},
+	{"club_memberships.json", `
+		SELECT cm.*, cl.name as club_name
+		FROM club_members cm
+		LEFT JOIN clubs cl ON cl.uuid = cm.club_id
+		WHERE cm.archer_id = ?`},
+	{"payments.json", "SELECT * FROM payment_transactions WHERE user_id = ? ORDER BY created_at"},
+	{"orders.json", "SELECT * FROM orders WHERE buyer_id = ? ORDER BY created_at"},
+	{"notifications.json", "SELECT * FROM notifications WHERE user_id = ? ORDER BY created_at"},
+	{"media.json", "SELECT * FROM media WHERE user_id = ? ORDER BY created_at"},
+}
+
+// exportRows runs the query and returns its rows as JSON-ready maps without secret columns
+func exportRows(db *sqlx.DB, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []map[string]interface{}{}
+	for rows.Next() {
+		row := map[string]interface{}{}
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		for _, k := range exportHiddenColumns {
+			delete(row, k)
+		}
+		list = append(list, row)
+	}
+	return list, rows.Err()
+}
+
+// writeArcherExport writes the archer's data export as a ZIP: one JSON file per kind of data and
+// the archer's uploaded files under media/
+func writeArcherExport(db *sqlx.DB, w io.Writer, archerID string) error {
+	zw := zip.NewWriter(w)
+
+	for _, section := range archerExportSections {
+		rows, err := exportRows(db, section.Query, archerID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section.File, err)
+		}
+		body, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		f, err := zw.Create(section.File)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(body); err != nil {
+			return err
+		}
+	}
+
+	var files []string
+	db.Select(&files, "SELECT url FROM media WHERE user_id = ?", archerID)
+	for _, name := range files {
+		name = filepath.Base(name)
+		src, err := os.Open(filepath.Join("./media", name))
+		if err != nil {
+			continue // listed in media.json even when the file is gone
+		}
+		f, err := zw.Create("media/" + name)
+		if err == nil {
+			_, err = io.Copy(f, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// ExportMyData downloads everything the platform holds about the current archer as a ZIP
+func ExportMyData(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if c.GetString("user_type") != "archer" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Data export is available for archer accounts"})
+			return
+		}
+
+		// The ZIP is streamed as it's built, so media files never sit in memory all at once
+		filename := fmt.Sprintf("archeryhub-data-%s.zip", time.Now().Format("20060102"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+		c.Header("Content-Type", "application/zip")
+		if err := writeArcherExport(db, c.Writer, userID); err != nil {
+			log.Printf("[data-export] failed for %s: %v", userID, err)
+			if !c.Writer.Written() {
+				c.Header("Content-Disposition", "")
+				c.Header("Content-Type", "")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build data export"})
+			} else {
+				c.Abort()
+			}
+			return
+		}
+
+		utils.LogActivity(db, userID, "", "data_exported", "archer", userID,
+			"Downloaded personal data export", c.ClientIP(), c.Request.UserAgent())
+	}
+}
+
+// openArcherObligations counts what has to be settled before the account can be anonymised:
+// registrations for events that haven't ended and orders that are still unpaid
+func openArcherObligations(q sqlx.Queryer, archerID string) (registrations, orders int) {
+	sqlx.Get(q, &registrations, `
+		SELECT COUNT(*) FROM event_participants tp
+		JOIN events e ON e.uuid = tp.event_id
+		WHERE tp.archer_id = ? AND e.end_date >= CURDATE() AND e.status != 'cancelled'
+	`, archerID)
+	sqlx.Get(q, &orders, "SELECT COUNT(*) FROM orders WHERE buyer_id = ? AND payment_status = 'unpaid' AND status != 'cancelled'", archerID)
+	return registrations, orders
+}
+
+// pendingDeletion returns the account's deletion request that is still in its grace period
+func pendingDeletion(db *sqlx.DB, userID string) (*models.AccountDeletionRequest, error) {
+	var r models.AccountDeletionRequest
+	err := db.Get(&r, "SELECT * FROM account_deletion_requests WHERE user_id = ? AND status = 'pending' LIMIT 1", userID)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetMyDeletionRequest returns the current archer's pending deletion request, or null
+func GetMyDeletionRequest(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, err := pendingDeletion(db, c.GetString("user_id"))
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusOK, gin.H{"data": nil})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deletion request"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": r})
+	}
+}
+
+// RequestAccountDeletion schedules the current archer account for deletion after the grace period
+func RequestAccountDeletion(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if c.GetString("user_type") != "archer" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account deletion is available for archer accounts"})
+			return
+		}
+
+		var req models.RequestAccountDeletionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+		if identity.Password != nil && *identity.Password != "" && *identity.Password != req.Password {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+			return
+		}
+
+		if _, err := pendingDeletion(db, userID); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Account deletion is already scheduled"})
+			return
+		}
+		if registrations, orders := openArcherObligations(db, userID); registrations > 0 || orders > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "Cancel your upcoming event registrations and unpaid orders before deleting the account",
+				"registrations": registrations,
+				"orders":        orders,
+			})
+			return
+		}
+
+		r := models.AccountDeletionRequest{
+			UUID:         uuid.New().String(),
+			UserID:       userID,
+			UserType:     "archer",
+			IdentityID:   &identity.UUID,
+			Email:        &identity.Email,
+			Status:       "pending",
+			ScheduledFor: time.Now().Add(accountDeletionGrace),
+			CreatedAt:    time.Now(),
+		}
+		if req.Reason != "" {
+			r.Reason = &req.Reason
+		}
+		_, err := db.Exec(`
+			INSERT INTO account_deletion_requests (uuid, user_id, user_type, identity_id, email, reason, status, scheduled_for)
+			VALUES (?, ?, ?, ?, ?, ?, 'pending', ?)
+		`, r.UUID, userID, r.UserType, identity.UUID, identity.Email, r.Reason, r.ScheduledFor)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+			return
+		}
+
+		utils.LogActivity(db, userID, "", "account_deletion_requested", "archer", userID,
+			"Requested account deletion", c.ClientIP(), c.Request.UserAgent())
+
+		when := r.ScheduledFor.Format("02 Jan 2006")
+		utils.Notify(db, userID, "archer", "warning", "Penghapusan akun dijadwalkan",
+			"Akun Anda akan dihapus pada "+when+". Anda dapat membatalkannya sebelum tanggal tersebut.", "/settings/account")
+		utils.SendMail(utils.Mail{
+			To:      identity.Email,
+			Subject: "Penghapusan akun Archery Hub dijadwalkan",
+			Body: "Halo,\n\n" +
+				"Kami menerima permintaan untuk menghapus akun Archery Hub Anda. Akun akan dihapus pada " + when + ".\n\n" +
+				"Data pribadi Anda akan dianonimkan; hasil pertandingan tetap tercatat tanpa nama Anda.\n\n" +
+				"Jika Anda berubah pikiran, batalkan penghapusan dari pengaturan akun sebelum tanggal tersebut:\n" + appLink("/settings/account", "") + "\n",
+		})
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Account deletion scheduled", "data": r})
+	}
+}
+
+// CancelAccountDeletion cancels the current archer's pending deletion request
+func CancelAccountDeletion(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+
+		result, err := db.Exec(`
+			UPDATE account_deletion_requests SET status = 'cancelled', cancelled_at = NOW()
+			WHERE user_id = ? AND status = 'pending'
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No account deletion is scheduled"})
+			return
+		}
+
+		utils.LogActivity(db, userID, "", "account_deletion_cancelled", "archer", userID,
+			"Cancelled account deletion", c.ClientIP(), c.Request.UserAgent())
+		utils.Notify(db, userID, "archer", "success", "Penghapusan akun dibatalkan",
+			"Akun Anda tidak akan dihapus.", "/settings/account")
+
+		c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+	}
+}
+
+// anonymiseArcher strips the archer's personal data and login while keeping registrations and
+// score rows, so published results stay valid under a placeholder name
+func anonymiseArcher(db *sqlx.DB, r models.AccountDeletionRequest) error {
+	// Anything taken on during the grace period holds the deletion until it's settled
+	if registrations, orders := openArcherObligations(db, r.UserID); registrations > 0 || orders > 0 {
+		return fmt.Errorf("%d upcoming registrations and %d unpaid orders are still open", registrations, orders)
+	}
+
+	var mediaFiles []string
+	db.Select(&mediaFiles, "SELECT url FROM media WHERE user_id = ?", r.UserID)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	steps := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE archers SET full_name = ?, username = NULL, email = NULL, password = NULL, google_id = NULL,
+			phone = NULL, address = NULL, bio = NULL, avatar_url = NULL, date_of_birth = NULL, gender = NULL, city = NULL,
+			school = NULL, status = 'deleted', updated_at = NOW()
+		  WHERE uuid = ?`, []interface{}{deletedArcherName, r.UserID}},
+		{`UPDATE event_registrations SET athlete_name = ?, athlete_email = NULL, athlete_phone = NULL
+		  WHERE user_id = ?`, []interface{}{deletedArcherName, r.UserID}},
+		{"UPDATE orders SET shipping_address = NULL WHERE buyer_id = ?", []interface{}{r.UserID}},
+		{"DELETE FROM club_members WHERE archer_id = ? AND status IN ('pending', 'invited')", []interface{}{r.UserID}},
+		{"DELETE FROM archer_guardians WHERE archer_id = ? OR guardian_id = ?", []interface{}{r.UserID, r.UserID}},
+		{"DELETE FROM notifications WHERE user_id = ?", []interface{}{r.UserID}},
+		{"DELETE FROM media WHERE user_id = ?", []interface{}{r.UserID}},
+		{"DELETE FROM identity_profiles WHERE profile_id = ?", []interface{}{r.UserID}},
+		// Logs keep what happened but lose where from and the entry snapshots, which carry the name
+		{"UPDATE activity_logs SET ip_address = '', user_agent = '' WHERE user_id = ?", []interface{}{r.UserID}},
+		{`UPDATE activity_logs SET before_data = NULL, after_data = NULL
+		  WHERE (entity_type = 'archer' AND entity_id = ?)
+			OR (entity_type = 'event_participant' AND (
+				entity_id IN (SELECT uuid FROM event_participants WHERE archer_id = ?)
+				OR JSON_UNQUOTE(JSON_EXTRACT(before_data, '$.archer_id')) = ?
+				OR JSON_UNQUOTE(JSON_EXTRACT(after_data, '$.archer_id')) = ?))`,
+			[]interface{}{r.UserID, r.UserID, r.UserID, r.UserID}},
+		// Tripay callbacks echo the customer details sent with the payment
+		{`UPDATE payment_transactions
+		  SET callback_data = IF(JSON_VALID(callback_data),
+			JSON_REMOVE(callback_data, '$.customer_name', '$.customer_email', '$.customer_phone'), NULL)
+		  WHERE user_id = ? AND callback_data IS NOT NULL`, []interface{}{r.UserID}},
+		{"UPDATE auth_sessions SET ip_address = '', user_agent = '', email = '', name = ?, avatar = '' WHERE user_id = ?",
+			[]interface{}{deletedArcherName, r.UserID}},
+		{"UPDATE account_deletion_requests SET status = 'completed', completed_at = NOW(), email = NULL WHERE uuid = ?", []interface{}{r.UUID}},
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step.query, step.args...); err != nil {
+			return err
+		}
+	}
+
+	// The login goes too, unless it still has other profiles (an organization, a club)
+	if r.IdentityID != nil {
+		var others int
+		tx.Get(&others, "SELECT COUNT(*) FROM identity_profiles WHERE identity_id = ?", *r.IdentityID)
+		if others == 0 {
+			for _, q := range []string{
+				`UPDATE identities SET email = CONCAT('deleted-', uuid, '@deleted.invalid'), password = NULL, google_id = NULL,
+					totp_secret = NULL, totp_enabled_at = NULL, email_verified_at = NULL, status = 'deleted', updated_at = NOW()
+				 WHERE uuid = ?`,
+				"DELETE FROM identity_devices WHERE identity_id = ?",
+				"DELETE FROM two_factor_recovery_codes WHERE identity_id = ?",
+				"DELETE FROM login_attempts WHERE identity_id = ?",
+			} {
+				if _, err := tx.Exec(q, *r.IdentityID); err != nil {
+					return err
+				}
+			}
+		} else {
+			// The login stays for the other profiles, but the addresses it was used from go
+			if _, err := tx.Exec("UPDATE login_attempts SET ip_address = '', user_agent = '' WHERE identity_id = ?", *r.IdentityID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	revokeUserSessions(db, r.UserID, "account_deleted")
+	for _, name := range mediaFiles {
+		os.Remove(filepath.Join("./media", filepath.Base(name)))
+	}
+	return nil
+}
+
+// RunAccountDeletions anonymises the accounts whose deletion grace period is over
+func RunAccountDeletions(db *sqlx.DB) (int, error) {
+	var due []models.AccountDeletionRequest
+	err := db.Select(&due, "SELECT * FROM account_deletion_requests WHERE status = 'pending' AND scheduled_for <= NOW()")
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, r := range due {
+		if err := anonymiseArcher(db, r); err != nil {
+			log.Printf("[account-deletion] failed for %s: %v", r.UserID, err)
+			continue
+		}
+		deleted++
+
+		utils.LogActivity(db, r.UserID, "", "account_deleted", "archer", r.UserID,
+			"Account anonymised after deletion request", "", "")
+		if r.Email != nil {
+			utils.SendMail(utils.Mail{
+				To:      *r.Email,
+				Subject: "Akun Archery Hub telah dihapus",
+				Body:    "Halo,\n\nSesuai permintaan Anda, akun Archery Hub Anda telah dihapus dan data pribadi Anda telah dianonimkan.\n\nTerima kasih telah menggunakan Archery Hub.",
+			})
+		}
+	}
+	return deleted, nil
+}
+
+// StartAccountDeletionScheduler runs RunAccountDeletions on a fixed interval in the background
+func StartAccountDeletionScheduler(db *sqlx.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deleted, err := RunAccountDeletions(db)
+			if err != nil {
+				log.Printf("[account-deletion] cycle failed: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("[account-deletion] anonymised %d accounts", deleted)
+			}
+		}
+	}()
+}
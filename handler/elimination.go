@@ -675,6 +675,9 @@ func CreateBracket(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "bracket_created", "elimination_bracket", bracketUUID,
+			fmt.Sprintf("Created %s bracket of %d", req.BracketType, req.BracketSize), nil, bracketSnapshot(db, bracketUUID))
+
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "Bracket created and generated successfully",
 			"bracket": gin.H{
@@ -685,6 +688,61 @@ func CreateBracket(db *sqlx.DB) gin.HandlerFunc {
 	}
 }
 
+// bracketSnapshot reads a bracket, by bracket_id or uuid, for the activity log
+func bracketSnapshot(db *sqlx.DB, bracketID string) map[string]interface{} {
+	return activitySnapshot(db, `SELECT * FROM elimination_brackets WHERE bracket_id = ? OR uuid = ?`, bracketID, bracketID)
+}
+
+// bracketEventUUID is the event a bracket snapshot belongs to
+func bracketEventUUID(bracket map[string]interface{}) string {
+	eventUUID, _ := bracket["event_uuid"].(string)
+	return eventUUID
+}
+
+// matchEventUUID is the event an elimination match belongs to
+func matchEventUUID(db *sqlx.DB, matchID string) string {
+	var eventUUID string
+	db.Get(&eventUUID, `
+		SELECT eb.event_uuid FROM elimination_matches em
+		JOIN elimination_brackets eb ON em.bracket_uuid = eb.uuid
+		WHERE em.uuid = ?
+	`, matchID)
+	return eventUUID
+}
+
+// scorableMatchEvent resolves the event of the :matchId and checks it is the event in the
+// path before checking that the caller may score it. Writes the error response itself.
+func scorableMatchEvent(db *sqlx.DB, c *gin.Context) (string, bool) {
+	eventUUID := matchEventUUID(db, c.Param("matchId"))
+	var pathEventUUID string
+	db.Get(&pathEventUUID, `SELECT uuid FROM events WHERE uuid = ? OR slug = ?`, c.Param("id"), c.Param("id"))
+	if eventUUID == "" || eventUUID != pathEventUUID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return "", false
+	}
+	if !canScoreEvent(db, c, eventUUID) {
+		return "", false
+	}
+	return eventUUID, true
+}
+
+// matchEndSnapshot reads both sides of a match end for the activity log
+func matchEndSnapshot(db *sqlx.DB, matchID string, endNo int) map[string]interface{} {
+	rows, _ := exportRows(db, `
+		SELECT eme.side, eme.end_total,
+		       GROUP_CONCAT(IF(emas.is_x = 1, 'X', emas.score) ORDER BY emas.arrow_no) as arrows
+		FROM elimination_match_ends eme
+		LEFT JOIN elimination_match_arrow_scores emas ON emas.match_end_uuid = eme.uuid
+		WHERE eme.match_uuid = ? AND eme.end_no = ?
+		GROUP BY eme.uuid, eme.side, eme.end_total
+	`, matchID, endNo)
+	sides := map[string]interface{}{}
+	for _, r := range rows {
+		sides[fmt.Sprintf("side_%v", r["side"])] = map[string]interface{}{"total": r["end_total"], "arrows": r["arrows"]}
+	}
+	return sides
+}
+
 // UpdateBracket updates an existing elimination bracket
 func UpdateBracket(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -719,6 +777,7 @@ func UpdateBracket(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := bracketSnapshot(db, bracketID)
 		_, err = db.Exec(`
 			UPDATE elimination_brackets 
 			SET category_uuid = ?, bracket_type = ?, format = ?, bracket_size = ?, ends_per_match = ?, arrows_per_end = ?
@@ -730,6 +789,9 @@ func UpdateBracket(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		after := bracketSnapshot(db, bracketID)
+		logEventActivity(db, c, bracketEventUUID(after), "bracket_updated", "elimination_bracket", fmt.Sprint(after["uuid"]), "Updated bracket", before, after)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Bracket updated successfully"})
 	}
 }
@@ -918,6 +980,9 @@ func GenerateBracket(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, bracket.EventUUID, "bracket_generated", "elimination_bracket", bracketUUID,
+			fmt.Sprintf("Generated bracket with %d entries", len(entries)), nil, nil)
+
 		c.JSON(http.StatusOK, gin.H{
 			"message":       "Bracket generated successfully",
 			"entries_count": len(entries),
@@ -972,6 +1037,22 @@ func UpdateMatchTargets(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		matchTargets := func() map[string]interface{} {
+			rows, _ := exportRows(db, `
+				SELECT em.uuid, et.target_name FROM elimination_matches em
+				LEFT JOIN event_targets et ON em.target_uuid = et.uuid
+				WHERE em.bracket_uuid = ?
+			`, bracket.UUID)
+			byMatch := map[string]interface{}{}
+			for _, r := range rows {
+				if id, ok := r["uuid"].(string); ok {
+					byMatch[id] = r["target_name"]
+				}
+			}
+			return byMatch
+		}
+		before := matchTargets()
+
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -1015,6 +1096,9 @@ func UpdateMatchTargets(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, bracket.EventUUID, "match_targets_updated", "elimination_bracket", bracket.UUID,
+			fmt.Sprintf("Assigned targets to %d matches", updated), before, matchTargets())
+
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Targets updated successfully",
 			"updated": updated,
@@ -1042,6 +1126,7 @@ func DeleteBracket(db *sqlx.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete a running bracket"})
 			return
 		}
+		before := bracketSnapshot(db, bracket.UUID)
 
 		tx, err := db.Beginx()
 		if err != nil {
@@ -1067,6 +1152,8 @@ func DeleteBracket(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, bracketEventUUID(before), "bracket_deleted", "elimination_bracket", bracket.UUID, "Deleted bracket", before, nil)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Bracket deleted successfully"})
 	}
 }
@@ -1315,6 +1402,10 @@ func GetMatch(db *sqlx.DB) gin.HandlerFunc {
 func UpdateMatchScore(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		matchID := c.Param("matchId")
+		eventUUID, ok := scorableMatchEvent(db, c)
+		if !ok {
+			return
+		}
 
 		var req struct {
 			EndNo   int      `json:"end_no" binding:"required"`
@@ -1329,6 +1420,7 @@ func UpdateMatchScore(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := matchEndSnapshot(db, matchID, req.EndNo)
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -1478,6 +1570,9 @@ func UpdateMatchScore(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "match_score_updated", "elimination_match", matchID,
+			fmt.Sprintf("Updated end %d", req.EndNo), before, matchEndSnapshot(db, matchID, req.EndNo))
+
 		c.JSON(http.StatusOK, gin.H{"message": "Score updated successfully"})
 	}
 }
@@ -1486,6 +1581,10 @@ func UpdateMatchScore(db *sqlx.DB) gin.HandlerFunc {
 func FinishMatch(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		matchID := c.Param("matchId")
+		eventUUID, ok := scorableMatchEvent(db, c)
+		if !ok {
+			return
+		}
 
 		var req struct {
 			WinnerEntryID string `json:"winner_entry_id" binding:"required"`
@@ -1527,6 +1626,7 @@ func FinishMatch(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := rowSnapshot(db, "elimination_matches", matchID)
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -1650,6 +1750,9 @@ func FinishMatch(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "match_finished", "elimination_match", matchID,
+			"Finished match and advanced the winner", before, rowSnapshot(db, "elimination_matches", matchID))
+
 		c.JSON(http.StatusOK, gin.H{
 			"message":          "Match finished and winner advanced",
 			"winner_entry_id":  req.WinnerEntryID,
@@ -1662,6 +1765,10 @@ func FinishMatch(db *sqlx.DB) gin.HandlerFunc {
 func EndMatch(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		matchID := c.Param("matchId")
+		eventUUID, ok := scorableMatchEvent(db, c)
+		if !ok {
+			return
+		}
 
 		var req struct {
 			WinnerEntryID string `json:"winner_entry_id"`
@@ -1827,6 +1934,7 @@ func EndMatch(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		// Start transaction
+		before := rowSnapshot(db, "elimination_matches", matchID)
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -1939,6 +2047,9 @@ func EndMatch(db *sqlx.DB) gin.HandlerFunc {
 			LEFT JOIN teams t ON ee.team_uuid = t.uuid
 			WHERE ee.uuid = ?`, winnerID)
 
+		logEventActivity(db, c, eventUUID, "match_finished", "elimination_match", matchID,
+			"Ended match, winner "+winnerName, before, rowSnapshot(db, "elimination_matches", matchID))
+
 		c.JSON(http.StatusOK, gin.H{
 			"message":         "Match ended",
 			"winner_entry_id": winnerID,
@@ -1954,6 +2065,7 @@ func EndMatch(db *sqlx.DB) gin.HandlerFunc {
 func StartBracket(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		bracketID := c.Param("bracketId")
+		before := bracketSnapshot(db, bracketID)
 
 		result, err := db.Exec(`
 			UPDATE elimination_brackets
@@ -1971,6 +2083,9 @@ func StartBracket(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		after := bracketSnapshot(db, bracketID)
+		logEventActivity(db, c, bracketEventUUID(after), "bracket_started", "elimination_bracket", fmt.Sprint(after["uuid"]), "Started bracket", before, after)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Bracket started"})
 	}
 }
@@ -1979,6 +2094,7 @@ func StartBracket(db *sqlx.DB) gin.HandlerFunc {
 func CloseBracket(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		bracketID := c.Param("bracketId")
+		before := bracketSnapshot(db, bracketID)
 
 		result, err := db.Exec(`
 			UPDATE elimination_brackets
@@ -1996,6 +2112,9 @@ func CloseBracket(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		after := bracketSnapshot(db, bracketID)
+		logEventActivity(db, c, bracketEventUUID(after), "bracket_closed", "elimination_bracket", fmt.Sprint(after["uuid"]), "Closed bracket", before, after)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Bracket closed"})
 	}
 }
@@ -76,21 +76,22 @@ func GetEvents(db *sqlx.DB) gin.HandlerFunc {
 				u.email as organizer_email,
 				u.slug as organizer_slug,
 				u.avatar_url as organizer_avatar_url,
+				COALESCE(u.verification_status = 'verified', 0) as organizer_verified,
 				COUNT(DISTINCT tp2.archer_id) as participant_count,
 				COUNT(DISTINCT te.uuid) as event_count,
 				tp.payment_status,
 				tp.uuid as participant_uuid
 			FROM events t
 			LEFT JOIN (
-				SELECT uuid as id, name as full_name, email, slug, avatar_url FROM organizations
+				SELECT uuid as id, name as full_name, email, slug, avatar_url, verification_status FROM organizations
 				UNION ALL
-				SELECT uuid as id, name as full_name, email, slug, avatar_url FROM clubs
+				SELECT uuid as id, name as full_name, email, slug, avatar_url, verification_status FROM clubs
 			) u ON t.organizer_id = u.id
 			LEFT JOIN event_participants tp ON t.uuid = tp.event_id AND tp.archer_id = ?
 			LEFT JOIN event_participants tp2 ON t.uuid = tp2.event_id
 			LEFT JOIN event_categories te ON t.uuid = te.event_id
 			` + whereClause + `
-			GROUP BY t.uuid, tp.payment_status, tp.uuid, u.full_name, u.email, u.slug, u.avatar_url
+			GROUP BY t.uuid, tp.payment_status, tp.uuid, u.full_name, u.email, u.slug, u.avatar_url, u.verification_status
 			ORDER BY t.start_date DESC
 			LIMIT ? OFFSET ?
 			`
@@ -107,18 +108,19 @@ func GetEvents(db *sqlx.DB) gin.HandlerFunc {
 				u.email as organizer_email,
 				u.slug as organizer_slug,
 				u.avatar_url as organizer_avatar_url,
+				COALESCE(u.verification_status = 'verified', 0) as organizer_verified,
 				COUNT(DISTINCT tp.archer_id) as participant_count,
 				COUNT(DISTINCT te.uuid) as event_count
 			FROM events t
 			LEFT JOIN (
-				SELECT uuid as id, name as full_name, email, slug, avatar_url FROM organizations
+				SELECT uuid as id, name as full_name, email, slug, avatar_url, verification_status FROM organizations
 				UNION ALL
-				SELECT uuid as id, name as full_name, email, slug, avatar_url FROM clubs
+				SELECT uuid as id, name as full_name, email, slug, avatar_url, verification_status FROM clubs
 			) u ON t.organizer_id = u.id
 			LEFT JOIN event_participants tp ON t.uuid = tp.event_id
 			LEFT JOIN event_categories te ON t.uuid = te.event_id
 			` + whereClause + `
-			GROUP BY t.uuid, u.full_name, u.email, u.slug, u.avatar_url
+			GROUP BY t.uuid, u.full_name, u.email, u.slug, u.avatar_url, u.verification_status
 			ORDER BY t.start_date DESC
 			LIMIT ? OFFSET ?
 			`
@@ -173,15 +175,16 @@ func GetEventByID(db *sqlx.DB) gin.HandlerFunc {
 				u.email as organizer_email,
 				u.avatar_url as organizer_avatar_url,
 				u.slug as organizer_slug,
+				COALESCE(u.verification_status = 'verified', 0) as organizer_verified,
 				COALESCE(participant_stats.participant_count, 0) as participant_count,
 				COALESCE(category_stats.event_count, 0) as event_count,
 				COALESCE(target_stats.target_count, 0) as target_count,
 				COALESCE(active_target_stats.active_target_count, 0) as active_target_count
 			FROM events t
 			LEFT JOIN (
-				SELECT uuid as id, name as full_name, email, avatar_url, slug FROM organizations
+				SELECT uuid as id, name as full_name, email, avatar_url, slug, verification_status FROM organizations
 				UNION ALL
-				SELECT uuid as id, name as full_name, email, avatar_url, slug FROM clubs
+				SELECT uuid as id, name as full_name, email, avatar_url, slug, verification_status FROM clubs
 			) u ON t.organizer_id = u.id
 			LEFT JOIN (
 				SELECT event_id, COUNT(DISTINCT archer_id) as participant_count
@@ -348,6 +351,10 @@ func CreateEvent(db *sqlx.DB) gin.HandlerFunc {
 		if status == "" {
 			status = "draft"
 		}
+		if isLiveEventStatus(status) && c.GetString("role") != "admin" && !organizerMayPublish(db, userID.(string)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only verified organizers can publish events", "code": "organizer_not_verified"})
+			return
+		}
 
 		// Use location_type if provided, otherwise fallback to type for backward compatibility
 		locationType := req.LocationType
@@ -405,9 +412,7 @@ func CreateEvent(db *sqlx.DB) gin.HandlerFunc {
 			}
 		}
 
-		// Log activity
-		userID, _ = c.Get("user_id")
-		utils.LogActivity(db, userID.(string), eventUUID, "Event_created", "Event", eventUUID, "Created new Event: "+req.Name, c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, eventUUID, "event_created", "event", eventUUID, "Created new Event: "+req.Name, nil, rowSnapshot(db, "events", eventUUID))
 
 		c.JSON(http.StatusCreated, gin.H{
 			"message": "Event created successfully",
@@ -426,6 +431,10 @@ func UpdateEvent(db *sqlx.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
 			return
 		}
+		if req.RankingTier != nil && *req.RankingTier != "" && !isRankingTier(*req.RankingTier) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ranking_tier must be club, regional or national"})
+			return
+		}
 
 		// Resolve slug to UUID if needed
 		var actualID string
@@ -436,6 +445,17 @@ func UpdateEvent(db *sqlx.DB) gin.HandlerFunc {
 		}
 		id = actualID
 
+		if req.Status != nil && isLiveEventStatus(*req.Status) && c.GetString("role") != "admin" {
+			var organizerID string
+			db.Get(&organizerID, "SELECT COALESCE(organizer_id, '') FROM events WHERE uuid = ?", id)
+			if !organizerMayPublish(db, organizerID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only verified organizers can publish events", "code": "organizer_not_verified"})
+				return
+			}
+		}
+
+		before := rowSnapshot(db, "events", id)
+
 		// Build dynamic update query
 		query := "UPDATE events SET updated_at = NOW()"
 		args := []interface{}{}
@@ -528,6 +548,14 @@ func UpdateEvent(db *sqlx.DB) gin.HandlerFunc {
 			query += ", faq = ?"
 			args = append(args, models.ToJSON(req.FAQ))
 		}
+		if req.RankingTier != nil {
+			query += ", ranking_tier = ?"
+			if *req.RankingTier == "" {
+				args = append(args, nil)
+			} else {
+				args = append(args, *req.RankingTier)
+			}
+		}
 		if req.LocationType != nil {
 			query += ", location_type = ?"
 			args = append(args, *req.LocationType)
@@ -546,9 +574,13 @@ func UpdateEvent(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), id, "Event_updated", "Event", id, "Updated Event", c.ClientIP(), c.Request.UserAgent())
+		if req.RankingTier != nil {
+			if err := refreshEventRanking(db, id); err != nil {
+				fmt.Printf("[UpdateEvent] Failed to refresh ranking results for %s: %v\n", id, err)
+			}
+		}
+
+		logEventActivity(db, c, id, "event_updated", "event", id, "Updated Event", before, rowSnapshot(db, "events", id))
 
 		c.JSON(http.StatusOK, gin.H{"message": "Event updated successfully"})
 	}
@@ -567,6 +599,8 @@ func DeleteEvent(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := rowSnapshot(db, "events", actualID)
+
 		result, err := db.Exec("DELETE FROM events WHERE uuid = ?", actualID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete Event", "details": err.Error()})
@@ -579,9 +613,7 @@ func DeleteEvent(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), "", "Event_deleted", "Event", id, "Deleted Event", c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, actualID, "event_deleted", "event", actualID, "Deleted Event", before, nil)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Event deleted successfully"})
 	}
@@ -700,6 +732,9 @@ func GetEventEvents(db *sqlx.DB) gin.HandlerFunc {
 func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
+		if !apiKeyCoversEvent(db, c, eventID) {
+			return
+		}
 		limitStr := c.DefaultQuery("limit", "10")
 		offsetStr := c.DefaultQuery("offset", "0")
 		categoryFilter := c.Query("category")
@@ -742,6 +777,7 @@ func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 				ArcherID   string  `db:"archer_id" json:"archer_id"`
 				FullName   string  `db:"full_name" json:"full_name"`
 				Email      string  `db:"email" json:"email"`
+				DateOfBirth *time.Time `db:"date_of_birth" json:"-"`
 				AvatarURL  *string `db:"avatar_url" json:"avatar_url"`
 				ClubName   *string `db:"club_name" json:"club_name"`
 				Categories string  `db:"categories" json:"-"`
@@ -754,6 +790,7 @@ func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 					a.uuid as archer_id,
 					a.full_name,
 					COALESCE(a.email, '') as email,
+					a.date_of_birth,
 					a.avatar_url,
 					COALESCE(cl.name, '') as club_name,
 					JSON_ARRAYAGG(JSON_OBJECT(
@@ -788,8 +825,11 @@ func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 				return
 			}
 
-			// Post-process categories and mask URLs
+			// Post-process categories and mask URLs; minors' contact details stay private
 			for i := range participants {
+				if isMinorOn(participants[i].DateOfBirth, time.Now()) {
+					participants[i].Email = ""
+				}
 				if participants[i].Categories != "" {
 					var cats []interface{}
 					if err := json.Unmarshal([]byte(participants[i].Categories), &cats); err == nil {
@@ -862,6 +902,7 @@ func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 			Username           *string `db:"username" json:"username"`
 			FullName           string  `db:"full_name" json:"full_name"`
 			Email              string  `db:"email" json:"email"`
+			DateOfBirth        *time.Time `db:"date_of_birth" json:"-"`
 			City               *string `db:"city" json:"city"`
 			ClubID             *string `db:"club_id" json:"club_id"`
 			ClubName           *string `db:"club_name" json:"club_name"`
@@ -890,6 +931,7 @@ func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 				a.username as username,
 				a.full_name as full_name,
 				COALESCE(a.email, '') as email,
+				a.date_of_birth,
 				a.city as city,
 				a.club_id as club_id,
 				a.avatar_url as avatar_url,
@@ -912,7 +954,7 @@ func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 				GROUP BY participant_uuid
 			) scores ON tp.uuid = scores.participant_uuid
 			` + whereClause + `
-			GROUP BY tp.uuid, a.uuid, cl.uuid, te.uuid, d.uuid, c.uuid, et.uuid, gd.uuid, a.id, a.username, a.full_name, a.email, a.city, a.club_id, a.avatar_url, cl.name, d.name, c.name, et.name, gd.name, scores.total_score, scores.total_x
+			GROUP BY tp.uuid, a.uuid, cl.uuid, te.uuid, d.uuid, c.uuid, et.uuid, gd.uuid, a.id, a.username, a.full_name, a.email, a.date_of_birth, a.city, a.club_id, a.avatar_url, cl.name, d.name, c.name, et.name, gd.name, scores.total_score, scores.total_x
 			ORDER BY total_score DESC, total_x DESC, a.full_name ASC
 			LIMIT ? OFFSET ?
 		`
@@ -929,8 +971,11 @@ func GetEventParticipants(db *sqlx.DB) gin.HandlerFunc {
 		db.Get(&verifiedCount, "SELECT COUNT(*) FROM event_participants WHERE event_id = ? AND status = 'Terdaftar'", actualEventID)
 		db.Get(&pendingCount, "SELECT COUNT(*) FROM event_participants WHERE event_id = ? AND status = 'Menunggu Acc'", actualEventID)
 
-		// Mask avatar URLs
+		// Mask avatar URLs; minors' contact details stay private
 		for i := range participants {
+			if isMinorOn(participants[i].DateOfBirth, time.Now()) {
+				participants[i].Email = ""
+			}
 			if participants[i].AvatarURL != nil {
 				masked := utils.MaskMediaURL(*participants[i].AvatarURL)
 				participants[i].AvatarURL = &masked
@@ -998,6 +1043,7 @@ func GetEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 			FullName           string  `db:"full_name" json:"full_name"`
 			Username           *string `db:"username" json:"username"`
 			Email              string  `db:"email" json:"email"`
+			DateOfBirth        *time.Time `db:"date_of_birth" json:"-"`
 			City               *string `db:"city" json:"city"`
 			ClubID             *string `db:"club_id" json:"club_id"`
 			ClubName           *string `db:"club_name" json:"club_name"`
@@ -1028,6 +1074,7 @@ func GetEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 				a.username as username,
 				a.full_name as full_name,
 				COALESCE(a.email, '') as email,
+				a.date_of_birth,
 				a.city as city,
 				a.club_id as club_id,
 				a.avatar_url as avatar_url,
@@ -1070,6 +1117,10 @@ func GetEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 
 		fmt.Printf("[DEBUG] Found participant: %s (UUID: %s)\n", participant.FullName, participant.ID)
 
+		if isMinorOn(participant.DateOfBirth, time.Now()) {
+			participant.Email = ""
+		}
+
 		// Mask avatar URL
 		if participant.AvatarURL != nil {
 			masked := utils.MaskMediaURL(*participant.AvatarURL)
@@ -1147,6 +1198,12 @@ func UpdateEventSchedule(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		scheduleQuery := `
+			SELECT uuid, title, description, start_time, end_time, day_order, sort_order, location
+			FROM event_schedule WHERE event_id = ? ORDER BY day_order, sort_order
+		`
+		before, _ := exportRows(db, scheduleQuery, actualEventID)
+
 		// Delete existing schedules
 		_, err = db.Exec("DELETE FROM event_schedule WHERE event_id = ?", actualEventID)
 		if err != nil {
@@ -1200,6 +1257,10 @@ func UpdateEventSchedule(db *sqlx.DB) gin.HandlerFunc {
 			}
 		}
 
+		after, _ := exportRows(db, scheduleQuery, actualEventID)
+		logEventActivity(db, c, actualEventID, "schedule_updated", "event", actualEventID, fmt.Sprintf("Updated %d schedule items", len(req.Schedules)),
+			gin.H{"schedules": before}, gin.H{"schedules": after})
+
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Event schedules updated successfully",
 			"count":   len(req.Schedules),
@@ -1267,6 +1328,8 @@ func CreateEventCategoryRef(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		auditAdmin(db, c, "event_category_created", "event_category", id, "", req)
+
 		c.JSON(http.StatusCreated, gin.H{"id": id})
 	}
 }
@@ -1322,6 +1385,8 @@ func UpdateEventCategoryRef(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		auditAdmin(db, c, "event_category_updated", "event_category", id, "", req)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Category updated"})
 	}
 }
@@ -1331,15 +1396,24 @@ func PublishEvent(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
 
+		if c.GetString("role") != "admin" {
+			var organizerID string
+			db.Get(&organizerID, "SELECT COALESCE(organizer_id, '') FROM events WHERE uuid = ?", eventID)
+			if !organizerMayPublish(db, organizerID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only verified organizers can publish events", "code": "organizer_not_verified"})
+				return
+			}
+		}
+
+		before := rowSnapshot(db, "events", eventID)
+
 		_, err := db.Exec("UPDATE events SET status = 'published' WHERE uuid = ?", eventID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish event"})
 			return
 		}
 
-		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), eventID, "event_published", "event", eventID, "Published event", c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, eventID, "event_published", "event", eventID, "Published event", before, rowSnapshot(db, "events", eventID))
 
 		c.JSON(http.StatusOK, gin.H{"message": "Event published successfully"})
 	}
@@ -1353,8 +1427,10 @@ func RegisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 		var req struct {
 			AthleteID        string   `json:"athlete_id" binding:"required"`
 			EventCategoryID  string   `json:"event_category_id" binding:"required"`
-			PaymentAmount    float64  `json:"payment_amount"`
 			PaymentProofURLs []string `json:"payment_proof_urls"`
+			DeclaredAmount   *float64 `json:"declared_amount"`
+			PromoCode        string   `json:"promo_code"`
+			CreditNoteCode   string   `json:"credit_note_code"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -1398,6 +1474,11 @@ func RegisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !canRegisterAthlete(db, c, actualEventID, archerUUID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only register yourself or athletes of a club you coach"})
+			return
+		}
+
 		err = db.Get(&exists, `
 			SELECT EXISTS(SELECT 1 FROM event_participants 
 			WHERE event_id = ? AND archer_id = ?)
@@ -1413,6 +1494,17 @@ func RegisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Minors need their guardian's consent
+		consentStatus, consentBy, ok := registrationGuardianConsent(db, c, actualEventID, archerUUID)
+		if !ok {
+			return
+		}
+		var consentAt *time.Time
+		if consentBy != nil {
+			now := time.Now()
+			consentAt = &now
+		}
+
 		// Insert participant
 		participantUUID := uuid.New().String()
 		registrationDate := time.Now()
@@ -1421,29 +1513,109 @@ func RegisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 			proofURLs = strings.Join(req.PaymentProofURLs, ",")
 		}
 
-		_, err = db.Exec(`
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		// The entry price is computed server-side from the event's pricing rules
+		quote, err := computeEntryPrice(tx, actualEventID, req.EventCategoryID, archerUUID, req.PromoCode, registrationDate, registrationDate)
+		if err == errPromoCodeInvalid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute entry price", "details": err.Error()})
+			return
+		}
+		if quote.PromoCodeID != nil {
+			if err := consumePromoCode(tx, *quote.PromoCodeID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// Credit from an earlier refund covers (part of) the entry fee
+		paymentAmount := quote.Total
+		creditApplied := 0.0
+		if req.CreditNoteCode != "" {
+			creditApplied, err = redeemCreditNote(tx, req.CreditNoteCode, archerUUID, participantUUID, paymentAmount)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			paymentAmount -= creditApplied
+		}
+
+		// An entry fully covered by a promo code or credit has nothing left to pay
+		paymentStatus := "menunggu_acc"
+		var qrRaw *string
+		if paymentAmount <= 0 {
+			paymentAmount = 0
+			paymentStatus = "lunas"
+			qr := uuid.New().String()
+			qrRaw = &qr
+		}
+
+		_, err = tx.Exec(`
 			INSERT INTO event_participants (
 				uuid, event_id, archer_id, category_id, 
-				registration_date, payment_status, payment_amount, payment_proof_urls, status
-			) VALUES (?, ?, ?, ?, ?, 'menunggu_acc', ?, ?, 'Terdaftar')
-		`, participantUUID, actualEventID, archerUUID, req.EventCategoryID, registrationDate, req.PaymentAmount, proofURLs)
+				registration_date, payment_status, payment_amount, payment_proof_urls, declared_amount, status,
+				base_fee, discount_amount, promo_code_id, pricing_breakdown,
+				guardian_consent_status, guardian_consent_by, guardian_consent_at, qr_raw
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 'Terdaftar', ?, ?, ?, ?, ?, ?, ?, ?)
+		`, participantUUID, actualEventID, archerUUID, req.EventCategoryID, registrationDate, paymentStatus, paymentAmount, proofURLs, req.DeclaredAmount,
+			quote.BaseFee, quote.DiscountAmount, quote.PromoCodeID, models.ToJSON(quote.Lines),
+			consentStatus, consentBy, consentAt, qrRaw)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register participant", "details": err.Error()})
 			return
 		}
 
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit registration"})
+			return
+		}
+
 		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), actualEventID, "participant_registered", "event_participant", participantUUID, "Registered participant for event category: "+req.EventCategoryID, c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, actualEventID, "participant_registered", "event_participant", participantUUID, "Registered participant for event category: "+req.EventCategoryID, nil, rowSnapshot(db, "event_participants", participantUUID))
+
+		if consentStatus != nil && *consentStatus == "pending" {
+			notifyGuardiansOfRegistration(db, archerUUID, actualEventID)
+		}
 
 		c.JSON(http.StatusCreated, gin.H{
-			"id":      participantUUID,
-			"message": "Participant registered successfully",
+			"id":                      participantUUID,
+			"message":                 "Participant registered successfully",
+			"price":                   quote,
+			"credit_applied":          creditApplied,
+			"amount_due":              paymentAmount,
+			"payment_status":          paymentStatus,
+			"guardian_consent_status": consentStatus,
 		})
 	}
 }
 
+// cancelParticipantEntry removes a registration: it opens a refund request for what was paid
+// (percent nil follows the event's refund policy) and gives back the promo code use before the
+// row goes away. The archer is told with notifyRefundRequested once tx is committed.
+func cancelParticipantEntry(tx *sqlx.Tx, participantID, reason string, percent *float64) (string, error) {
+	refundID, err := createParticipantRefund(tx, participantID, reason, percent)
+	if err != nil {
+		return "", err
+	}
+	if err := releasePromoCode(tx, participantID); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("DELETE FROM event_participants WHERE uuid = ?", participantID); err != nil {
+		return "", err
+	}
+	return refundID, nil
+}
+
 // CancelParticipantRegistration allows an archer to cancel their registration
 func CancelParticipantRegistration(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -1465,7 +1637,7 @@ func CancelParticipantRegistration(db *sqlx.DB) gin.HandlerFunc {
 		// Check if the participant belongs to the logged-in user
 		var userArcherID string
 		err = db.Get(&userArcherID, "SELECT uuid FROM archers WHERE uuid = ? OR user_id = ?", userID, userID)
-		if err != nil || userArcherID != archerID {
+		if (err != nil || userArcherID != archerID) && !isActiveGuardian(db, userID.(string), archerID) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "You can only cancel your own registration"})
 			return
 		}
@@ -1478,14 +1650,34 @@ func CancelParticipantRegistration(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Delete the participant registration
-		_, err = db.Exec("DELETE FROM event_participants WHERE uuid = ?", participantID)
+		before := rowSnapshot(db, "event_participants", participantID)
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		// Refund per the event's refund policy
+		refundID, err := cancelParticipantEntry(tx, participantID, "participant_cancelled", nil)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel registration"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Registration cancelled successfully"})
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel registration"})
+			return
+		}
+		if refundID != "" {
+			notifyRefundRequested(db, refundID)
+		}
+
+		if eventID, ok := before["event_id"].(string); ok {
+			logEventActivity(db, c, eventID, "participant_cancelled", "event_participant", participantID, "Cancelled registration", before, nil)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Registration cancelled successfully", "refund_id": refundID})
 	}
 }
 
@@ -1517,6 +1709,8 @@ func DeleteEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := rowSnapshot(db, "event_participants", actualParticipantID)
+
 		// Start transaction for cleanup
 		tx, err := db.Beginx()
 		if err != nil {
@@ -1548,7 +1742,11 @@ func DeleteEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 3. Delete from event_participants
+		// 3. Give back the promo code use and delete from event_participants
+		if err := releasePromoCode(tx, actualParticipantID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release promo code", "details": err.Error()})
+			return
+		}
 		_, err = tx.Exec("DELETE FROM event_participants WHERE uuid = ? AND event_id = ?", actualParticipantID, actualEventID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete participant", "details": err.Error()})
@@ -1561,10 +1759,7 @@ func DeleteEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		// Log activity
-		userID, _ := c.Get("user_id")
-		if userID != nil {
-			utils.LogActivity(db, userID.(string), actualEventID, "participant_kicked", "event", actualEventID, "Kicked participant: "+actualParticipantID, c.ClientIP(), c.Request.UserAgent())
-		}
+		logEventActivity(db, c, actualEventID, "participant_kicked", "event_participant", actualParticipantID, "Kicked participant: "+actualParticipantID, before, nil)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Participant removed from event successfully"})
 	}
@@ -1633,6 +1828,20 @@ func UpdateEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 		actualParticipantID := pInfo.UUID
 		fmt.Printf("[DEBUG] Updating participant UUID: %s for input: %s\n", actualParticipantID, participantID)
 
+		// Snapshot the payment before the update so a decision on a pending proof can be recorded
+		var before struct {
+			PaymentStatus  string   `db:"payment_status"`
+			PaymentAmount  float64  `db:"payment_amount"`
+			DeclaredAmount *float64 `db:"declared_amount"`
+			ProofURLs      string   `db:"proof_urls"`
+		}
+		db.Get(&before, `
+			SELECT COALESCE(payment_status, '') as payment_status, COALESCE(payment_amount, 0) as payment_amount,
+				declared_amount, COALESCE(payment_proof_urls, '') as proof_urls
+			FROM event_participants WHERE uuid = ?
+		`, actualParticipantID)
+		snapshot := rowSnapshot(db, "event_participants", actualParticipantID)
+
 		// Build dynamic update query
 		query := "UPDATE event_participants SET updated_at = NOW()"
 		args := []interface{}{}
@@ -1731,9 +1940,20 @@ func UpdateEventParticipant(db *sqlx.DB) gin.HandlerFunc {
 
 		// Log activity
 		userID, _ := c.Get("user_id")
-		if userID != nil {
-			utils.LogActivity(db, userID.(string), actualEventID, "participant_updated", "event_participant", actualParticipantID, "Updated participant", c.ClientIP(), c.Request.UserAgent())
+		if userID != nil && req.PaymentStatus != nil && before.PaymentStatus == "menunggu_acc" && *req.PaymentStatus != "menunggu_acc" {
+			decision := "rejected"
+			if *req.PaymentStatus == "lunas" {
+				decision = "approved"
+			}
+			if err := recordPaymentProofReview(db, actualEventID, actualParticipantID, decision, "", userID.(string), before.PaymentAmount, before.DeclaredAmount, before.ProofURLs); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Participant updated but the payment review could not be recorded", "details": err.Error()})
+				return
+			}
+			if pInfo.ArcherID != nil {
+				notifyPaymentProofDecision(db, *pInfo.ArcherID, actualEventID, decision, "")
+			}
 		}
+		logEventActivity(db, c, actualEventID, "participant_updated", "event_participant", actualParticipantID, "Updated participant", snapshot, rowSnapshot(db, "event_participants", actualParticipantID))
 
 		c.JSON(http.StatusOK, gin.H{"message": "Participant updated successfully"})
 	}
@@ -1782,6 +2002,7 @@ func CreateEventCategories(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		count := 0
+		created := []string{}
 		for _, divUUID := range req.Divisions {
 			for _, catUUID := range req.Categories {
 				// Check if combination already exists
@@ -1805,13 +2026,13 @@ func CreateEventCategories(db *sqlx.DB) gin.HandlerFunc {
 
 				if err == nil {
 					count++
+					created = append(created, catEventID)
 				}
 			}
 		}
 
 		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), eventID, "categories_created", "event", eventID, fmt.Sprintf("Created %d categories in batch", count), c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, eventID, "categories_created", "event", eventID, fmt.Sprintf("Created %d categories in batch", count), nil, gin.H{"category_ids": created})
 
 		c.JSON(http.StatusCreated, gin.H{
 			"message": fmt.Sprintf("Successfully created %d categories", count),
@@ -1919,8 +2140,7 @@ func CreateEventCategory(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), eventID, "category_created", "event_category", catEventID, "Created event category", c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, actualEventID, "category_created", "event_category", catEventID, "Created event category", nil, rowSnapshot(db, "event_categories", catEventID))
 
 		c.JSON(http.StatusCreated, gin.H{
 			"id":      catEventID,
@@ -1986,6 +2206,8 @@ func UpdateEventCategory(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := rowSnapshot(db, "event_categories", categoryID)
+
 		// Build dynamic update query
 		query := "UPDATE event_categories SET updated_at = NOW()"
 		args := []interface{}{}
@@ -2025,8 +2247,7 @@ func UpdateEventCategory(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), eventID, "category_updated", "event_category", categoryID, "Updated event category", c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, actualEventID, "category_updated", "event_category", categoryID, "Updated event category", before, rowSnapshot(db, "event_categories", categoryID))
 
 		c.JSON(http.StatusOK, gin.H{"message": "Category updated successfully"})
 	}
@@ -2070,6 +2291,7 @@ func DeleteEventCategory(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := rowSnapshot(db, "event_categories", categoryID)
 		_, err = db.Exec("DELETE FROM event_categories WHERE uuid = ? AND event_id = ?", categoryID, actualEventID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category", "details": err.Error()})
@@ -2077,8 +2299,7 @@ func DeleteEventCategory(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		// Log activity
-		userID, _ := c.Get("user_id")
-		utils.LogActivity(db, userID.(string), eventID, "category_deleted", "event_category", categoryID, "Deleted event category", c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, actualEventID, "category_deleted", "event_category", categoryID, "Deleted event category", before, nil)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 	}
@@ -2124,7 +2345,6 @@ func GetEventImages(db *sqlx.DB) gin.HandlerFunc {
 func UpdateEventImages(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
-		userID, _ := c.Get("user_id")
 
 		var req struct {
 			Images []struct {
@@ -2141,6 +2361,9 @@ func UpdateEventImages(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		var previous []string
+		db.Select(&previous, "SELECT url FROM event_images WHERE event_id = ? ORDER BY display_order", eventID)
+
 		// Delete existing images
 		_, err := db.Exec("DELETE FROM event_images WHERE event_id = ?", eventID)
 		if err != nil {
@@ -2166,7 +2389,11 @@ func UpdateEventImages(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		// Log activity
-		utils.LogActivity(db, userID.(string), eventID, "event_images_updated", "event", eventID, fmt.Sprintf("Updated %d event images", len(req.Images)), c.ClientIP(), c.Request.UserAgent())
+		urls := make([]string, len(req.Images))
+		for i, img := range req.Images {
+			urls[i] = img.URL
+		}
+		logEventActivity(db, c, eventID, "event_images_updated", "event", eventID, fmt.Sprintf("Updated %d event images", len(req.Images)), gin.H{"images": previous}, gin.H{"images": urls})
 
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Event images updated successfully",
@@ -2355,6 +2582,7 @@ func ReregisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 		// Find participant by qr_raw
 		type ParticipantInfo struct {
 			UUID         string  `db:"uuid"`
+			EventID      string  `db:"event_id"`
 			FullName     string  `db:"full_name"`
 			Email        string  `db:"email"`
 			ClubName     *string `db:"club_name"`
@@ -2368,6 +2596,7 @@ func ReregisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 		err := db.Get(&participant, `
 			SELECT 
 				ep.uuid,
+				ep.event_id,
 				a.full_name,
 				a.email,
 				c.name as club_name,
@@ -2415,6 +2644,8 @@ func ReregisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, participant.EventID, "participant_reregistered", "event_participant", participant.UUID, "Re-registered "+participant.FullName, nil, nil)
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"message": "Registrasi ulang berhasil",
@@ -2430,3 +2661,26 @@ func ReregisterParticipant(db *sqlx.DB) gin.HandlerFunc {
 		})
 	}
 }
+
+// loadManagedEvent resolves an event by UUID or slug and checks that the current user organizes it
+// (admins may manage any event). It writes the error response and returns false on failure.
+func loadManagedEvent(db *sqlx.DB, c *gin.Context, eventParam string) (string, bool) {
+	var event struct {
+		UUID        string  `db:"uuid"`
+		OrganizerID *string `db:"organizer_id"`
+	}
+	err := db.Get(&event, `SELECT uuid, organizer_id FROM events WHERE uuid = ? OR slug = ?`, eventParam, eventParam)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return "", false
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	if role != "admin" && (event.OrganizerID == nil || *event.OrganizerID != userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the event organizer can perform this action"})
+		return "", false
+	}
+
+	return event.UUID, true
+}
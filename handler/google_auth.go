@@ -10,12 +10,11 @@ import (
 	"net/url"
 	"os"
 	"strings"
-	"time"
 
+	"archeryhub-api/models"
 	"archeryhub-api/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
@@ -208,15 +207,51 @@ func GoogleCallback(db *sqlx.DB) gin.HandlerFunc {
 
 		// Find existing user across all tables
 		type UserRecord struct {
-			UUID string `db:"uuid"`
-			Type string
-			Role string `db:"role"`
+			UUID   string `db:"uuid"`
+			Type   string
+			Role   string `db:"role"`
+			Status string `db:"status"`
 		}
 		var record UserRecord
 
+		// Suspended, deactivated and merged accounts can't sign in, like with a password
+		rejectInactive := func() {
+			if c.ContentType() == "application/json" || c.GetHeader("Accept") == "application/json" || c.Request.Method == "POST" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active", "code": "account_inactive"})
+			} else {
+				c.Redirect(http.StatusTemporaryRedirect, appURL+"/auth/login?error=account_inactive")
+			}
+		}
+
+		// A known login signs in as its most recently used profile. It never falls back to the
+		// profile tables, which would skip the identity's and profiles' status.
+		knownIdentity := false
+		if identity, err := findIdentity(db, userInfo.Email, userInfo.ID); err == nil {
+			knownIdentity = true
+			if identity.Status != "active" {
+				rejectInactive()
+				return
+			}
+			if profiles, err := identityProfiles(db, identity.UUID); err == nil {
+				if profile, ok := pickProfile(profiles, ""); ok {
+					userID = profile.ProfileID
+					userType = profile.UserType
+					role = profile.UserType
+					found = true
+				}
+			}
+			if !found {
+				rejectInactive()
+				return
+			}
+		}
+
 		// Priority search
 		tables := []string{"archers", "organizations", "clubs", "sellers"}
 		for _, t := range tables {
+			if found || knownIdentity {
+				break
+			}
 			typeToRole := t
 			if typeToRole == "archers" {
 				typeToRole = "archer"
@@ -231,9 +266,13 @@ func GoogleCallback(db *sqlx.DB) gin.HandlerFunc {
 				typeToRole = "seller"
 			}
 
-			query := "SELECT uuid, '" + typeToRole + "' as role FROM " + t + " WHERE email = ? OR google_id = ?"
+			query := "SELECT uuid, '" + typeToRole + "' as role, COALESCE(status, 'active') as status FROM " + t + " WHERE email = ? OR google_id = ?"
 			err = db.Get(&record, query, userInfo.Email, userInfo.ID)
 			if err == nil && record.UUID != "" {
+				if record.Status != "active" {
+					rejectInactive()
+					return
+				}
 				userType = typeToRole
 				userID = record.UUID
 				role = record.Role
@@ -369,8 +408,62 @@ func GoogleCallback(db *sqlx.DB) gin.HandlerFunc {
 			utils.LogActivity(db, userID, "", "user_registered", userType, userID, "User registered via Google: "+userInfo.Email, c.ClientIP(), c.Request.UserAgent())
 		}
 
-		// Generate JWT token (use displayNameForJWT so existing user keeps their name)
-		token, err := generateGoogleJWT(userID, userInfo.Email, role, userType, displayNameForJWT, userInfo.Picture)
+		// Google has verified the address already
+		if table, ok := accountTable(userType); ok && userInfo.VerifiedEmail {
+			db.Exec("UPDATE "+table+" SET email_verified_at = NOW() WHERE uuid = ? AND email = ? AND email_verified_at IS NULL", userID, userInfo.Email)
+		}
+
+		// Attach the Google account to the profile's identity so any of its profiles can use it
+		identityID, err := ensureIdentityForProfile(db, userType, userID)
+		if err != nil {
+			fmt.Printf("Failed to link identity for %s %s: %v\n", userType, userID, err)
+		} else {
+			db.Exec("UPDATE identities SET google_id = COALESCE(google_id, ?), updated_at = NOW() WHERE uuid = ?", userInfo.ID, identityID)
+			if userInfo.VerifiedEmail {
+				db.Exec("UPDATE identities SET email_verified_at = NOW() WHERE uuid = ? AND email = ? AND email_verified_at IS NULL", identityID, userInfo.Email)
+			}
+			db.Exec("UPDATE identity_profiles SET last_used_at = NOW() WHERE profile_id = ?", userID)
+		}
+
+		// Platform admins get the admin role whichever profile they use
+		identity, _ := loadIdentity(db, identityID)
+		if identity != nil && identity.Status != "active" {
+			rejectInactive()
+			return
+		}
+		if identity != nil {
+			role = sessionRole(identity, userType)
+		}
+
+		// Google stands in for the password only; logins with 2FA still answer the challenge
+		if identity != nil && twoFactorEnabled(identity) {
+			challenge, cerr := startTwoFactorChallenge(db, identity, models.IdentityProfile{ProfileID: userID, UserType: userType})
+			if cerr != nil {
+				if c.ContentType() == "application/json" || c.GetHeader("Accept") == "application/json" {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "token_generation_failed"})
+				} else {
+					c.Redirect(http.StatusTemporaryRedirect, appURL+"/auth/login?error=token_generation_failed")
+				}
+				return
+			}
+			if c.ContentType() == "application/json" || c.GetHeader("Accept") == "application/json" || c.Request.Method == "POST" {
+				c.JSON(http.StatusOK, twoFactorChallengeBody(challenge))
+			} else {
+				c.Redirect(http.StatusTemporaryRedirect, appURL+"/auth/two-factor?challenge="+url.QueryEscape(challenge))
+			}
+			return
+		}
+
+		// Start a session (use displayNameForJWT so existing user keeps their name); sets the cookies
+		tokens, err := issueSession(db, c, sessionProfile{
+			IdentityID: identityID,
+			UserID:     userID,
+			Email:      userInfo.Email,
+			Role:       role,
+			UserType:   userType,
+			Name:       displayNameForJWT,
+			Avatar:     userInfo.Picture,
+		})
 		if err != nil {
 			if c.ContentType() == "application/json" || c.GetHeader("Accept") == "application/json" {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "token_generation_failed"})
@@ -382,22 +475,17 @@ func GoogleCallback(db *sqlx.DB) gin.HandlerFunc {
 
 		// Log activity
 		utils.LogActivity(db, userID, "", "user_logged_in", userType, userID, "User logged in via Google", c.ClientIP(), c.Request.UserAgent())
-
-		// Set cookie
-		isProduction := os.Getenv("ENV") == "production"
-		domain := ""
-		if isProduction {
-			domain = ".archeryhub.id"
+		if identity != nil {
+			recordLoginSuccess(db, c, identity, userID, userType, "google")
 		}
 
-		c.SetSameSite(http.SameSiteLaxMode)
-		c.SetCookie("auth_token", token, 60*60*24*7, "/", domain, isProduction, true)
-
 		// Return response based on request type
 		if c.ContentType() == "application/json" || c.GetHeader("Accept") == "application/json" || c.Request.Method == "POST" {
 			c.JSON(http.StatusOK, gin.H{
-				"token":       token,
-				"is_new_user": isNewUser,
+				"token":         tokens.AccessToken,
+				"refresh_token": tokens.RefreshToken,
+				"expires_in":    int(accessTokenTTL.Seconds()),
+				"is_new_user":   isNewUser,
 				"user": gin.H{
 					"id":         userID,
 					"email":      userInfo.Email,
@@ -410,7 +498,7 @@ func GoogleCallback(db *sqlx.DB) gin.HandlerFunc {
 
 		} else {
 			// Redirect back to app
-			target := appURL + "?token=" + token
+			target := appURL + "?token=" + tokens.AccessToken
 			c.Redirect(http.StatusTemporaryRedirect, target)
 		}
 	}
@@ -516,25 +604,3 @@ func splitState(stateData string) []string {
 	}
 	return result
 }
-
-// generateGoogleJWT generates a JWT token for Google OAuth users
-func generateGoogleJWT(userID, email, role, userType, name, avatar string) (string, error) {
-	secret := []byte(os.Getenv("JWT_SECRET"))
-	if len(secret) == 0 {
-		secret = []byte("archeryhub-secret-key-change-in-production")
-	}
-
-	claims := jwt.MapClaims{
-		"user_id":   userID,
-		"email":     email,
-		"name":      name,
-		"avatar":    avatar,
-		"role":      role,
-		"user_type": userType,
-		"exp":       time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
-		"iat":       time.Now().Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
-}
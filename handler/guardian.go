@@ -0,0 +1,428 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// guardianConsentAge is the age below which an archer needs a guardian's consent to register
+const guardianConsentAge = 18
+
+// guardianLinkSelect selects guardian links with both sides' names
+const guardianLinkSelect = `
+	SELECT g.*, a.full_name as archer_name, a.avatar_url as archer_avatar_url, a.date_of_birth as archer_date_of_birth,
+		COALESCE(ga.full_name, gorg.name, gclub.name, '') as guardian_name
+	FROM archer_guardians g
+	JOIN archers a ON g.archer_id = a.uuid
+	LEFT JOIN archers ga ON g.guardian_type = 'archer' AND g.guardian_id = ga.uuid
+	LEFT JOIN organizations gorg ON g.guardian_type = 'organization' AND g.guardian_id = gorg.uuid
+	LEFT JOIN clubs gclub ON g.guardian_type = 'club' AND g.guardian_id = gclub.uuid
+`
+
+// isMinorOn reports whether someone born on dob is younger than guardianConsentAge at the given time
+func isMinorOn(dob *time.Time, at time.Time) bool {
+	return dob != nil && dob.AddDate(guardianConsentAge, 0, 0).After(at)
+}
+
+// hideMinorContact clears the contact details of a minor before a profile is shown publicly
+func hideMinorContact(a *models.Archer) {
+	if isMinorOn(a.DateOfBirth, time.Now()) {
+		a.Email = nil
+		a.Phone = nil
+		a.Address = nil
+	}
+}
+
+// isActiveGuardian reports whether the user is a confirmed guardian of the archer
+func isActiveGuardian(db sqlx.Queryer, guardianID, archerID string) bool {
+	var ok bool
+	sqlx.Get(db, &ok, `
+		SELECT EXISTS(SELECT 1 FROM archer_guardians WHERE guardian_id = ? AND archer_id = ? AND status = 'active')
+	`, guardianID, archerID)
+	return ok
+}
+
+// createGuardianLink records a guardian link; active links carry the guardian's consent
+func createGuardianLink(q sqlx.Execer, archerID, guardianID, guardianType, relationship, status, requestedBy, ip string) (string, error) {
+	linkID := uuid.New().String()
+	_, err := q.Exec(`
+		INSERT INTO archer_guardians (uuid, archer_id, guardian_id, guardian_type, relationship, status, requested_by, consent_given_at, consent_ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), ?)
+	`, linkID, archerID, guardianID, guardianType, relationship, status, requestedBy, ip)
+	return linkID, err
+}
+
+// registrationGuardianConsent works out the guardian consent for registering the archer for the
+// event. Adults need none (nil status). A minor registered by their guardian (or an admin) is
+// consented straight away; otherwise the registration waits for a guardian and the archer must
+// have one. Writes the error response and returns false when the registration can't go ahead.
+func registrationGuardianConsent(db *sqlx.DB, c *gin.Context, eventID, archerID string) (status, consentBy *string, ok bool) {
+	var info struct {
+		DateOfBirth *time.Time `db:"date_of_birth"`
+		StartDate   *time.Time `db:"start_date"`
+	}
+	if err := db.Get(&info, `
+		SELECT a.date_of_birth, e.start_date
+		FROM archers a, events e
+		WHERE a.uuid = ? AND e.uuid = ?
+	`, archerID, eventID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check guardian consent"})
+		return nil, nil, false
+	}
+
+	at := time.Now()
+	if info.StartDate != nil {
+		at = *info.StartDate
+	}
+	if !isMinorOn(info.DateOfBirth, at) {
+		return nil, nil, true
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	uid, _ := userID.(string)
+	if role == "admin" || isActiveGuardian(db, uid, archerID) {
+		given := "given"
+		return &given, &uid, true
+	}
+
+	var guardians int
+	db.Get(&guardians, "SELECT COUNT(*) FROM archer_guardians WHERE archer_id = ? AND status = 'active'", archerID)
+	if guardians == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Archers under 18 need a registered guardian to consent to event registration",
+			"code":  "GUARDIAN_REQUIRED",
+		})
+		return nil, nil, false
+	}
+
+	pending := "pending"
+	return &pending, nil, true
+}
+
+// notifyGuardiansOfRegistration asks the archer's guardians to consent to a registration
+func notifyGuardiansOfRegistration(db *sqlx.DB, archerID, eventID string) {
+	var guardians []struct {
+		GuardianID   string `db:"guardian_id"`
+		GuardianType string `db:"guardian_type"`
+	}
+	db.Select(&guardians, "SELECT guardian_id, guardian_type FROM archer_guardians WHERE archer_id = ? AND status = 'active'", archerID)
+
+	var eventName string
+	db.Get(&eventName, "SELECT name FROM events WHERE uuid = ?", eventID)
+	for _, g := range guardians {
+		utils.Notify(db, g.GuardianID, g.GuardianType, "warning", "Persetujuan wali diperlukan",
+			archerName(db, archerID)+" mendaftar ke "+eventName+" dan menunggu persetujuan Anda.", "/guardians/consents")
+	}
+}
+
+// loadGuardianLink loads a guardian link the current user is a party to (or any link for admins)
+func loadGuardianLink(db *sqlx.DB, c *gin.Context) (models.ArcherGuardian, bool) {
+	var link models.ArcherGuardian
+	if err := db.Get(&link, "SELECT * FROM archer_guardians WHERE uuid = ?", c.Param("linkId")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Guardian link not found"})
+		return link, false
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	uid, _ := userID.(string)
+	if role != "admin" && link.GuardianID != uid && link.ArcherID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not your guardian link"})
+		return link, false
+	}
+	return link, true
+}
+
+// GetMyWards lists the archers the current user is (or asked to be) guardian of
+func GetMyWards(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var links []models.ArcherGuardianDetail
+		err := db.Select(&links, guardianLinkSelect+`
+			WHERE g.guardian_id = ? AND g.status != 'revoked'
+			ORDER BY a.full_name ASC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wards"})
+			return
+		}
+
+		if links == nil {
+			links = []models.ArcherGuardianDetail{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": links})
+	}
+}
+
+// GetMyGuardians lists the guardians linked to the current archer
+func GetMyGuardians(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var links []models.ArcherGuardianDetail
+		err := db.Select(&links, guardianLinkSelect+`
+			WHERE g.archer_id = ? AND g.status != 'revoked'
+			ORDER BY g.created_at ASC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch guardians"})
+			return
+		}
+
+		if links == nil {
+			links = []models.ArcherGuardianDetail{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": links})
+	}
+}
+
+// RequestGuardianLink asks to become guardian of an existing archer. The link stays pending until
+// an admin confirms it.
+func RequestGuardianLink(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.GuardianLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !req.Consent {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Guardian consent is required"})
+			return
+		}
+
+		var archerID string
+		if err := db.Get(&archerID, "SELECT uuid FROM archers WHERE uuid = ? OR username = ? OR (id != '' AND id = ?)",
+			req.ArcherID, req.ArcherID, req.ArcherID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archer not found"})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		userType, _ := c.Get("user_type")
+		uid, _ := userID.(string)
+		utype, _ := userType.(string)
+		// Another profile of the archer's own login is still the archer
+		if identityID := identityOf(db, uid); uid == archerID || (identityID != "" && identityID == identityOf(db, archerID)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You can't be your own guardian"})
+			return
+		}
+
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM archer_guardians WHERE guardian_id = ? AND archer_id = ? AND status != 'revoked')", uid, archerID)
+		if exists {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already linked to this archer"})
+			return
+		}
+
+		linkID, err := createGuardianLink(db, archerID, uid, utype, req.Relationship, "pending", uid, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request guardian link"})
+			return
+		}
+
+		utils.Notify(db, archerID, "archer", "info", "Permintaan wali",
+			"Seseorang meminta untuk terdaftar sebagai wali Anda. Permintaan ini akan diverifikasi oleh admin.", "/guardians/mine")
+
+		c.JSON(http.StatusCreated, gin.H{"id": linkID, "status": "pending", "message": "Guardian link requested"})
+	}
+}
+
+// ConfirmGuardianLink activates a pending link. Only an admin may confirm, after checking the
+// relationship outside the app: a minor confirming their own guardian would consent for themselves.
+func ConfirmGuardianLink(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link, ok := loadGuardianLink(db, c)
+		if !ok {
+			return
+		}
+
+		if c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Guardian links are confirmed by an admin"})
+			return
+		}
+		if link.Status != "pending" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Guardian link is not pending"})
+			return
+		}
+
+		if _, err := db.Exec("UPDATE archer_guardians SET status = 'active', updated_at = NOW() WHERE uuid = ?", link.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm guardian link"})
+			return
+		}
+
+		utils.Notify(db, link.GuardianID, link.GuardianType, "success", "Wali dikonfirmasi",
+			"Anda sekarang terdaftar sebagai wali "+archerName(db, link.ArcherID)+".", "/guardians/wards")
+		utils.Notify(db, link.ArcherID, "archer", "info", "Wali dikonfirmasi",
+			"Admin telah mengonfirmasi wali Anda.", "/guardians/mine")
+
+		c.JSON(http.StatusOK, gin.H{"message": "Guardian link confirmed"})
+	}
+}
+
+// GetAdminGuardianLinks lists guardian links by status (pending by default) for admins to check
+func GetAdminGuardianLinks(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.DefaultQuery("status", "pending")
+
+		var links []models.ArcherGuardianDetail
+		err := db.Select(&links, guardianLinkSelect+`
+			WHERE g.status = ?
+			ORDER BY g.created_at ASC
+		`, status)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch guardian links"})
+			return
+		}
+
+		if links == nil {
+			links = []models.ArcherGuardianDetail{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": links})
+	}
+}
+
+// RevokeGuardianLink ends a guardian link; either side or an admin may revoke it
+func RevokeGuardianLink(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link, ok := loadGuardianLink(db, c)
+		if !ok {
+			return
+		}
+		if link.Status == "revoked" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Guardian link already revoked"})
+			return
+		}
+
+		if _, err := db.Exec("UPDATE archer_guardians SET status = 'revoked', updated_at = NOW() WHERE uuid = ?", link.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke guardian link"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Guardian link revoked"})
+	}
+}
+
+// GetPendingGuardianConsents lists registrations of the current user's wards awaiting consent
+func GetPendingGuardianConsents(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var consents []models.PendingGuardianConsent
+		err := db.Select(&consents, `
+			SELECT ep.uuid as participant_id, ep.archer_id, a.full_name as archer_name,
+				e.uuid as event_id, e.name as event_name, e.start_date as event_start_date,
+				ep.category_id, ep.registration_date
+			FROM event_participants ep
+			JOIN archer_guardians g ON g.archer_id = ep.archer_id AND g.guardian_id = ? AND g.status = 'active'
+			JOIN archers a ON ep.archer_id = a.uuid
+			JOIN events e ON ep.event_id = e.uuid
+			WHERE ep.guardian_consent_status = 'pending'
+			ORDER BY ep.registration_date ASC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending consents"})
+			return
+		}
+
+		if consents == nil {
+			consents = []models.PendingGuardianConsent{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": consents})
+	}
+}
+
+// DecideGuardianConsent lets a guardian approve or decline a ward's registration. A declined
+// registration is removed.
+func DecideGuardianConsent(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.GuardianConsentDecision
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var participant struct {
+			ArcherID string  `db:"archer_id"`
+			EventID  string  `db:"event_id"`
+			Status   *string `db:"guardian_consent_status"`
+		}
+		err := db.Get(&participant, "SELECT archer_id, event_id, guardian_consent_status FROM event_participants WHERE uuid = ?", c.Param("participantId"))
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load registration"})
+			return
+		}
+
+		userID := c.GetString("user_id")
+		if !isActiveGuardian(db, userID, participant.ArcherID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the archer's guardian can decide on consent"})
+			return
+		}
+		if participant.Status == nil || *participant.Status != "pending" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Registration is not awaiting guardian consent"})
+			return
+		}
+
+		before := rowSnapshot(db, "event_participants", c.Param("participantId"))
+		refundID := ""
+		if req.Approve {
+			_, err = db.Exec(`
+				UPDATE event_participants
+				SET guardian_consent_status = 'given', guardian_consent_by = ?, guardian_consent_at = NOW()
+				WHERE uuid = ?
+			`, userID, c.Param("participantId"))
+		} else {
+			// Declining cancels the registration like the archer would, with everything paid given back
+			fullRefund := 100.0
+			var tx *sqlx.Tx
+			tx, err = db.Beginx()
+			if err == nil {
+				defer tx.Rollback()
+				refundID, err = cancelParticipantEntry(tx, c.Param("participantId"), "guardian_declined", &fullRefund)
+				if err == nil {
+					err = tx.Commit()
+				}
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record guardian consent"})
+			return
+		}
+		if refundID != "" {
+			notifyRefundRequested(db, refundID)
+		}
+
+		var eventName string
+		db.Get(&eventName, "SELECT name FROM events WHERE uuid = ?", participant.EventID)
+		if req.Approve {
+			utils.Notify(db, participant.ArcherID, "archer", "success", "Wali menyetujui pendaftaran",
+				"Pendaftaran Anda ke "+eventName+" telah disetujui wali.", "")
+			logEventActivity(db, c, participant.EventID, "guardian_consent_given", "event_participant", c.Param("participantId"),
+				"Guardian consented to registration", before, rowSnapshot(db, "event_participants", c.Param("participantId")))
+			c.JSON(http.StatusOK, gin.H{"message": "Consent given"})
+			return
+		}
+
+		utils.Notify(db, participant.ArcherID, "archer", "warning", "Wali menolak pendaftaran",
+			"Pendaftaran Anda ke "+eventName+" dibatalkan karena tidak disetujui wali.", "")
+		logEventActivity(db, c, participant.EventID, "guardian_consent_declined", "event_participant", c.Param("participantId"),
+			"Guardian declined registration", before, nil)
+		c.JSON(http.StatusOK, gin.H{"message": "Registration declined and removed", "refund_id": refundID})
+	}
+}
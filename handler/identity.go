@@ -0,0 +1,547 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var errProfileWithoutEmail = errors.New("profile has no email to log in with")
+
+// sessionProfile is who a session acts as: the identity that logged in and the profile chosen
+type sessionProfile struct {
+	IdentityID string
+	UserID     string // profile uuid, what handlers see as user_id
+	Email      string
+	Role       string
+	UserType   string
+	Name       string
+	Avatar     string
+	TwoFactor  bool // passed a second factor when signing in
+	// ImpersonatorID is the admin acting as the profile in a support session
+	ImpersonatorID string
+}
+
+// identityProfileSelect lists profile links with the profile's display fields
+const identityProfileSelect = `
+	SELECT ip.uuid, ip.identity_id, ip.user_type, ip.profile_id, ip.last_used_at, ip.created_at,
+		COALESCE(a.id, ip.profile_id) as code,
+		COALESCE(a.full_name, o.name, cl.name, s.store_name, '') as name,
+		COALESCE(a.username, o.slug, cl.slug, s.slug) as username,
+		COALESCE(a.email, o.email, cl.email, s.email) as email,
+		COALESCE(a.avatar_url, o.avatar_url, cl.avatar_url, s.avatar_url) as avatar_url,
+		COALESCE(a.status, o.status, cl.status, s.status, '') as status
+	FROM identity_profiles ip
+	LEFT JOIN archers a ON ip.user_type = 'archer' AND a.uuid = ip.profile_id
+	LEFT JOIN organizations o ON ip.user_type = 'organization' AND o.uuid = ip.profile_id
+	LEFT JOIN clubs cl ON ip.user_type = 'club' AND cl.uuid = ip.profile_id
+	LEFT JOIN sellers s ON ip.user_type = 'seller' AND s.uuid = ip.profile_id
+`
+
+// findIdentity finds an identity by email, or by Google account id when one is given
+func findIdentity(q sqlx.Queryer, email, googleID string) (*models.Identity, error) {
+	var identity models.Identity
+	err := sqlx.Get(q, &identity, `
+		SELECT * FROM identities
+		WHERE email = ? OR (? != '' AND google_id = ?)
+		ORDER BY (google_id <=> ?) DESC
+		LIMIT 1
+	`, email, googleID, googleID, googleID)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// identityOf returns the identity a profile is linked to, or "" when it isn't linked
+func identityOf(q sqlx.Queryer, profileID string) string {
+	var identityID string
+	sqlx.Get(q, &identityID, "SELECT identity_id FROM identity_profiles WHERE profile_id = ? LIMIT 1", profileID)
+	return identityID
+}
+
+// identityEmailTaken reports whether a login other than the profile's own already uses the email
+func identityEmailTaken(q sqlx.Queryer, profileID, email string) bool {
+	var taken bool
+	sqlx.Get(q, &taken, `
+		SELECT EXISTS(
+			SELECT 1 FROM identities
+			WHERE email = ? AND uuid NOT IN (SELECT identity_id FROM identity_profiles WHERE profile_id = ?)
+		)
+	`, email, profileID)
+	return taken
+}
+
+// syncIdentityEmail carries a profile's new email over to its login. A changed address has to
+// be verified again before it counts.
+func syncIdentityEmail(q sqlx.Execer, profileID, email string) error {
+	if email == "" {
+		return nil
+	}
+	_, err := q.Exec(`
+		UPDATE identities i
+		JOIN identity_profiles p ON p.identity_id = i.uuid
+		SET i.email_verified_at = IF(i.email <=> ?, i.email_verified_at, NULL), i.email = ?, i.updated_at = NOW()
+		WHERE p.profile_id = ?
+	`, email, email, profileID)
+	return err
+}
+
+// ensureIdentityForProfile links a profile to the identity with the same email, creating the
+// identity from the profile's credentials when there's none yet, and returns the identity id.
+// An existing identity keeps its credentials and only fills in what it lacks.
+func ensureIdentityForProfile(db *sqlx.DB, userType, profileID string) (string, error) {
+	if identityID := identityOf(db, profileID); identityID != "" {
+		return identityID, nil
+	}
+
+	table, ok := accountTable(userType)
+	if !ok {
+		return "", errors.New("unknown user type " + userType)
+	}
+
+	var profile struct {
+		Email           *string    `db:"email"`
+		Password        *string    `db:"password"`
+		GoogleID        *string    `db:"google_id"`
+		EmailVerifiedAt *time.Time `db:"email_verified_at"`
+	}
+	if err := db.Get(&profile, "SELECT email, NULLIF(password, '') as password, google_id, email_verified_at FROM "+table+" WHERE uuid = ?", profileID); err != nil {
+		return "", err
+	}
+	if profile.Email == nil || strings.TrimSpace(*profile.Email) == "" {
+		return "", errProfileWithoutEmail
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var identityID string
+	err = tx.Get(&identityID, "SELECT uuid FROM identities WHERE email = ? FOR UPDATE", *profile.Email)
+	if err == sql.ErrNoRows {
+		identityID = uuid.New().String()
+		_, err = tx.Exec(`
+			INSERT INTO identities (uuid, email, password, google_id, email_verified_at, status)
+			VALUES (?, ?, ?, ?, ?, 'active')
+		`, identityID, *profile.Email, profile.Password, profile.GoogleID, profile.EmailVerifiedAt)
+	} else if err == nil {
+		_, err = tx.Exec(`
+			UPDATE identities
+			SET password = COALESCE(password, ?), google_id = COALESCE(google_id, ?),
+				email_verified_at = COALESCE(email_verified_at, ?), updated_at = NOW()
+			WHERE uuid = ?
+		`, profile.Password, profile.GoogleID, profile.EmailVerifiedAt, identityID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO identity_profiles (uuid, identity_id, user_type, profile_id)
+		VALUES (?, ?, ?, ?)
+	`, uuid.New().String(), identityID, userType, profileID); err != nil {
+		return "", err
+	}
+	return identityID, tx.Commit()
+}
+
+// MigrateIdentities links every profile that can log in (it has an email plus a password or a
+// Google account) to an identity. Profiles sharing an email end up on one identity, which takes
+// the credentials of the first profile in Login's lookup order. Safe to run repeatedly.
+func MigrateIdentities(db *sqlx.DB) {
+	linked := 0
+	for _, t := range accountTables {
+		var profileIDs []string
+		err := db.Select(&profileIDs, `
+			SELECT uuid FROM `+t.Table+`
+			WHERE email IS NOT NULL AND email != ''
+				AND ((password IS NOT NULL AND password != '') OR google_id IS NOT NULL)
+				AND uuid NOT IN (SELECT profile_id FROM identity_profiles WHERE user_type = ?)
+		`, t.UserType)
+		if err != nil {
+			log.Printf("[identities] failed to list %s: %v", t.Table, err)
+			continue
+		}
+
+		for _, profileID := range profileIDs {
+			if _, err := ensureIdentityForProfile(db, t.UserType, profileID); err != nil {
+				log.Printf("[identities] failed to link %s %s: %v", t.UserType, profileID, err)
+				continue
+			}
+			linked++
+		}
+	}
+	if linked > 0 {
+		log.Printf("[identities] linked %d existing profiles", linked)
+	}
+
+	if _, ok := emailVerificationSince(); emailVerificationRequired() && !ok {
+		log.Printf("[identities] REQUIRE_EMAIL_VERIFICATION is on without EMAIL_VERIFICATION_SINCE; logins created before the switch must verify too")
+	}
+}
+
+// identityProfiles lists an identity's profiles, most recently used first
+func identityProfiles(db *sqlx.DB, identityID string) ([]models.IdentityProfile, error) {
+	var profiles []models.IdentityProfile
+	err := db.Select(&profiles, identityProfileSelect+`
+		WHERE ip.identity_id = ?
+		ORDER BY ip.last_used_at IS NULL, ip.last_used_at DESC, ip.created_at ASC
+	`, identityID)
+	for i := range profiles {
+		if profiles[i].AvatarURL != nil {
+			masked := utils.MaskMediaURL(*profiles[i].AvatarURL)
+			profiles[i].AvatarURL = &masked
+		}
+	}
+	return profiles, err
+}
+
+// pickProfile returns the first active profile, of the given type when one is asked for
+func pickProfile(profiles []models.IdentityProfile, userType string) (models.IdentityProfile, bool) {
+	for _, p := range profiles {
+		if p.Status == "active" && (userType == "" || p.UserType == userType) {
+			return p, true
+		}
+	}
+	return models.IdentityProfile{}, false
+}
+
+// profileSession scopes a session of the identity to the profile
+func profileSession(identity *models.Identity, profile models.IdentityProfile) sessionProfile {
+	avatar := ""
+	if profile.AvatarURL != nil {
+		avatar = *profile.AvatarURL
+	}
+	return sessionProfile{
+		IdentityID: identity.UUID,
+		UserID:     profile.ProfileID,
+		Email:      identity.Email,
+		Role:       sessionRole(identity, profile.UserType),
+		UserType:   profile.UserType,
+		Name:       profile.Name,
+		Avatar:     avatar,
+	}
+}
+
+// sessionRole is the role a session of the identity's profile gets: platform admins sign in as
+// admin whatever profile they use, everyone else has the role of the profile type
+func sessionRole(identity *models.Identity, userType string) string {
+	if identity.PlatformRole != nil && *identity.PlatformRole == "admin" {
+		return "admin"
+	}
+	return userType
+}
+
+// touchProfile remembers the profile as the identity's most recently used one
+func touchProfile(db *sqlx.DB, linkID string) {
+	db.Exec("UPDATE identity_profiles SET last_used_at = NOW() WHERE uuid = ?", linkID)
+}
+
+// currentIdentity loads the identity of the session, linking older sessions' profiles on the fly
+func currentIdentity(db *sqlx.DB, c *gin.Context) (*models.Identity, bool) {
+	identityID := c.GetString("identity_id")
+	if identityID == "" {
+		var err error
+		identityID, err = ensureIdentityForProfile(db, c.GetString("user_type"), c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This profile has no login of its own"})
+			return nil, false
+		}
+	}
+
+	identity, err := loadIdentity(db, identityID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return nil, false
+	}
+	return identity, true
+}
+
+// GetMyProfiles lists the profiles the current login can switch between
+func GetMyProfiles(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+
+		profiles, err := identityProfiles(db, identity.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+			return
+		}
+
+		current := c.GetString("user_id")
+		for i := range profiles {
+			profiles[i].Current = profiles[i].ProfileID == current
+		}
+
+		if profiles == nil {
+			profiles = []models.IdentityProfile{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": profiles, "identity": identity})
+	}
+}
+
+// SwitchProfile ends the current session and starts one scoped to another profile of the same
+// identity
+func SwitchProfile(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.SwitchProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+
+		profiles, err := identityProfiles(db, identity.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+			return
+		}
+
+		var target *models.IdentityProfile
+		for i := range profiles {
+			if profiles[i].ProfileID == req.ProfileID || profiles[i].UUID == req.ProfileID {
+				target = &profiles[i]
+				break
+			}
+		}
+		if target == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile not linked to your account"})
+			return
+		}
+		if target.Status != "active" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Profile is not active", "code": "account_inactive"})
+			return
+		}
+
+		if sessionID := c.GetString("session_id"); sessionID != "" {
+			revokeSession(db, sessionID, "profile_switch")
+		}
+
+		// The new session keeps the second factor the current one was signed in with
+		session := profileSession(identity, *target)
+		session.TwoFactor = c.GetBool("two_factor")
+		tokens, err := issueSession(db, c, session)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		touchProfile(db, target.UUID)
+
+		utils.LogActivity(db, target.ProfileID, "", "profile_switched", target.UserType, target.ProfileID,
+			"Switched to "+target.UserType+" profile", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, AuthResponse{
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int(accessTokenTTL.Seconds()),
+			User:         target,
+		})
+	}
+}
+
+// CreateLinkedProfile creates a profile of another type (e.g. a club for an archer) under the
+// current identity, using the identity's email
+func CreateLinkedProfile(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.CreateProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM identity_profiles WHERE identity_id = ? AND user_type = ?)", identity.UUID, req.UserType)
+		if exists {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a " + req.UserType + " profile"})
+			return
+		}
+
+		profileID := uuid.New().String()
+		slug := req.Slug
+		if slug == "" {
+			var cleaned strings.Builder
+			for _, r := range strings.ReplaceAll(strings.ToLower(req.Name), " ", "-") {
+				if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+					cleaned.WriteRune(r)
+				}
+			}
+			slug = cleaned.String() + "-" + profileID[:8]
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		switch req.UserType {
+		case "archer":
+			_, err = tx.Exec(`
+				INSERT INTO archers (uuid, username, email, full_name, status, is_verified, email_verified_at, created_at, updated_at)
+				VALUES (?, ?, ?, ?, 'active', true, ?, NOW(), NOW())
+			`, profileID, slug, identity.Email, req.Name, identity.EmailVerifiedAt)
+		case "organization":
+			_, err = tx.Exec(`
+				INSERT INTO organizations (uuid, slug, email, name, status, email_verified_at, created_at, updated_at)
+				VALUES (?, ?, ?, ?, 'active', ?, NOW(), NOW())
+			`, profileID, slug, identity.Email, req.Name, identity.EmailVerifiedAt)
+		case "club":
+			_, err = tx.Exec(`
+				INSERT INTO clubs (uuid, slug, email, name, status, email_verified_at, created_at, updated_at)
+				VALUES (?, ?, ?, ?, 'active', ?, NOW(), NOW())
+			`, profileID, slug, identity.Email, req.Name, identity.EmailVerifiedAt)
+		case "seller":
+			_, err = tx.Exec(`
+				INSERT INTO sellers (uuid, slug, email, store_name, status, email_verified_at, created_at, updated_at)
+				VALUES (?, ?, ?, ?, 'active', ?, NOW(), NOW())
+			`, profileID, slug, identity.Email, req.Name, identity.EmailVerifiedAt)
+		}
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Failed to create profile; the email or slug may already be used by another " + req.UserType, "details": err.Error()})
+			return
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO identity_profiles (uuid, identity_id, user_type, profile_id)
+			VALUES (?, ?, ?, ?)
+		`, uuid.New().String(), identity.UUID, req.UserType, profileID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link profile"})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit profile"})
+			return
+		}
+
+		utils.LogActivity(db, profileID, "", "user_registered", req.UserType, profileID,
+			"Profile added to existing login: "+req.Name, c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Profile created", "profile_id": profileID, "user_type": req.UserType, "slug": slug})
+	}
+}
+
+// LinkExistingProfile moves the profiles of another login into the current identity after
+// checking that login's credentials. The other login is disabled and its sessions revoked.
+func LinkExistingProfile(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.LinkProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+
+		// Guessing the other login's password here is throttled like the login form
+		if !checkLoginThrottle(c, req.Email) {
+			recordLoginAttempt(db, c, req.Email, "", false, "throttled")
+			return
+		}
+
+		other, err := findIdentity(db, req.Email, "")
+		if err == sql.ErrNoRows {
+			if account, aerr := findAccountByEmail(db, req.Email); aerr == nil {
+				if _, lerr := ensureIdentityForProfile(db, account.UserType, account.UUID); lerr == nil {
+					other, err = findIdentity(db, req.Email, "")
+				}
+			}
+		}
+		if err != nil || other.Password == nil || *other.Password != req.Password {
+			var failed *models.Identity
+			if err == nil {
+				failed = other
+			}
+			recordLoginFailure(db, c, req.Email, failed, "invalid_credentials")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password", "code": "invalid_credentials"})
+			return
+		}
+		clearLoginFailures(req.Email)
+		if other.UUID == identity.UUID {
+			c.JSON(http.StatusConflict, gin.H{"error": "That login is already yours"})
+			return
+		}
+		// The other login's second factor is checked like a sign-in to it would
+		if twoFactorEnabled(other) && req.Code == "" && req.RecoveryCode == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "That login needs its two-factor code", "code": "two_factor_required"})
+			return
+		}
+
+		var clash bool
+		db.Get(&clash, `
+			SELECT EXISTS(
+				SELECT 1 FROM identity_profiles mine
+				JOIN identity_profiles theirs ON theirs.user_type = mine.user_type AND theirs.identity_id = ?
+				WHERE mine.identity_id = ?
+			)
+		`, other.UUID, identity.UUID)
+		if clash {
+			c.JSON(http.StatusConflict, gin.H{"error": "Both logins have a profile of the same type"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if twoFactorEnabled(other) && !checkSecondFactor(tx, other, req.Code, req.RecoveryCode) {
+			recordLoginFailure(db, c, req.Email, other, "invalid_two_factor_code")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code", "code": "invalid_two_factor_code"})
+			return
+		}
+		if _, err := tx.Exec("UPDATE identity_profiles SET identity_id = ? WHERE identity_id = ?", identity.UUID, other.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link profiles"})
+			return
+		}
+		if _, err := tx.Exec("UPDATE identities SET status = 'disabled', updated_at = NOW() WHERE uuid = ?", other.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable the other login"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit link"})
+			return
+		}
+
+		revokeUserSessions(db, other.UUID, "identity_linked")
+		utils.LogActivity(db, c.GetString("user_id"), "", "identity_linked", c.GetString("user_type"), identity.UUID,
+			"Linked login "+other.Email, c.ClientIP(), c.Request.UserAgent())
+
+		profiles, _ := identityProfiles(db, identity.UUID)
+		if profiles == nil {
+			profiles = []models.IdentityProfile{}
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Profiles linked", "data": profiles})
+	}
+}
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// loginLimit is how hard a throttled key is slowed down: the first FreeAttempts failures cost
+// nothing, each later one doubles the wait up to MaxDelay, and LockoutAt failures lock the key
+// for Lockout. Counters are forgotten after Window without failures.
+type loginLimit struct {
+	Prefix       string
+	FreeAttempts int
+	LockoutAt    int
+	MaxDelay     time.Duration
+	Lockout      time.Duration
+	Window       time.Duration
+}
+
+var (
+	accountLoginLimit = loginLimit{Prefix: "login:account:", FreeAttempts: 3, LockoutAt: 10, MaxDelay: 30 * time.Second, Lockout: 15 * time.Minute, Window: 15 * time.Minute}
+	ipLoginLimit      = loginLimit{Prefix: "login:ip:", FreeAttempts: 10, LockoutAt: 50, MaxDelay: 30 * time.Second, Lockout: 15 * time.Minute, Window: 15 * time.Minute}
+	// twoFactorLoginLimit counts wrong second-factor codes per identity. A correct password doesn't
+	// reset it, so starting fresh challenges doesn't buy more guesses.
+	twoFactorLoginLimit = loginLimit{Prefix: "login:2fa:", FreeAttempts: 3, LockoutAt: 10, MaxDelay: 30 * time.Second, Lockout: time.Hour, Window: time.Hour}
+)
+
+// deviceCookieMaxAge is how long a browser is remembered as a known device
+const deviceCookieMaxAge = 365 * 24 * 60 * 60
+
+// loginAccountKey normalises the email so case variants share one counter
+func loginAccountKey(email string) string {
+	return accountLoginLimit.Prefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+// fail counts a failure against the key and returns the updated counter
+func (l loginLimit) fail(key string) utils.Attempt {
+	return utils.Attempts().Incr(key, l.Window, func(failures int) time.Duration {
+		switch {
+		case failures >= l.LockoutAt:
+			return l.Lockout
+		case failures > l.FreeAttempts:
+			delay := time.Duration(math.Pow(2, float64(failures-l.FreeAttempts-1))) * time.Second
+			if delay > l.MaxDelay {
+				delay = l.MaxDelay
+			}
+			return delay
+		}
+		return 0
+	})
+}
+
+// checkLoginThrottle refuses the attempt while the IP or the account has to wait. It answers
+// before the password is looked at, so a locked account can't be probed. It writes the error
+// response and returns false.
+func checkLoginThrottle(c *gin.Context, email string) bool {
+	store := utils.Attempts()
+	now := time.Now()
+
+	for _, key := range []string{ipLoginLimit.Prefix + c.ClientIP(), loginAccountKey(email)} {
+		a := store.Get(key)
+		if !a.LockedUntil.After(now) {
+			continue
+		}
+
+		wait := int(math.Ceil(a.LockedUntil.Sub(now).Seconds()))
+		c.Header("Retry-After", fmt.Sprint(wait))
+
+		code := "too_many_attempts"
+		message := fmt.Sprintf("Terlalu banyak percobaan login. Coba lagi dalam %d detik.", wait)
+		if a.Failures >= ipLoginLimit.LockoutAt || (strings.HasPrefix(key, accountLoginLimit.Prefix) && a.Failures >= accountLoginLimit.LockoutAt) {
+			code = "account_locked"
+			message = fmt.Sprintf("Login dikunci sementara karena terlalu banyak percobaan gagal. Coba lagi dalam %d menit.", (wait+59)/60)
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": message, "code": code, "retry_after": wait})
+		return false
+	}
+	return true
+}
+
+// checkTwoFactorThrottle refuses a second-factor code while the identity has to wait. It writes
+// the error response and returns false.
+func checkTwoFactorThrottle(c *gin.Context, identityID string) bool {
+	now := time.Now()
+	a := utils.Attempts().Get(twoFactorLoginLimit.Prefix + identityID)
+	if !a.LockedUntil.After(now) {
+		return true
+	}
+
+	wait := int(math.Ceil(a.LockedUntil.Sub(now).Seconds()))
+	c.Header("Retry-After", fmt.Sprint(wait))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("Terlalu banyak kode yang salah. Coba lagi dalam %d detik.", wait),
+		"code":        "too_many_attempts",
+		"retry_after": wait,
+	})
+	return false
+}
+
+// recordTwoFactorFailure counts a wrong second-factor code against the IP, the account and the
+// identity's own counter
+func recordTwoFactorFailure(db *sqlx.DB, c *gin.Context, identity *models.Identity) {
+	recordLoginFailure(db, c, identity.Email, identity, "invalid_two_factor_code")
+	twoFactorLoginLimit.fail(twoFactorLoginLimit.Prefix + identity.UUID)
+}
+
+// clearTwoFactorFailures forgets the identity's wrong codes once a second factor checked out
+func clearTwoFactorFailures(identityID string) {
+	utils.Attempts().Delete(twoFactorLoginLimit.Prefix + identityID)
+}
+
+// recordLoginAttempt keeps a row of every attempt for the account's security history
+func recordLoginAttempt(db *sqlx.DB, c *gin.Context, email, identityID string, success bool, reason string) {
+	var identity *string
+	if identityID != "" {
+		identity = &identityID
+	}
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	db.Exec(`
+		INSERT INTO login_attempts (uuid, email, identity_id, success, reason, ip_address, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), strings.ToLower(email), identity, success, reason, c.ClientIP(), userAgent)
+}
+
+// recordLoginFailure counts a failed attempt against the IP and the account. The owner is
+// emailed when the account gets locked.
+func recordLoginFailure(db *sqlx.DB, c *gin.Context, email string, identity *models.Identity, reason string) {
+	identityID := ""
+	if identity != nil {
+		identityID = identity.UUID
+	}
+	recordLoginAttempt(db, c, email, identityID, false, reason)
+
+	ipLoginLimit.fail(ipLoginLimit.Prefix + c.ClientIP())
+	a := accountLoginLimit.fail(loginAccountKey(email))
+
+	if identity != nil && a.Failures == accountLoginLimit.LockoutAt {
+		utils.SendMail(utils.Mail{
+			To:      identity.Email,
+			Subject: "Akun Archery Hub dikunci sementara",
+			Body: "Halo,\n\n" +
+				fmt.Sprintf("Terdapat %d percobaan login gagal ke akun Anda, terakhir dari alamat IP %s. ", a.Failures, c.ClientIP()) +
+				fmt.Sprintf("Login dikunci selama %d menit.\n\n", int(accountLoginLimit.Lockout.Minutes())) +
+				"Jika ini bukan Anda, segera atur ulang password Anda:\n" + appLink("/auth/forgot-password", "") + "\n",
+		})
+	}
+}
+
+// clearLoginFailures forgets the account's failures once its password checked out. The IP keeps
+// its count, so one valid account doesn't reset a credential-stuffing run.
+func clearLoginFailures(email string) {
+	utils.Attempts().Delete(loginAccountKey(email))
+}
+
+// deviceID reads the device id from the X-Device-ID header (mobile app) or the device cookie
+func deviceID(c *gin.Context) string {
+	if id := c.GetHeader("X-Device-ID"); id != "" {
+		return id
+	}
+	id, _ := c.Cookie("device_id")
+	return id
+}
+
+// setDeviceCookie remembers the browser across logins
+func setDeviceCookie(c *gin.Context, id string) {
+	isProduction := os.Getenv("ENV") == "production"
+	host := c.Request.Host
+	isLocal := strings.HasPrefix(host, "localhost") || strings.HasPrefix(host, "127.0.0.1") || strings.HasPrefix(host, "0.0.0.0")
+
+	domain := ""
+	secure := false
+	if isProduction && !isLocal {
+		domain = ".archeryhub.id"
+		secure = true
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("device_id", id, deviceCookieMaxAge, "/", domain, secure, true)
+}
+
+// recordLoginSuccess logs a completed login and alerts the owner when it came from a device the
+// identity never used before. The very first device of an identity isn't announced.
+func recordLoginSuccess(db *sqlx.DB, c *gin.Context, identity *models.Identity, profileID, userType, method string) {
+	recordLoginAttempt(db, c, identity.Email, identity.UUID, true, method)
+	clearLoginFailures(identity.Email)
+
+	device := deviceID(c)
+	if device == "" {
+		device = uuid.New().String()
+		setDeviceCookie(c, device)
+	}
+	deviceHash := hashToken(device)
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	var known, anyDevice bool
+	db.Get(&known, "SELECT EXISTS(SELECT 1 FROM identity_devices WHERE identity_id = ? AND device_hash = ?)", identity.UUID, deviceHash)
+	if known {
+		db.Exec(`
+			UPDATE identity_devices SET last_seen_at = NOW(), ip_address = ?, user_agent = ?
+			WHERE identity_id = ? AND device_hash = ?
+		`, c.ClientIP(), userAgent, identity.UUID, deviceHash)
+		return
+	}
+
+	db.Get(&anyDevice, "SELECT EXISTS(SELECT 1 FROM identity_devices WHERE identity_id = ?)", identity.UUID)
+	db.Exec(`
+		INSERT INTO identity_devices (uuid, identity_id, device_hash, user_agent, ip_address, first_seen_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, NOW(), NOW())
+	`, uuid.New().String(), identity.UUID, deviceHash, userAgent, c.ClientIP())
+	if !anyDevice {
+		return
+	}
+
+	when := time.Now().Format("02 Jan 2006 15:04")
+	utils.Notify(db, profileID, userType, "warning", "Login dari perangkat baru",
+		fmt.Sprintf("Akun Anda digunakan untuk login dari perangkat baru (%s, IP %s) pada %s. Jika ini bukan Anda, segera ganti password dan keluar dari semua sesi.", userAgent, c.ClientIP(), when),
+		"/settings/security")
+	utils.SendMail(utils.Mail{
+		To:      identity.Email,
+		Subject: "Login baru ke akun Archery Hub",
+		Body: "Halo,\n\n" +
+			"Akun Anda baru saja digunakan untuk login dari perangkat yang belum pernah dipakai sebelumnya.\n\n" +
+			"Waktu: " + when + "\n" +
+			"Alamat IP: " + c.ClientIP() + "\n" +
+			"Perangkat: " + userAgent + "\n\n" +
+			"Jika ini bukan Anda, segera atur ulang password Anda dan keluar dari semua sesi:\n" + appLink("/auth/forgot-password", "") + "\n",
+	})
+}
+
+// GetMyLoginHistory lists the recent login attempts on the current login, newest first
+func GetMyLoginHistory(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+
+		var attempts []models.LoginAttempt
+		err := db.Select(&attempts, `
+			SELECT uuid, success, reason, ip_address, user_agent, created_at FROM login_attempts
+			WHERE identity_id = ? OR email = ?
+			ORDER BY created_at DESC
+			LIMIT 50
+		`, identity.UUID, strings.ToLower(identity.Email))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login history"})
+			return
+		}
+
+		if attempts == nil {
+			attempts = []models.LoginAttempt{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": attempts})
+	}
+}
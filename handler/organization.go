@@ -40,6 +40,7 @@ type Organization struct {
 	SocialTwitter      *string `db:"social_twitter" json:"social_twitter"`
 	SocialMedia        *string `db:"social_media" json:"social_media"`
 	VerificationStatus *string `db:"verification_status" json:"verification_status"`
+	IsVerified         bool    `db:"-" json:"is_verified"`
 	Status             *string `db:"status" json:"status"`
 	CreatedAt          string  `db:"created_at" json:"created_at"`
 	UpdatedAt          string  `db:"updated_at" json:"updated_at"`
@@ -77,6 +78,7 @@ func GetOrganizations(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		for i := range orgs {
+			orgs[i].IsVerified = isVerified(orgs[i].VerificationStatus)
 			if orgs[i].AvatarURL != nil {
 				masked := utils.MaskMediaURL(*orgs[i].AvatarURL)
 				orgs[i].AvatarURL = &masked
@@ -194,6 +196,7 @@ func GetOrganizationBySlug(db *sqlx.DB) gin.HandlerFunc {
 				"mission":              org.Mission,
 				"history":               org.History,
 				"verification_status":  org.VerificationStatus,
+				"is_verified":          isVerified(org.VerificationStatus),
 				"status":               org.Status,
 				"created_at":           org.CreatedAt,
 				"updated_at":           org.UpdatedAt,
@@ -107,7 +107,11 @@ func CreatePayment(db *sqlx.DB) gin.HandlerFunc {
 		var amount int
 		var customerName, customerEmail, customerPhone string
 		var registrationID *string
+		var participantID *string
 		var orderItems []gin.H
+		// Pricing a participant (which may use up a promo code) is only committed together with
+		// the saved transaction, so a failed gateway call doesn't keep the promo use
+		var pricingTx *sqlx.Tx
 
 		if req.Type == "platform_fee" {
 			// Get event details
@@ -154,6 +158,97 @@ func CreatePayment(db *sqlx.DB) gin.HandlerFunc {
 					"quantity": 1,
 				},
 			}
+		} else if req.Type == "participant" {
+			if req.ParticipantID == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ParticipantID is required for participant type"})
+				return
+			}
+
+			var participant struct {
+				UUID          string  `db:"uuid"`
+				ArcherID      string  `db:"archer_id"`
+				PaymentStatus string  `db:"payment_status"`
+				FullName      string  `db:"full_name"`
+				Email         *string `db:"email"`
+				Phone         *string `db:"phone"`
+				EventName     string  `db:"event_name"`
+				ConsentStatus *string `db:"guardian_consent_status"`
+			}
+			err := db.Get(&participant, `
+				SELECT ep.uuid, ep.archer_id, COALESCE(ep.payment_status, '') as payment_status,
+				       a.full_name, a.email, a.phone, e.name as event_name, ep.guardian_consent_status
+				FROM event_participants ep
+				JOIN archers a ON ep.archer_id = a.uuid
+				JOIN events e ON ep.event_id = e.uuid
+				WHERE ep.uuid = ? AND ep.event_id = ?
+			`, *req.ParticipantID, req.EventID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+				return
+			}
+			if participant.ArcherID != userID.(string) && !isActiveGuardian(db, userID.(string), participant.ArcherID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only pay for your own registration or your ward's"})
+				return
+			}
+			if participant.ConsentStatus != nil && *participant.ConsentStatus == "pending" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Registration is waiting for guardian consent"})
+				return
+			}
+			if participant.PaymentStatus == "lunas" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Already paid"})
+				return
+			}
+
+			// Re-price at payment time (early-bird is kept from the registration date)
+			tx, err := db.Beginx()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+				return
+			}
+			defer tx.Rollback()
+
+			price, err := priceParticipantForPayment(tx, participant.UUID, req.PromoCode)
+			if err == errPromoCodeInvalid {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute entry price"})
+				return
+			}
+			if price <= 0 {
+				// A promo code that covers the whole fee settles the entry right away
+				_, err := tx.Exec(`
+					UPDATE event_participants
+					SET payment_status = 'lunas', status = 'Terdaftar', qr_raw = COALESCE(qr_raw, ?), updated_at = NOW()
+					WHERE uuid = ?
+				`, uuid.New().String(), participant.UUID)
+				if err == nil {
+					err = tx.Commit()
+				}
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save entry price"})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"message": "Nothing to pay for this registration", "payment_status": "lunas"})
+				return
+			}
+			pricingTx = tx
+
+			amount = int(price)
+			customerName = participant.FullName
+			customerEmail = utils.StringValue(participant.Email, "user@archeryhub.id")
+			customerPhone = utils.StringValue(participant.Phone, "08123456789")
+			participantID = req.ParticipantID
+
+			orderItems = []gin.H{
+				{
+					"sku":      "EVENT-ENTRY",
+					"name":     fmt.Sprintf("Event Entry Fee - %s", participant.EventName),
+					"price":    amount,
+					"quantity": 1,
+				},
+			}
 		} else {
 			// Default to registration
 			if req.RegistrationID == nil {
@@ -228,6 +323,13 @@ func CreatePayment(db *sqlx.DB) gin.HandlerFunc {
 			expiredAt = time.Unix(int64(exp), 0)
 		}
 
+		feeMerchant, _ := tripayResult["fee_merchant"].(float64)
+		feeCustomer, _ := tripayResult["fee_customer"].(float64)
+		totalAmount := float64(amount)
+		if total, ok := tripayResult["amount"].(float64); ok {
+			totalAmount = total
+		}
+
 		transaction := models.PaymentTransaction{
 			UUID:            transactionID,
 			Reference:       merchantRef,
@@ -235,9 +337,12 @@ func CreatePayment(db *sqlx.DB) gin.HandlerFunc {
 			UserID:          userID.(string),
 			EventID:         &req.EventID,
 			RegistrationID:  registrationID,
+			ParticipantID:   participantID,
 			Amount:          float64(amount),
-			FeeAmount:       0, // We'll calculate this better later if needed
-			TotalAmount:     float64(amount),
+			FeeAmount:       feeMerchant + feeCustomer,
+			FeeMerchant:     feeMerchant,
+			FeeCustomer:     feeCustomer,
+			TotalAmount:     totalAmount,
 			PaymentMethod:   utils.StringPtr(req.Method),
 			VANumber:        utils.InterfaceToStringPtr(tripayResult["pay_code"]),
 			QRURL:           utils.InterfaceToStringPtr(tripayResult["qr_url"]),
@@ -249,20 +354,30 @@ func CreatePayment(db *sqlx.DB) gin.HandlerFunc {
 
 		query := `
 			INSERT INTO payment_transactions (
-				id, reference, tripay_reference, user_id, event_id, registration_id,
-				amount, fee_amount, total_amount, payment_method, va_number, qr_url,
+				uuid, reference, tripay_reference, user_id, event_id, registration_id, participant_id,
+				amount, fee_amount, fee_merchant, fee_customer, total_amount, payment_method, va_number, qr_url,
 				checkout_url, pay_code, status, expired_at
 			) VALUES (
-				:id, :reference, :tripay_reference, :user_id, :event_id, :registration_id,
-				:amount, :fee_amount, :total_amount, :payment_method, :va_number, :qr_url,
+				:uuid, :reference, :tripay_reference, :user_id, :event_id, :registration_id, :participant_id,
+				:amount, :fee_amount, :fee_merchant, :fee_customer, :total_amount, :payment_method, :va_number, :qr_url,
 				:checkout_url, :pay_code, :status, :expired_at
 			)
 		`
-		_, err = db.NamedExec(query, transaction)
+		var saver sqlx.Ext = db
+		if pricingTx != nil {
+			saver = pricingTx
+		}
+		_, err = sqlx.NamedExec(saver, query, transaction)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save transaction: " + err.Error()})
 			return
 		}
+		if pricingTx != nil {
+			if err := pricingTx.Commit(); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save transaction: " + err.Error()})
+				return
+			}
+		}
 
 		// Update registration if applicable
 		if registrationID != nil {
@@ -306,68 +421,18 @@ func PaymentCallback(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Update transaction status
-		var transactionID string
-		var eventID *string
-		var registrationID *string
-		err = db.QueryRow("SELECT uuid, event_id, registration_id FROM payment_transactions WHERE reference = ?", payload.MerchantRef).Scan(&transactionID, &eventID, &registrationID)
-		if err != nil {
+		outcome, err := applyPaymentSignal(db, payload.MerchantRef, "callback", payload.Status, body)
+		if err == errPaymentNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
 			return
 		}
-
-		status := "pending"
-		regPaymentStatus := "pending"
-		if payload.Status == "PAID" {
-			status = "paid"
-			regPaymentStatus = "paid"
-		} else if payload.Status == "EXPIRED" {
-			status = "expired"
-			regPaymentStatus = "unpaid"
-		} else if payload.Status == "FAILED" {
-			status = "failed"
-			regPaymentStatus = "unpaid"
-		}
-
-		tx, err := db.Beginx()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
-			return
-		}
-
-		_, err = tx.Exec("UPDATE payment_transactions SET status = ?, callback_data = ?, paid_at = ? WHERE uuid = ?", status, body, time.Now(), transactionID)
 		if err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
-			return
-		}
-
-		// Update registration if applicable
-		if registrationID != nil {
-			_, err = tx.Exec("UPDATE event_registrations SET payment_status = ? WHERE id = ?", regPaymentStatus, *registrationID)
-			if err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update registration"})
-				return
-			}
-		}
-
-		// Update event status if platform fee is paid
-		if status == "paid" && registrationID == nil && eventID != nil {
-			_, err = tx.Exec("UPDATE events SET status = 'published' WHERE uuid = ?", *eventID)
-			if err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event status"})
-				return
-			}
-		}
-
-		if err := tx.Commit(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process callback"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"success": true})
+		// Duplicate and rejected callbacks are still acknowledged so Tripay stops retrying
+		c.JSON(http.StatusOK, gin.H{"success": true, "outcome": outcome})
 	}
 }
 
@@ -480,6 +545,9 @@ func CreateEventPaymentMethod(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, eventID, "payment_method_created", "event_payment_method", methodID, "Added payment method "+req.PaymentMethod,
+			nil, rowSnapshot(db, "event_payment_methods", methodID))
+
 		c.JSON(http.StatusCreated, gin.H{
 			"uuid":    methodID,
 			"message": "Payment method created successfully",
@@ -506,6 +574,7 @@ func UpdateEventPaymentMethod(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := rowSnapshot(db, "event_payment_methods", methodID)
 		_, err := db.Exec(`
 			UPDATE event_payment_methods 
 			SET payment_method = COALESCE(?, payment_method),
@@ -523,6 +592,10 @@ func UpdateEventPaymentMethod(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		after := rowSnapshot(db, "event_payment_methods", methodID)
+		eventID, _ := after["event_id"].(string)
+		logEventActivity(db, c, eventID, "payment_method_updated", "event_payment_method", methodID, "Updated payment method", before, after)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Payment method updated successfully"})
 	}
 }
@@ -532,12 +605,17 @@ func DeleteEventPaymentMethod(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		methodID := c.Param("methodId")
 
+		before := rowSnapshot(db, "event_payment_methods", methodID)
 		_, err := db.Exec("DELETE FROM event_payment_methods WHERE uuid = ?", methodID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payment method"})
 			return
 		}
 
+		if eventID, ok := before["event_id"].(string); ok {
+			logEventActivity(db, c, eventID, "payment_method_deleted", "event_payment_method", methodID, "Deleted payment method", before, nil)
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Payment method deleted successfully"})
 	}
 }
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var errPaymentNotFound = errors.New("payment transaction not found")
+
+// paymentTransitions lists the status changes a payment transaction is allowed to make.
+// Anything not listed here (e.g. paid -> expired from a late callback) is refused.
+var paymentTransitions = map[string][]string{
+	"pending":  {"paid", "expired", "failed"},
+	"expired":  {"paid"}, // Tripay may settle a payment that was made right before expiry
+	"failed":   {},
+	"paid":     {"refunded"},
+	"refunded": {},
+}
+
+// canTransitionPayment reports whether a transaction may move from one status to another
+func canTransitionPayment(from, to string) bool {
+	for _, allowed := range paymentTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentStatusFromTripay maps a Tripay transaction status to our local status
+func paymentStatusFromTripay(tripayStatus string) string {
+	switch tripayStatus {
+	case "PAID":
+		return "paid"
+	case "EXPIRED":
+		return "expired"
+	case "FAILED":
+		return "failed"
+	case "REFUND":
+		return "refunded"
+	default:
+		return "pending"
+	}
+}
+
+// registrationPaymentStatus maps a transaction status to event_registrations.payment_status
+func registrationPaymentStatus(status string) string {
+	switch status {
+	case "paid":
+		return "paid"
+	case "refunded":
+		return "refunded"
+	case "expired", "failed":
+		return "unpaid"
+	default:
+		return "pending"
+	}
+}
+
+// applyPaymentSignal appends a ledger entry for a status signal and applies the resulting
+// transition when it is legal. Repeated signals are recorded as duplicates and change nothing.
+// It returns the ledger outcome: applied, duplicate or rejected.
+func applyPaymentSignal(db *sqlx.DB, reference, source, tripayStatus string, payload []byte) (string, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var trx struct {
+		UUID           string  `db:"uuid"`
+		Status         string  `db:"status"`
+		EventID        *string `db:"event_id"`
+		RegistrationID *string `db:"registration_id"`
+		ParticipantID  *string `db:"participant_id"`
+		DuesInvoiceID  *string `db:"dues_invoice_id"`
+	}
+	err = tx.Get(&trx, `
+		SELECT uuid, status, event_id, registration_id, participant_id, dues_invoice_id
+		FROM payment_transactions
+		WHERE reference = ?
+		FOR UPDATE
+	`, reference)
+	if err == sql.ErrNoRows {
+		return "", errPaymentNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(sum[:])
+	toStatus := paymentStatusFromTripay(tripayStatus)
+
+	var seen bool
+	if err := tx.Get(&seen, `
+		SELECT EXISTS(SELECT 1 FROM payment_events WHERE transaction_id = ? AND payload_hash = ?)
+	`, trx.UUID, payloadHash); err != nil {
+		return "", err
+	}
+
+	outcome := "applied"
+	if seen || toStatus == trx.Status {
+		outcome = "duplicate"
+	} else if !canTransitionPayment(trx.Status, toStatus) {
+		outcome = "rejected"
+	}
+
+	// Polling a still-pending transaction isn't news; logging each poll would flood the ledger
+	if outcome == "duplicate" && toStatus == trx.Status && source == "reconcile" {
+		return outcome, tx.Commit()
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO payment_events (uuid, transaction_id, source, tripay_status, from_status, to_status, outcome, payload_hash, payload)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), trx.UUID, source, tripayStatus, trx.Status, toStatus, outcome, payloadHash, payload)
+	if err != nil {
+		return "", err
+	}
+
+	if outcome != "applied" {
+		return outcome, tx.Commit()
+	}
+
+	var paidAt interface{}
+	if toStatus == "paid" {
+		paidAt = time.Now()
+	}
+
+	// Callbacks and transaction details both carry the final Tripay fees
+	var fees struct {
+		FeeMerchant *float64 `json:"fee_merchant"`
+		FeeCustomer *float64 `json:"fee_customer"`
+	}
+	_ = json.Unmarshal(payload, &fees)
+
+	_, err = tx.Exec(`
+		UPDATE payment_transactions
+		SET status = ?, callback_data = ?, paid_at = COALESCE(?, paid_at),
+		    fee_merchant = COALESCE(?, fee_merchant), fee_customer = COALESCE(?, fee_customer),
+		    fee_amount = COALESCE(?, fee_merchant) + COALESCE(?, fee_customer),
+		    updated_at = NOW()
+		WHERE uuid = ?
+	`, toStatus, payload, paidAt, fees.FeeMerchant, fees.FeeCustomer, fees.FeeMerchant, fees.FeeCustomer, trx.UUID)
+	if err != nil {
+		return "", err
+	}
+
+	if trx.RegistrationID != nil {
+		_, err = tx.Exec("UPDATE event_registrations SET payment_status = ? WHERE id = ?", registrationPaymentStatus(toStatus), *trx.RegistrationID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if trx.ParticipantID != nil && toStatus == "paid" {
+		_, err = tx.Exec(`
+			UPDATE event_participants
+			SET payment_status = 'lunas', status = 'Terdaftar', qr_raw = COALESCE(qr_raw, ?)
+			WHERE uuid = ?
+		`, uuid.New().String(), *trx.ParticipantID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if trx.DuesInvoiceID != nil && toStatus == "paid" {
+		if err := settleDuesInvoice(tx, *trx.DuesInvoiceID, trx.UUID); err != nil {
+			return "", err
+		}
+	}
+
+	// Platform fee paid: the event goes live, unless its organizer still has to be verified
+	// (it's then published with PublishEvent once they are)
+	if toStatus == "paid" && trx.RegistrationID == nil && trx.ParticipantID == nil && trx.EventID != nil {
+		var organizerID string
+		sqlx.Get(tx, &organizerID, "SELECT COALESCE(organizer_id, '') FROM events WHERE uuid = ?", *trx.EventID)
+		if organizerMayPublish(tx, organizerID) {
+			_, err = tx.Exec("UPDATE events SET status = 'published' WHERE uuid = ?", *trx.EventID)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return outcome, tx.Commit()
+}
+
+// ReconcilePendingPayments asks Tripay for the current state of transactions that have been
+// pending for a while and feeds the answer through the same ledger as callbacks.
+func ReconcilePendingPayments(db *sqlx.DB) (int, error) {
+	var pending []struct {
+		Reference       string `db:"reference"`
+		TripayReference string `db:"tripay_reference"`
+	}
+	err := db.Select(&pending, `
+		SELECT reference, tripay_reference
+		FROM payment_transactions
+		WHERE status = 'pending' AND tripay_reference IS NOT NULL
+		  AND created_at < NOW() - INTERVAL 30 MINUTE
+		ORDER BY created_at ASC
+		LIMIT 200
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	tripay := utils.NewTripayClient()
+	applied := 0
+	for _, p := range pending {
+		detail, err := tripay.GetTransactionDetail(p.TripayReference)
+		if err != nil {
+			log.Printf("[payment] reconcile detail failed reference=%s: %v", p.Reference, err)
+			continue
+		}
+
+		status, _ := detail["status"].(string)
+		payload, _ := json.Marshal(detail)
+		outcome, err := applyPaymentSignal(db, p.Reference, "reconcile", status, payload)
+		if err != nil {
+			log.Printf("[payment] reconcile apply failed reference=%s: %v", p.Reference, err)
+			continue
+		}
+		if outcome == "applied" {
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+// StartPaymentReconciler runs ReconcilePendingPayments on a fixed interval in the background
+func StartPaymentReconciler(db *sqlx.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			applied, err := ReconcilePendingPayments(db)
+			if err != nil {
+				log.Printf("[payment] reconcile run failed: %v", err)
+				continue
+			}
+			if applied > 0 {
+				log.Printf("[payment] reconcile applied %d status changes", applied)
+			}
+		}
+	}()
+}
+
+// TriggerPaymentReconciliation runs a reconciliation pass on demand (admin only)
+func TriggerPaymentReconciliation(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		applied, err := ReconcilePendingPayments(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile payments: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Reconciliation completed", "applied": applied})
+	}
+}
+
+// GetPaymentEvents returns the ledger entries of a transaction owned by the current user
+func GetPaymentEvents(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reference := c.Param("reference")
+		userID, _ := c.Get("user_id")
+		role, _ := c.Get("role")
+
+		var trx struct {
+			UUID   string `db:"uuid"`
+			UserID string `db:"user_id"`
+		}
+		err := db.Get(&trx, "SELECT uuid, user_id FROM payment_transactions WHERE reference = ?", reference)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		if trx.UserID != userID && role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this transaction"})
+			return
+		}
+
+		var events []models.PaymentEvent
+		err = db.Select(&events, `
+			SELECT uuid, transaction_id, source, tripay_status, from_status, to_status, outcome, payload_hash, payload, created_at
+			FROM payment_events
+			WHERE transaction_id = ?
+			ORDER BY created_at ASC
+		`, trx.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment events"})
+			return
+		}
+
+		if events == nil {
+			events = []models.PaymentEvent{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": events})
+	}
+}
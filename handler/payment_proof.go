@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	errProofNotPending     = errors.New("payment proof is not waiting for review")
+	errParticipantNotFound = errors.New("participant not found in this event")
+)
+
+// reviewPaymentProof applies an organizer decision to a participant's manual payment and
+// records it in payment_proof_reviews. It returns the archer to notify.
+func reviewPaymentProof(tx *sqlx.Tx, eventID, participantID, decision, reason, reviewerID string) (string, error) {
+	var p struct {
+		ArcherID       string   `db:"archer_id"`
+		PaymentStatus  string   `db:"payment_status"`
+		PaymentAmount  float64  `db:"payment_amount"`
+		DeclaredAmount *float64 `db:"declared_amount"`
+		ProofURLs      string   `db:"proof_urls"`
+	}
+	err := tx.Get(&p, `
+		SELECT archer_id, COALESCE(payment_status, '') as payment_status, COALESCE(payment_amount, 0) as payment_amount,
+			declared_amount, COALESCE(payment_proof_urls, '') as proof_urls
+		FROM event_participants
+		WHERE uuid = ? AND event_id = ?
+		FOR UPDATE
+	`, participantID, eventID)
+	if err == sql.ErrNoRows {
+		return "", errParticipantNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if p.PaymentStatus != "menunggu_acc" {
+		return "", errProofNotPending
+	}
+
+	if decision == "approved" {
+		_, err = tx.Exec(`
+			UPDATE event_participants
+			SET payment_status = 'lunas', status = 'Terdaftar', qr_raw = COALESCE(qr_raw, ?), updated_at = NOW()
+			WHERE uuid = ?
+		`, uuid.New().String(), participantID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE event_participants
+			SET payment_status = 'belum_lunas', status = 'Menunggu Acc', updated_at = NOW()
+			WHERE uuid = ?
+		`, participantID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := recordPaymentProofReview(tx, eventID, participantID, decision, reason, reviewerID, p.PaymentAmount, p.DeclaredAmount, p.ProofURLs); err != nil {
+		return "", err
+	}
+
+	return p.ArcherID, nil
+}
+
+// recordPaymentProofReview appends a decision to the payment proof audit trail
+func recordPaymentProofReview(q sqlx.Execer, eventID, participantID, decision, reason, reviewerID string, expected float64, declared *float64, proofURLs string) error {
+	var reasonPtr *string
+	if reason != "" {
+		reasonPtr = &reason
+	}
+	_, err := q.Exec(`
+		INSERT INTO payment_proof_reviews (uuid, event_id, participant_id, decision, reason, expected_amount, declared_amount, proof_urls, reviewed_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), eventID, participantID, decision, reasonPtr, expected, declared, proofURLs, reviewerID)
+	return err
+}
+
+// notifyPaymentProofDecision tells the archer what happened to their payment proof
+func notifyPaymentProofDecision(db *sqlx.DB, archerID, eventID, decision, reason string) {
+	var eventName string
+	db.Get(&eventName, "SELECT name FROM events WHERE uuid = ?", eventID)
+
+	if decision == "approved" {
+		utils.Notify(db, archerID, "archer", "success", "Pembayaran diterima",
+			fmt.Sprintf("Bukti pembayaran Anda untuk %s telah diverifikasi. Pendaftaran Anda sudah lunas.", eventName), "")
+		return
+	}
+	message := fmt.Sprintf("Bukti pembayaran Anda untuk %s ditolak", eventName)
+	if reason != "" {
+		message += ": " + reason
+	}
+	utils.Notify(db, archerID, "archer", "danger", "Bukti pembayaran ditolak",
+		message+". Silakan unggah ulang bukti pembayaran.", "")
+}
+
+// GetPaymentProofQueue lists participants of an event whose payment proof awaits verification
+func GetPaymentProofQueue(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var items []models.PaymentProofQueueItem
+		err := db.Select(&items, `
+			SELECT tp.uuid as participant_id, tp.archer_id, COALESCE(a.full_name, '') as archer_name,
+				COALESCE(tp.category_id, '') as category_id,
+				COALESCE(NULLIF(TRIM(CONCAT(COALESCE(rbt.name, ''), ' ', COALESCE(rag.name, ''))), ''), 'Unknown Category') as category_name,
+				COALESCE(tp.payment_amount, 0) as expected_amount, tp.declared_amount,
+				COALESCE(tp.payment_proof_urls, '') as proof_urls,
+				COALESCE(tp.updated_at, tp.registration_date) as submitted_at
+			FROM event_participants tp
+			LEFT JOIN archers a ON tp.archer_id = a.uuid
+			LEFT JOIN event_categories ec ON tp.category_id = ec.uuid
+			LEFT JOIN ref_bow_types rbt ON ec.division_uuid = rbt.uuid
+			LEFT JOIN ref_age_groups rag ON ec.category_uuid = rag.uuid
+			WHERE tp.event_id = ? AND tp.payment_status = 'menunggu_acc'
+			  AND tp.payment_proof_urls IS NOT NULL AND tp.payment_proof_urls != ''
+			ORDER BY submitted_at ASC
+		`, eventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment proof queue", "details": err.Error()})
+			return
+		}
+
+		if items == nil {
+			items = []models.PaymentProofQueueItem{}
+		}
+		for i := range items {
+			items[i].ProofURLs = strings.Split(items[i].ProofURLsRaw, ",")
+			items[i].AmountMatches = items[i].DeclaredAmount != nil && math.Abs(*items[i].DeclaredAmount-items[i].ExpectedAmount) < 0.01
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": items, "total": len(items)})
+	}
+}
+
+// ReviewPaymentProofs approves or rejects payment proofs. The participant comes from the
+// :participantId route param, or from participant_ids in the body for bulk review.
+func ReviewPaymentProofs(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req models.ReviewPaymentProofRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if id := c.Param("participantId"); id != "" {
+			req.ParticipantIDs = []string{id}
+		}
+		if len(req.ParticipantIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "participant_ids is required"})
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Decision == "rejected" && req.Reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required when rejecting a payment proof"})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		reviewerID := userID.(string)
+
+		// Each participant is reviewed in its own transaction so one stale entry
+		// doesn't block the rest of a bulk action
+		reviewed := []string{}
+		failed := []gin.H{}
+		for _, participantID := range req.ParticipantIDs {
+			before := rowSnapshot(db, "event_participants", participantID)
+			tx, err := db.Beginx()
+			if err != nil {
+				failed = append(failed, gin.H{"participant_id": participantID, "error": "Failed to start transaction"})
+				continue
+			}
+
+			archerID, err := reviewPaymentProof(tx, eventID, participantID, req.Decision, req.Reason, reviewerID)
+			if err == nil {
+				err = tx.Commit()
+			}
+			tx.Rollback()
+			if err != nil {
+				failed = append(failed, gin.H{"participant_id": participantID, "error": err.Error()})
+				continue
+			}
+
+			reviewed = append(reviewed, participantID)
+			notifyPaymentProofDecision(db, archerID, eventID, req.Decision, req.Reason)
+			logEventActivity(db, c, eventID, "payment_proof_"+req.Decision, "event_participant", participantID, "Payment proof "+req.Decision, before, rowSnapshot(db, "event_participants", participantID))
+		}
+
+		status := http.StatusOK
+		if len(reviewed) == 0 {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"message":  fmt.Sprintf("%d payment proof(s) %s", len(reviewed), req.Decision),
+			"reviewed": reviewed,
+			"failed":   failed,
+		})
+	}
+}
+
+// GetPaymentProofReviews returns the decision history of an event's payment proofs
+func GetPaymentProofReviews(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		query := `
+			SELECT uuid, event_id, participant_id, decision, reason, expected_amount, declared_amount, proof_urls, reviewed_by, created_at
+			FROM payment_proof_reviews
+			WHERE event_id = ?
+		`
+		args := []interface{}{eventID}
+		if participantID := c.Query("participant_id"); participantID != "" {
+			query += " AND participant_id = ?"
+			args = append(args, participantID)
+		}
+		query += " ORDER BY created_at DESC"
+
+		var reviews []models.PaymentProofReview
+		if err := db.Select(&reviews, query, args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment proof reviews"})
+			return
+		}
+
+		if reviews == nil {
+			reviews = []models.PaymentProofReview{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": reviews})
+	}
+}
+
+// SubmitPaymentProof lets an archer or their guardian (re)upload a manual transfer proof for a registration
+func SubmitPaymentProof(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		participantID := c.Param("participantId")
+		userID, _ := c.Get("user_id")
+
+		var req models.SubmitPaymentProofRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var p struct {
+			EventID       string  `db:"event_id"`
+			ArcherID      string  `db:"archer_id"`
+			PaymentStatus string  `db:"payment_status"`
+			ConsentStatus *string `db:"guardian_consent_status"`
+		}
+		err := db.Get(&p, `
+			SELECT event_id, archer_id, COALESCE(payment_status, '') as payment_status, guardian_consent_status
+			FROM event_participants WHERE uuid = ?
+		`, participantID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+			return
+		}
+
+		// The archer or their guardian pays, like with CreatePayment
+		var userArcherID string
+		err = db.Get(&userArcherID, "SELECT uuid FROM archers WHERE uuid = ? OR user_id = ?", userID, userID)
+		if (err != nil || userArcherID != p.ArcherID) && !isActiveGuardian(db, userID.(string), p.ArcherID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only submit payment proof for your own registration or your ward's"})
+			return
+		}
+		if p.ConsentStatus != nil && *p.ConsentStatus == "pending" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Registration is waiting for guardian consent"})
+			return
+		}
+		if p.PaymentStatus == "lunas" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Registration is already paid"})
+			return
+		}
+
+		before := rowSnapshot(db, "event_participants", participantID)
+		_, err = db.Exec(`
+			UPDATE event_participants
+			SET payment_proof_urls = ?, declared_amount = ?, payment_status = 'menunggu_acc', status = 'Menunggu Acc', updated_at = NOW()
+			WHERE uuid = ?
+		`, strings.Join(req.PaymentProofURLs, ","), req.DeclaredAmount, participantID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save payment proof"})
+			return
+		}
+
+		logEventActivity(db, c, p.EventID, "payment_proof_submitted", "event_participant", participantID, "Submitted payment proof", before, rowSnapshot(db, "event_participants", participantID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Payment proof submitted and waiting for verification"})
+	}
+}
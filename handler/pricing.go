@@ -0,0 +1,486 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var errPromoCodeInvalid = errors.New("promo code is invalid, expired or fully used")
+
+// discountAmount applies a percent or fixed discount to an amount
+func discountAmount(discountType string, value, amount float64) float64 {
+	if discountType == "percent" {
+		return math.Round(amount * value / 100)
+	}
+	return math.Min(value, amount)
+}
+
+// computeEntryPrice prices an entry for a category of an event at the given time: the event (or
+// category) fee, the early-bird price while it lasts, the archer's club discount and a promo code.
+// The promo code has to be valid at promoAt, which is when it's redeemed.
+func computeEntryPrice(db sqlx.Queryer, eventID, categoryID, archerID, promoCode string, at, promoAt time.Time) (models.PriceQuote, error) {
+	quote := models.PriceQuote{Lines: []models.PriceLine{}}
+
+	var eventFee float64
+	if err := sqlx.Get(db, &eventFee, "SELECT COALESCE(entry_fee, 0) FROM events WHERE uuid = ?", eventID); err != nil {
+		return quote, err
+	}
+
+	// A category-specific price wins over the event-wide price row, which wins over events.entry_fee
+	var price models.EventPrice
+	err := sqlx.Get(db, &price, `
+		SELECT uuid, event_id, category_id, entry_fee, early_bird_fee, early_bird_until
+		FROM event_prices
+		WHERE event_id = ? AND (category_id = ? OR category_id IS NULL)
+		ORDER BY category_id IS NULL ASC
+		LIMIT 1
+	`, eventID, categoryID)
+
+	quote.BaseFee = eventFee
+	if err == nil {
+		quote.BaseFee = price.EntryFee
+		if price.EarlyBirdFee != nil && price.EarlyBirdUntil != nil && !at.After(*price.EarlyBirdUntil) {
+			quote.BaseFee = *price.EarlyBirdFee
+			quote.EarlyBird = true
+		}
+	}
+	label := "Entry fee"
+	if quote.EarlyBird {
+		label = "Early-bird entry fee"
+	}
+	quote.Lines = append(quote.Lines, models.PriceLine{Label: label, Amount: quote.BaseFee})
+	remaining := quote.BaseFee
+
+	if archerID != "" {
+		var clubDiscount models.EventClubDiscount
+		err := sqlx.Get(db, &clubDiscount, `
+			SELECT d.uuid, d.event_id, d.club_id, d.discount_type, d.discount_value
+			FROM event_club_discounts d
+			JOIN archers a ON a.club_id = d.club_id
+			WHERE d.event_id = ? AND a.uuid = ?
+			LIMIT 1
+		`, eventID, archerID)
+		if err == nil {
+			amount := discountAmount(clubDiscount.DiscountType, clubDiscount.DiscountValue, remaining)
+			remaining -= amount
+			quote.DiscountAmount += amount
+			quote.Lines = append(quote.Lines, models.PriceLine{Label: "Club member discount", Amount: -amount})
+		}
+	}
+
+	if promoCode != "" {
+		promo, err := findPromoCode(db, eventID, categoryID, promoCode, promoAt)
+		if err != nil {
+			return quote, err
+		}
+		amount := discountAmount(promo.DiscountType, promo.DiscountValue, remaining)
+		remaining -= amount
+		quote.DiscountAmount += amount
+		quote.PromoCodeID = &promo.UUID
+		quote.Lines = append(quote.Lines, models.PriceLine{Label: "Promo " + promo.Code, Amount: -amount})
+	}
+
+	quote.Total = math.Max(0, remaining)
+	return quote, nil
+}
+
+// findPromoCode looks up a promo code and checks that it can be used for the category right now
+func findPromoCode(db sqlx.Queryer, eventID, categoryID, code string, at time.Time) (models.PromoCode, error) {
+	var promo models.PromoCode
+	err := sqlx.Get(db, &promo, `
+		SELECT uuid, event_id, code, discount_type, discount_value, max_uses, used_count,
+		       valid_from, valid_until, category_id, status, created_at, updated_at
+		FROM promo_codes
+		WHERE event_id = ? AND code = ?
+	`, eventID, strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		return promo, errPromoCodeInvalid
+	}
+
+	if promo.Status != "active" ||
+		(promo.ValidFrom != nil && at.Before(*promo.ValidFrom)) ||
+		(promo.ValidUntil != nil && at.After(*promo.ValidUntil)) ||
+		(promo.MaxUses != nil && promo.UsedCount >= *promo.MaxUses) ||
+		(promo.CategoryID != nil && *promo.CategoryID != categoryID) {
+		return promo, errPromoCodeInvalid
+	}
+
+	return promo, nil
+}
+
+// consumePromoCode increments the usage counter, refusing when the code has run out
+func consumePromoCode(tx *sqlx.Tx, promoCodeID string) error {
+	result, err := tx.Exec(`
+		UPDATE promo_codes SET used_count = used_count + 1, updated_at = NOW()
+		WHERE uuid = ? AND (max_uses IS NULL OR used_count < max_uses)
+	`, promoCodeID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errPromoCodeInvalid
+	}
+	return nil
+}
+
+// releasePromoCode gives back the use a participant's promo code took, when the registration is
+// cancelled or removed
+func releasePromoCode(q sqlx.Execer, participantID string) error {
+	_, err := q.Exec(`
+		UPDATE promo_codes p
+		JOIN event_participants ep ON ep.promo_code_id = p.uuid
+		SET p.used_count = GREATEST(p.used_count - 1, 0), p.updated_at = NOW()
+		WHERE ep.uuid = ?
+	`, participantID)
+	return err
+}
+
+// GetEntryPriceQuote previews the entry price of a category, including discounts
+func GetEntryPriceQuote(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := c.Param("id")
+		categoryID := c.Query("category_id")
+		if categoryID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "category_id is required"})
+			return
+		}
+
+		var actualEventID string
+		err := db.Get(&actualEventID, `SELECT uuid FROM events WHERE uuid = ? OR slug = ?`, eventID, eventID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+
+		archerID := c.Query("archer_id")
+		if archerID == "" {
+			if userType, _ := c.Get("user_type"); userType == "archer" {
+				userID, _ := c.Get("user_id")
+				archerID = fmt.Sprintf("%v", userID)
+			}
+		}
+
+		now := time.Now()
+		quote, err := computeEntryPrice(db, actualEventID, categoryID, archerID, c.Query("promo_code"), now, now)
+		if err == errPromoCodeInvalid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute price"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": quote})
+	}
+}
+
+// GetEventPricing returns the price table and club discounts of an event
+func GetEventPricing(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		prices := []models.EventPrice{}
+		db.Select(&prices, `
+			SELECT uuid, event_id, category_id, entry_fee, early_bird_fee, early_bird_until
+			FROM event_prices WHERE event_id = ?
+		`, actualEventID)
+
+		discounts := []models.EventClubDiscount{}
+		db.Select(&discounts, `
+			SELECT uuid, event_id, club_id, discount_type, discount_value
+			FROM event_club_discounts WHERE event_id = ?
+		`, actualEventID)
+
+		c.JSON(http.StatusOK, gin.H{"prices": prices, "club_discounts": discounts})
+	}
+}
+
+// pricingSnapshot reads the price table and club discounts of an event for the activity log
+func pricingSnapshot(db *sqlx.DB, eventID string) map[string]interface{} {
+	prices, _ := exportRows(db, `
+		SELECT category_id, entry_fee, early_bird_fee, early_bird_until
+		FROM event_prices WHERE event_id = ? ORDER BY category_id
+	`, eventID)
+	discounts, _ := exportRows(db, `
+		SELECT club_id, discount_type, discount_value
+		FROM event_club_discounts WHERE event_id = ? ORDER BY club_id
+	`, eventID)
+	return map[string]interface{}{"prices": prices, "club_discounts": discounts}
+}
+
+// UpdateEventPricing replaces the price table and club discounts of an event
+func UpdateEventPricing(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req models.EventPricingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		before := pricingSnapshot(db, actualEventID)
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("DELETE FROM event_prices WHERE event_id = ?", actualEventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear prices", "details": err.Error()})
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM event_club_discounts WHERE event_id = ?", actualEventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear club discounts", "details": err.Error()})
+			return
+		}
+
+		for _, p := range req.Prices {
+			_, err := tx.Exec(`
+				INSERT INTO event_prices (uuid, event_id, category_id, entry_fee, early_bird_fee, early_bird_until)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, uuid.New().String(), actualEventID, p.CategoryID, p.EntryFee, p.EarlyBirdFee, p.EarlyBirdUntil)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save prices", "details": err.Error()})
+				return
+			}
+		}
+
+		for _, d := range req.ClubDiscounts {
+			_, err := tx.Exec(`
+				INSERT INTO event_club_discounts (uuid, event_id, club_id, discount_type, discount_value)
+				VALUES (?, ?, ?, ?, ?)
+			`, uuid.New().String(), actualEventID, d.ClubID, d.DiscountType, d.DiscountValue)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save club discounts", "details": err.Error()})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit pricing"})
+			return
+		}
+
+		logEventActivity(db, c, actualEventID, "pricing_updated", "event", actualEventID, "Updated entry pricing", before, pricingSnapshot(db, actualEventID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Pricing updated successfully"})
+	}
+}
+
+// GetPromoCodes lists the promo codes of an event
+func GetPromoCodes(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var codes []models.PromoCode
+		err := db.Select(&codes, `
+			SELECT uuid, event_id, code, discount_type, discount_value, max_uses, used_count,
+			       valid_from, valid_until, category_id, status, created_at, updated_at
+			FROM promo_codes
+			WHERE event_id = ?
+			ORDER BY created_at DESC
+		`, actualEventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promo codes"})
+			return
+		}
+
+		if codes == nil {
+			codes = []models.PromoCode{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": codes})
+	}
+}
+
+// CreatePromoCode creates a promo code for an event
+func CreatePromoCode(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req models.PromoCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.DiscountType == "percent" && req.DiscountValue > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Percentage discount cannot exceed 100"})
+			return
+		}
+		if req.Status == "" {
+			req.Status = "active"
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(req.Code))
+		var exists bool
+		db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM promo_codes WHERE event_id = ? AND code = ?)", actualEventID, code)
+		if exists {
+			c.JSON(http.StatusConflict, gin.H{"error": "Promo code already exists for this event"})
+			return
+		}
+
+		promoID := uuid.New().String()
+		_, err := db.Exec(`
+			INSERT INTO promo_codes (uuid, event_id, code, discount_type, discount_value, max_uses, valid_from, valid_until, category_id, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, promoID, actualEventID, code, req.DiscountType, req.DiscountValue, req.MaxUses, req.ValidFrom, req.ValidUntil, req.CategoryID, req.Status)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promo code", "details": err.Error()})
+			return
+		}
+
+		logEventActivity(db, c, actualEventID, "promo_code_created", "promo_code", promoID, "Created promo code "+code, nil, rowSnapshot(db, "promo_codes", promoID))
+
+		c.JSON(http.StatusCreated, gin.H{"id": promoID, "message": "Promo code created successfully"})
+	}
+}
+
+// UpdatePromoCode updates a promo code of an event
+func UpdatePromoCode(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req models.PromoCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.DiscountType == "percent" && req.DiscountValue > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Percentage discount cannot exceed 100"})
+			return
+		}
+		if req.Status == "" {
+			req.Status = "active"
+		}
+
+		codeID := c.Param("codeId")
+		before := rowSnapshot(db, "promo_codes", codeID)
+		result, err := db.Exec(`
+			UPDATE promo_codes
+			SET code = ?, discount_type = ?, discount_value = ?, max_uses = ?, valid_from = ?, valid_until = ?,
+			    category_id = ?, status = ?, updated_at = NOW()
+			WHERE uuid = ? AND event_id = ?
+		`, strings.ToUpper(strings.TrimSpace(req.Code)), req.DiscountType, req.DiscountValue, req.MaxUses, req.ValidFrom, req.ValidUntil,
+			req.CategoryID, req.Status, codeID, actualEventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promo code"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+			return
+		}
+
+		logEventActivity(db, c, actualEventID, "promo_code_updated", "promo_code", codeID, "Updated promo code "+strings.ToUpper(strings.TrimSpace(req.Code)), before, rowSnapshot(db, "promo_codes", codeID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Promo code updated successfully"})
+	}
+}
+
+// DeletePromoCode deletes a promo code of an event
+func DeletePromoCode(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		codeID := c.Param("codeId")
+		before := rowSnapshot(db, "promo_codes", codeID)
+		result, err := db.Exec("DELETE FROM promo_codes WHERE uuid = ? AND event_id = ?", codeID, actualEventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete promo code"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows > 0 {
+			logEventActivity(db, c, actualEventID, "promo_code_deleted", "promo_code", codeID, "Deleted promo code", before, nil)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Promo code deleted successfully"})
+	}
+}
+
+// priceParticipantForPayment settles the amount due for a participant at payment time. The price is
+// taken at the registration date so an early-bird price is kept; a promo code may still be added
+// if none was used at registration, as long as it's valid now. Credit notes already redeemed are
+// deducted.
+func priceParticipantForPayment(tx *sqlx.Tx, participantID, promoCode string) (float64, error) {
+	var p struct {
+		EventID          string    `db:"event_id"`
+		ArcherID         string    `db:"archer_id"`
+		CategoryID       string    `db:"category_id"`
+		RegistrationDate time.Time `db:"registration_date"`
+		PaymentAmount    float64   `db:"payment_amount"`
+		BaseFee          *float64  `db:"base_fee"`
+		PromoCodeID      *string   `db:"promo_code_id"`
+	}
+	err := tx.Get(&p, `
+		SELECT event_id, archer_id, category_id, registration_date, COALESCE(payment_amount, 0) as payment_amount, base_fee, promo_code_id
+		FROM event_participants WHERE uuid = ?
+		FOR UPDATE
+	`, participantID)
+	if err != nil {
+		return 0, err
+	}
+
+	addPromo := promoCode != "" && p.PromoCodeID == nil
+	if p.BaseFee != nil && !addPromo {
+		return p.PaymentAmount, nil
+	}
+	if !addPromo {
+		promoCode = ""
+	}
+
+	quote, err := computeEntryPrice(tx, p.EventID, p.CategoryID, p.ArcherID, promoCode, p.RegistrationDate, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if quote.PromoCodeID != nil {
+		if err := consumePromoCode(tx, *quote.PromoCodeID); err != nil {
+			return 0, err
+		}
+	}
+
+	var credit float64
+	tx.Get(&credit, "SELECT COALESCE(SUM(amount), 0) FROM credit_note_redemptions WHERE participant_id = ?", participantID)
+	amount := math.Max(0, quote.Total-credit)
+
+	_, err = tx.Exec(`
+		UPDATE event_participants
+		SET payment_amount = ?, base_fee = ?, discount_amount = ?, promo_code_id = ?, pricing_breakdown = ?
+		WHERE uuid = ?
+	`, amount, quote.BaseFee, quote.DiscountAmount, quote.PromoCodeID, models.ToJSON(quote.Lines), participantID)
+	if err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
@@ -43,6 +43,7 @@ func GetQualificationSessions(db *sqlx.DB) gin.HandlerFunc {
 			EndTime          *string `db:"end_time" json:"end_time"`
 			TotalEnds        int     `db:"total_ends" json:"total_ends"`
 			ArrowsPerEnd     int     `db:"arrows_per_end" json:"arrows_per_end"`
+			Distance         *int    `db:"distance" json:"distance"`
 			CreatedAt        *string `db:"created_at" json:"created_at"`
 			UpdatedAt        *string `db:"updated_at" json:"updated_at"`
 			ParticipantCount int     `db:"participant_count" json:"participant_count"`
@@ -60,13 +61,14 @@ func GetQualificationSessions(db *sqlx.DB) gin.HandlerFunc {
 				qs.end_time,
 				qs.total_ends,
 				qs.arrows_per_end,
+				qs.distance,
 				qs.created_at,
 				qs.updated_at,
 				COUNT(DISTINCT qta.participant_uuid) as participant_count
 			FROM qualification_sessions qs
 			LEFT JOIN qualification_target_assignments qta ON qs.uuid = qta.session_uuid
 			WHERE qs.event_uuid = ?
-			GROUP BY qs.uuid, qs.session_date, qs.name, qs.start_time, qs.end_time, qs.total_ends, qs.arrows_per_end, qs.created_at, qs.updated_at
+			GROUP BY qs.uuid, qs.session_date, qs.name, qs.start_time, qs.end_time, qs.total_ends, qs.arrows_per_end, qs.distance, qs.created_at, qs.updated_at
 			ORDER BY qs.session_date ASC, qs.start_time ASC, qs.created_at ASC
 		`, eventUUID)
 		if err != nil {
@@ -98,6 +100,7 @@ func CreateQualificationSession(db *sqlx.DB) gin.HandlerFunc {
 			EndTime      *string `json:"end_time"`
 			TotalEnds    int     `json:"total_ends"`
 			ArrowsPerEnd int     `json:"arrows_per_end"`
+			Distance     *int    `json:"distance"` // meters, for personal bests per distance
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -136,14 +139,16 @@ func CreateQualificationSession(db *sqlx.DB) gin.HandlerFunc {
 
 		newUUID := uuid.New().String()
 		_, err = db.Exec(`
-			INSERT INTO qualification_sessions (uuid, event_uuid, session_code, session_date, name, start_time, end_time, total_ends, arrows_per_end)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			newUUID, eventUUID, sessionCode, req.SessionDate, req.Name, finalStartTime, finalEndTime, req.TotalEnds, req.ArrowsPerEnd)
+			INSERT INTO qualification_sessions (uuid, event_uuid, session_code, session_date, name, start_time, end_time, total_ends, arrows_per_end, distance)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			newUUID, eventUUID, sessionCode, req.SessionDate, req.Name, finalStartTime, finalEndTime, req.TotalEnds, req.ArrowsPerEnd, req.Distance)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session", "details": err.Error()})
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "session_created", "qualification_session", newUUID, "Created session "+req.Name, nil, rowSnapshot(db, "qualification_sessions", newUUID))
+
 		c.JSON(http.StatusOK, gin.H{
 			"message":      "Session created successfully",
 			"session_uuid": newUUID,
@@ -164,6 +169,7 @@ func UpdateQualificationSession(db *sqlx.DB) gin.HandlerFunc {
 			EndTime      *string `json:"end_time"`
 			TotalEnds    int     `json:"total_ends"`
 			ArrowsPerEnd int     `json:"arrows_per_end"`
+			Distance     *int    `json:"distance"` // meters, for personal bests per distance
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -187,16 +193,21 @@ func UpdateQualificationSession(db *sqlx.DB) gin.HandlerFunc {
 			finalEndTime = req.EndTime
 		}
 
+		before := rowSnapshot(db, "qualification_sessions", sessionUUID)
 		_, err := db.Exec(`
 			UPDATE qualification_sessions 
-			SET name = ?, session_date = ?, start_time = ?, end_time = ?, total_ends = ?, arrows_per_end = ?, updated_at = NOW()
+			SET name = ?, session_date = ?, start_time = ?, end_time = ?, total_ends = ?, arrows_per_end = ?, distance = ?, updated_at = NOW()
 			WHERE uuid = ?`,
-			req.Name, req.SessionDate, finalStartTime, finalEndTime, req.TotalEnds, req.ArrowsPerEnd, sessionUUID)
+			req.Name, req.SessionDate, finalStartTime, finalEndTime, req.TotalEnds, req.ArrowsPerEnd, req.Distance, sessionUUID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session", "details": err.Error()})
 			return
 		}
 
+		after := rowSnapshot(db, "qualification_sessions", sessionUUID)
+		eventUUID, _ := after["event_uuid"].(string)
+		logEventActivity(db, c, eventUUID, "session_updated", "qualification_session", sessionUUID, "Updated session "+req.Name, before, after)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Session updated successfully"})
 	}
 }
@@ -205,6 +216,7 @@ func UpdateQualificationSession(db *sqlx.DB) gin.HandlerFunc {
 func DeleteQualificationSession(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		sessionUUID := c.Param("sessionId")
+		before := rowSnapshot(db, "qualification_sessions", sessionUUID)
 
 		tx, err := db.Beginx()
 		if err != nil {
@@ -249,10 +261,56 @@ func DeleteQualificationSession(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		if eventUUID, ok := before["event_uuid"].(string); ok {
+			logEventActivity(db, c, eventUUID, "session_deleted", "qualification_session", sessionUUID, "Deleted session", before, nil)
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Session deleted successfully"})
 	}
 }
 
+// endScoresSnapshot reads a participant's ends in a session for the activity log, keyed by end
+// number so that only the ends that changed are kept in a before/after diff
+func endScoresSnapshot(db *sqlx.DB, sessionUUID, participantUUID string) map[string]interface{} {
+	rows, _ := exportRows(db, `
+		SELECT e.end_number, e.total_score_end,
+		       GROUP_CONCAT(IF(a.is_x = 1, 'X', a.score) ORDER BY a.arrow_number) as arrows
+		FROM qualification_end_scores e
+		LEFT JOIN qualification_arrow_scores a ON a.end_score_uuid = e.uuid
+		WHERE e.session_uuid = ? AND e.participant_uuid = ?
+		GROUP BY e.uuid, e.end_number, e.total_score_end
+	`, sessionUUID, participantUUID)
+	ends := map[string]interface{}{}
+	for _, r := range rows {
+		ends[fmt.Sprintf("end_%v", r["end_number"])] = map[string]interface{}{"total": r["total_score_end"], "arrows": r["arrows"]}
+	}
+	return ends
+}
+
+// assignmentsSnapshot reads the target of every participant in a session for the activity log
+func assignmentsSnapshot(db *sqlx.DB, sessionUUID string) map[string]interface{} {
+	rows, _ := exportRows(db, `
+		SELECT qta.participant_uuid, et.target_name
+		FROM qualification_target_assignments qta
+		LEFT JOIN event_targets et ON qta.target_uuid = et.uuid
+		WHERE qta.session_uuid = ?
+	`, sessionUUID)
+	targets := map[string]interface{}{}
+	for _, r := range rows {
+		if id, ok := r["participant_uuid"].(string); ok {
+			targets[id] = r["target_name"]
+		}
+	}
+	return targets
+}
+
+// sessionEventUUID is the event a qualification session belongs to
+func sessionEventUUID(db *sqlx.DB, sessionUUID string) string {
+	var eventUUID string
+	db.Get(&eventUUID, `SELECT event_uuid FROM qualification_sessions WHERE uuid = ?`, sessionUUID)
+	return eventUUID
+}
+
 // UpdateQualificationScore updates end scores for an assignment (supports batch)
 func UpdateQualificationScore(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -268,28 +326,18 @@ func UpdateQualificationScore(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		var raw map[string]interface{}
-		if err := c.ShouldBindJSON(&raw); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var eventUUID string
+		db.Get(&eventUUID, `SELECT event_uuid FROM qualification_sessions WHERE uuid = ?`, sessionUUID)
+		if !canScoreEvent(db, c, eventUUID) {
 			return
 		}
 
-		var ends []models.SingleEndScore
-		if _, exists := raw["ends"]; exists {
-			data, _ := json.Marshal(raw)
-			var batchReq models.ScoreBatchUpdateRequest
-			json.Unmarshal(data, &batchReq)
-			ends = batchReq.Ends
-		} else if _, exists := raw["end_number"]; exists {
-			data, _ := json.Marshal(raw)
-			var singleReq models.ScoreUpdateRequest
-			json.Unmarshal(data, &singleReq)
-			ends = []models.SingleEndScore{{EndNumber: singleReq.EndNumber, Arrows: singleReq.Arrows}}
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: 'ends' or 'end_number' required"})
+		ends, ok := bindEndScores(c)
+		if !ok {
 			return
 		}
 
+		before := endScoresSnapshot(db, sessionUUID, participantUUID)
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -353,6 +401,10 @@ func UpdateQualificationScore(db *sqlx.DB) gin.HandlerFunc {
 			allEndScoreUUIDs = append(allEndScoreUUIDs, currentEndScoreUUID)
 
 			for i, arrow := range end.Arrows {
+				// A blank arrow hasn't been shot yet; only scored arrows (misses included) are stored
+				if arrow == "" {
+					continue
+				}
 				val, _, _ := calculateArrowValue(arrow)
 				isX := 0
 				if arrow == "X" {
@@ -399,7 +451,19 @@ func UpdateQualificationScore(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Scores updated successfully"})
+		logEventActivity(db, c, eventUUID, "score_updated", "target_assignment", assignmentID,
+			fmt.Sprintf("Updated %d ends", len(ends)), before, endScoresSnapshot(db, sessionUUID, participantUUID))
+
+		// Award badges once the participant has finished the session
+		response := gin.H{"message": "Scores updated successfully"}
+		awarded, err := evaluateSessionAchievements(db, sessionUUID, participantUUID)
+		if err != nil {
+			fmt.Printf("[UpdateQualificationScore] Failed to evaluate achievements for %s: %v\n", participantUUID, err)
+		} else if len(awarded) > 0 {
+			response["achievements"] = awarded
+		}
+
+		c.JSON(http.StatusOK, response)
 	}
 }
 
@@ -472,6 +536,31 @@ func GetQualificationAssignmentScores(db *sqlx.DB) gin.HandlerFunc {
 	}
 }
 
+// bindEndScores reads end scores from a batch ({"ends": [...]}) or single-end
+// ({"end_number": n, "arrows": [...]}) request body, writing 400 when neither is present
+func bindEndScores(c *gin.Context) ([]models.SingleEndScore, bool) {
+	var raw map[string]interface{}
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	data, _ := json.Marshal(raw)
+	if _, exists := raw["ends"]; exists {
+		var batchReq models.ScoreBatchUpdateRequest
+		json.Unmarshal(data, &batchReq)
+		return batchReq.Ends, true
+	}
+	if _, exists := raw["end_number"]; exists {
+		var singleReq models.ScoreUpdateRequest
+		json.Unmarshal(data, &singleReq)
+		return []models.SingleEndScore{{EndNumber: singleReq.EndNumber, Arrows: singleReq.Arrows}}, true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: 'ends' or 'end_number' required"})
+	return nil, false
+}
+
 func calculateArrowValue(arrow string) (val int, x int, ten int) {
 	switch arrow {
 	case "X":
@@ -948,6 +1037,7 @@ func AutoAssignParticipants(db *sqlx.DB) gin.HandlerFunc {
 		})
 
 		// 5. Assign in order: slot order is already target-full-first (1A..1D, 2A..2D, ...)
+		before := assignmentsSnapshot(db, sessionID)
 		assignedCount := 0
 		for i, archer := range participants {
 			if i >= len(availableSlots) {
@@ -966,6 +1056,9 @@ func AutoAssignParticipants(db *sqlx.DB) gin.HandlerFunc {
 			assignedCount++
 		}
 
+		logEventActivity(db, c, eventUUID, "targets_auto_assigned", "qualification_session", sessionID,
+			fmt.Sprintf("Auto-assigned %d participants", assignedCount), before, assignmentsSnapshot(db, sessionID))
+
 		c.JSON(http.StatusOK, gin.H{"message": "Participants assigned successfully", "count": assignedCount})
 	}
 }
@@ -989,6 +1082,16 @@ func DeleteQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Removing an assignment drops its scores, so it needs the same rights as editing them
+		if !canScoreEvent(db, c, sessionEventUUID(db, sessionUUID)) {
+			return
+		}
+
+		before := rowSnapshot(db, "qualification_target_assignments", assignmentID)
+		if before != nil {
+			before["ends"] = endScoresSnapshot(db, sessionUUID, participantUUID)
+		}
+
 		// First delete all related arrow scores
 		_, err = db.Exec(`
 			DELETE FROM qualification_arrow_scores 
@@ -1022,6 +1125,9 @@ func DeleteQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, sessionEventUUID(db, sessionUUID), "target_assignment_deleted", "target_assignment", assignmentID,
+			"Removed participant from target and cleared their scores", before, nil)
+
 		c.JSON(http.StatusOK, gin.H{"message": "Assignment deleted successfully"})
 	}
 }
@@ -1057,6 +1163,7 @@ func CreateBulkTargetAssignments(db *sqlx.DB) gin.HandlerFunc {
 		}
 
 		fmt.Printf("[DEBUG] CreateBulkTargetAssignments - Attempting to assign for CategoryID: %s\n", req.CategoryID)
+		before := assignmentsSnapshot(db, sessionID)
 
 		// Validate session belongs to event
 		var sessionUUID string
@@ -1159,6 +1266,9 @@ func CreateBulkTargetAssignments(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "targets_assigned", "qualification_session", sessionID,
+			fmt.Sprintf("Assigned %d participants to targets", successCount), before, assignmentsSnapshot(db, sessionID))
+
 		c.JSON(http.StatusOK, gin.H{
 			"message":       "Assignments created successfully",
 			"success_count": successCount,
@@ -1181,7 +1291,12 @@ func ResetSessionAssignments(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		if !canScoreEvent(db, c, sessionEventUUID(db, sessionID)) {
+			return
+		}
+
 		categoryID := req.CategoryID
+		before := assignmentsSnapshot(db, sessionID)
 
 		tx, err := db.Beginx()
 		if err != nil {
@@ -1231,6 +1346,9 @@ func ResetSessionAssignments(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, sessionEventUUID(db, sessionID), "targets_reset", "qualification_session", sessionID,
+			"Reset assignments and scores for category "+categoryID, before, assignmentsSnapshot(db, sessionID))
+
 		c.JSON(http.StatusOK, gin.H{"message": "Assignments reset successfully"})
 	}
 }
@@ -1250,6 +1368,7 @@ func SwapTargetAssignments(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := assignmentsSnapshot(db, sessionID)
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -1301,6 +1420,9 @@ func SwapTargetAssignments(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, sessionEventUUID(db, sessionID), "targets_swapped", "qualification_session", sessionID,
+			"Swapped targets of two participants", before, assignmentsSnapshot(db, sessionID))
+
 		c.JSON(http.StatusOK, gin.H{"message": "Targets swapped successfully"})
 	}
 }
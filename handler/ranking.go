@@ -0,0 +1,548 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// rankingTierWeights multiplies the placing points of an event by its tier
+var rankingTierWeights = map[string]float64{
+	"club":     1,
+	"regional": 2,
+	"national": 4,
+}
+
+const (
+	// rankingWindowMonths is how long a result keeps counting towards the ranking
+	rankingWindowMonths = 12
+	// rankingBestResults is how many of an archer's results within the window count
+	rankingBestResults = 4
+)
+
+// isRankingTier reports whether tier is a valid event ranking tier
+func isRankingTier(tier string) bool {
+	_, ok := rankingTierWeights[tier]
+	return ok
+}
+
+// rankingPlacePoints returns the base points for a final placing, before the tier weight
+func rankingPlacePoints(place int) float64 {
+	switch {
+	case place == 1:
+		return 100
+	case place == 2:
+		return 80
+	case place == 3:
+		return 65
+	case place == 4:
+		return 55
+	case place <= 8:
+		return 40
+	case place <= 16:
+		return 25
+	case place <= 32:
+		return 12
+	default:
+		return 5
+	}
+}
+
+// refreshEventRankingResults replaces the ranking results of an event from its final placings.
+// Only completed events earn points, and only when they have a tier and a date.
+func refreshEventRankingResults(tx *sqlx.Tx, eventID string) (int, error) {
+	if _, err := tx.Exec("DELETE FROM national_ranking_results WHERE event_id = ?", eventID); err != nil {
+		return 0, err
+	}
+
+	var event struct {
+		Status    string     `db:"status"`
+		Tier      *string    `db:"ranking_tier"`
+		EventDate *time.Time `db:"event_date"`
+	}
+	err := tx.Get(&event, "SELECT status, ranking_tier, COALESCE(end_date, start_date) as event_date FROM events WHERE uuid = ?", eventID)
+	if err != nil {
+		return 0, err
+	}
+	if event.Status != "completed" || event.Tier == nil || !isRankingTier(*event.Tier) || event.EventDate == nil {
+		return 0, nil
+	}
+	weight := rankingTierWeights[*event.Tier]
+
+	var categories []struct {
+		UUID             string  `db:"uuid"`
+		BowTypeID        string  `db:"division_uuid"`
+		AgeGroupID       string  `db:"category_uuid"`
+		GenderDivisionID *string `db:"gender_division_uuid"`
+	}
+	err = tx.Select(&categories, "SELECT uuid, division_uuid, category_uuid, gender_division_uuid FROM event_categories WHERE event_id = ?", eventID)
+	if err != nil {
+		return 0, err
+	}
+
+	inserted := 0
+	for _, cat := range categories {
+		placings, err := categoryFinalPlacings(tx, cat.UUID)
+		if err != nil {
+			return inserted, err
+		}
+		for _, p := range placings {
+			_, err := tx.Exec(`
+				INSERT INTO national_ranking_results (uuid, archer_id, event_id, category_id, bow_type_id, gender_division_id, age_group_id, tier, place, points, event_date)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, uuid.New().String(), p.ArcherID, eventID, cat.UUID, cat.BowTypeID, cat.GenderDivisionID, cat.AgeGroupID,
+				*event.Tier, p.Place, rankingPlacePoints(p.Place)*weight, *event.EventDate)
+			if err != nil {
+				return inserted, err
+			}
+			inserted++
+		}
+	}
+	return inserted, nil
+}
+
+// recomputeRankingResults rebuilds ranking results for events changed since the given time, so an
+// event completed or corrected long after it ended is picked up too, as is one that lost its tier.
+// A zero time rebuilds the whole history.
+func recomputeRankingResults(db *sqlx.DB, since time.Time) (int, int, error) {
+	query := "SELECT uuid FROM events WHERE ranking_tier IS NOT NULL"
+	args := []interface{}{}
+	if !since.IsZero() {
+		query = `
+			SELECT uuid FROM events
+			WHERE updated_at >= ?
+			  AND (ranking_tier IS NOT NULL OR uuid IN (SELECT event_id FROM national_ranking_results))
+		`
+		args = append(args, since)
+	}
+
+	var eventIDs []string
+	if err := db.Select(&eventIDs, query, args...); err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	if since.IsZero() {
+		// Drop results of events that have since lost their tier or are no longer completed
+		_, err = tx.Exec(`
+			DELETE FROM national_ranking_results
+			WHERE event_id NOT IN (SELECT uuid FROM events WHERE ranking_tier IS NOT NULL AND status = 'completed')
+		`)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	results := 0
+	for _, eventID := range eventIDs {
+		n, err := refreshEventRankingResults(tx, eventID)
+		if err != nil {
+			return 0, 0, err
+		}
+		results += n
+	}
+
+	return len(eventIDs), results, tx.Commit()
+}
+
+// rankingFilter narrows a ranking list to one bow type, gender division and/or age group
+type rankingFilter struct {
+	BowTypeID        string
+	GenderDivisionID string
+	AgeGroupID       string
+}
+
+// bindRankingFilter resolves the ?bow_type=, ?gender_division= and ?age_group= filters, which
+// accept either reference uuids or codes
+func bindRankingFilter(db *sqlx.DB, c *gin.Context) rankingFilter {
+	resolve := func(table, value string) string {
+		if value == "" {
+			return ""
+		}
+		var id string
+		if err := db.Get(&id, "SELECT uuid FROM "+table+" WHERE uuid = ? OR code = ? LIMIT 1", value, value); err != nil {
+			return value
+		}
+		return id
+	}
+	return rankingFilter{
+		BowTypeID:        resolve("ref_bow_types", c.Query("bow_type")),
+		GenderDivisionID: resolve("ref_gender_divisions", c.Query("gender_division")),
+		AgeGroupID:       resolve("ref_age_groups", c.Query("age_group")),
+	}
+}
+
+// where returns the SQL conditions and arguments of the filter for the given table alias
+func (f rankingFilter) where(alias string) (string, []interface{}) {
+	cond := ""
+	args := []interface{}{}
+	if f.BowTypeID != "" {
+		cond += " AND " + alias + ".bow_type_id = ?"
+		args = append(args, f.BowTypeID)
+	}
+	if f.GenderDivisionID != "" {
+		cond += " AND " + alias + ".gender_division_id = ?"
+		args = append(args, f.GenderDivisionID)
+	}
+	if f.AgeGroupID != "" {
+		cond += " AND " + alias + ".age_group_id = ?"
+		args = append(args, f.AgeGroupID)
+	}
+	return cond, args
+}
+
+// computeRankings ranks archers as of the given time on the sum of their best results within
+// the window, separately for each bow type, gender division and age group
+func computeRankings(db sqlx.Queryer, asOf time.Time, filter rankingFilter) ([]models.RankingEntry, error) {
+	cond, filterArgs := filter.where("p")
+	args := []interface{}{asOf.AddDate(0, -rankingWindowMonths, 0), asOf}
+	args = append(args, filterArgs...)
+	args = append(args, rankingBestResults)
+
+	var entries []models.RankingEntry
+	err := sqlx.Select(db, &entries, `
+		SELECT ranked.*, a.full_name as archer_name, a.avatar_url, cl.name as club_name
+		FROM (
+			SELECT bow_type_id, gender_division_id, age_group_id, archer_id,
+				SUM(points) as points, COUNT(*) as results_counted,
+				RANK() OVER (PARTITION BY bow_type_id, gender_division_id, age_group_id ORDER BY SUM(points) DESC) as rank_position
+			FROM (
+				SELECT p.*,
+					ROW_NUMBER() OVER (PARTITION BY p.bow_type_id, p.gender_division_id, p.age_group_id, p.archer_id ORDER BY p.points DESC, p.event_date DESC) as result_no
+				FROM national_ranking_results p
+				WHERE p.event_date > ? AND p.event_date <= ?`+cond+`
+			) best
+			WHERE result_no <= ?
+			GROUP BY bow_type_id, gender_division_id, age_group_id, archer_id
+		) ranked
+		JOIN archers a ON ranked.archer_id = a.uuid
+		LEFT JOIN clubs cl ON a.club_id = cl.uuid
+		ORDER BY ranked.bow_type_id, ranked.gender_division_id, ranked.age_group_id, ranked.rank_position, a.full_name
+	`, args...)
+	return entries, err
+}
+
+// applyRankingMovement fills each entry's previous rank from the latest snapshot taken before
+// the given month
+func applyRankingMovement(db *sqlx.DB, entries []models.RankingEntry, before time.Time) {
+	var month time.Time
+	if err := db.Get(&month, "SELECT MAX(snapshot_month) FROM national_ranking_snapshots WHERE snapshot_month < ?", before); err != nil {
+		return
+	}
+
+	var previous []struct {
+		ArcherID         string  `db:"archer_id"`
+		BowTypeID        string  `db:"bow_type_id"`
+		GenderDivisionID *string `db:"gender_division_id"`
+		AgeGroupID       string  `db:"age_group_id"`
+		Rank             int     `db:"rank_position"`
+	}
+	db.Select(&previous, `
+		SELECT archer_id, bow_type_id, gender_division_id, age_group_id, rank_position
+		FROM national_ranking_snapshots WHERE snapshot_month = ?
+	`, month)
+
+	key := func(archerID, bowTypeID string, genderDivisionID *string, ageGroupID string) string {
+		gender := ""
+		if genderDivisionID != nil {
+			gender = *genderDivisionID
+		}
+		return archerID + "|" + bowTypeID + "|" + gender + "|" + ageGroupID
+	}
+	ranks := make(map[string]int, len(previous))
+	for _, p := range previous {
+		ranks[key(p.ArcherID, p.BowTypeID, p.GenderDivisionID, p.AgeGroupID)] = p.Rank
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		if rank, ok := ranks[key(e.ArcherID, e.BowTypeID, e.GenderDivisionID, e.AgeGroupID)]; ok {
+			movement := rank - e.Rank
+			e.PreviousRank = &rank
+			e.Movement = &movement
+		}
+	}
+}
+
+// takeRankingSnapshot stores the ranking as of the start of the month, replacing any earlier
+// snapshot for that month
+func takeRankingSnapshot(db *sqlx.DB, month time.Time) (int, error) {
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+
+	entries, err := computeRankings(db, month, rankingFilter{})
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM national_ranking_snapshots WHERE snapshot_month = ?", month); err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		_, err := tx.Exec(`
+			INSERT INTO national_ranking_snapshots (uuid, snapshot_month, archer_id, bow_type_id, gender_division_id, age_group_id, rank_position, points, results_counted)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), month, e.ArcherID, e.BowTypeID, e.GenderDivisionID, e.AgeGroupID, e.Rank, e.Points, e.ResultsCounted)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(entries), tx.Commit()
+}
+
+// RunRankingCycle refreshes the results of recently changed ranked events, then takes this
+// month's snapshot if it hasn't been taken yet
+func RunRankingCycle(db *sqlx.DB) error {
+	now := time.Now()
+	if _, _, err := recomputeRankingResults(db, now.AddDate(0, -1, 0)); err != nil {
+		return err
+	}
+
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var exists bool
+	db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM national_ranking_snapshots WHERE snapshot_month = ?)", month)
+	if exists {
+		return nil
+	}
+
+	n, err := takeRankingSnapshot(db, month)
+	if err == nil {
+		log.Printf("[ranking] snapshot %s stored with %d entries", month.Format("2006-01"), n)
+	}
+	return err
+}
+
+// StartRankingScheduler runs RunRankingCycle on a fixed interval in the background
+func StartRankingScheduler(db *sqlx.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := RunRankingCycle(db); err != nil {
+				log.Printf("[ranking] cycle failed: %v", err)
+			}
+		}
+	}()
+}
+
+// GetNationalRankings returns the live ranking, optionally as of ?as_of=YYYY-MM-DD, with each
+// archer's movement since the last monthly snapshot
+func GetNationalRankings(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asOf := time.Now()
+		if s := c.Query("as_of"); s != "" {
+			t, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be YYYY-MM-DD"})
+				return
+			}
+			asOf = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+
+		entries, err := computeRankings(db, asOf, bindRankingFilter(db, c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate rankings", "details": err.Error()})
+			return
+		}
+		applyRankingMovement(db, entries, time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, asOf.Location()))
+
+		if entries == nil {
+			entries = []models.RankingEntry{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":          entries,
+			"as_of":         asOf.Format("2006-01-02"),
+			"window_months": rankingWindowMonths,
+			"best_results":  rankingBestResults,
+		})
+	}
+}
+
+// GetRankingSnapshots lists the months a ranking snapshot was taken
+func GetRankingSnapshots(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var months []struct {
+			Month   string `json:"month" db:"month"`
+			Entries int    `json:"entries" db:"entries"`
+		}
+		err := db.Select(&months, `
+			SELECT DATE_FORMAT(snapshot_month, '%Y-%m') as month, COUNT(*) as entries
+			FROM national_ranking_snapshots
+			GROUP BY snapshot_month
+			ORDER BY snapshot_month DESC
+		`)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch snapshots"})
+			return
+		}
+
+		if months == nil {
+			months = []struct {
+				Month   string `json:"month" db:"month"`
+				Entries int    `json:"entries" db:"entries"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": months})
+	}
+}
+
+// GetRankingSnapshot returns the stored ranking of a month (YYYY-MM) with movement against the
+// snapshot before it
+func GetRankingSnapshot(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		month, err := time.ParseInLocation("2006-01", c.Param("month"), time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+			return
+		}
+
+		cond, args := bindRankingFilter(db, c).where("s")
+		args = append([]interface{}{month}, args...)
+
+		var entries []models.RankingEntry
+		err = db.Select(&entries, `
+			SELECT s.rank_position, s.archer_id, s.bow_type_id, s.gender_division_id, s.age_group_id, s.points, s.results_counted,
+				a.full_name as archer_name, a.avatar_url, cl.name as club_name
+			FROM national_ranking_snapshots s
+			JOIN archers a ON s.archer_id = a.uuid
+			LEFT JOIN clubs cl ON a.club_id = cl.uuid
+			WHERE s.snapshot_month = ?`+cond+`
+			ORDER BY s.bow_type_id, s.gender_division_id, s.age_group_id, s.rank_position, a.full_name
+		`, args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch snapshot", "details": err.Error()})
+			return
+		}
+		applyRankingMovement(db, entries, month)
+
+		if entries == nil {
+			entries = []models.RankingEntry{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": entries, "month": month.Format("2006-01")})
+	}
+}
+
+// GetArcherRanking returns an archer's current ranking positions and every ranking result in
+// the window, marking the ones that count
+func GetArcherRanking(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		archerID := c.Param("archerId")
+		now := time.Now()
+
+		var results []struct {
+			models.RankingResult
+			EventName string `json:"event_name" db:"event_name"`
+			Counted   bool   `json:"counted" db:"counted"`
+		}
+		err := db.Select(&results, `
+			SELECT r.*, e.name as event_name,
+				ROW_NUMBER() OVER (PARTITION BY r.bow_type_id, r.gender_division_id, r.age_group_id ORDER BY r.points DESC, r.event_date DESC) <= ? as counted
+			FROM national_ranking_results r
+			JOIN events e ON r.event_id = e.uuid
+			WHERE r.archer_id = ? AND r.event_date > ? AND r.event_date <= ?
+			ORDER BY r.event_date DESC
+		`, rankingBestResults, archerID, now.AddDate(0, -rankingWindowMonths, 0), now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ranking results", "details": err.Error()})
+			return
+		}
+
+		all, err := computeRankings(db, now, rankingFilter{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate rankings", "details": err.Error()})
+			return
+		}
+		applyRankingMovement(db, all, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()))
+
+		positions := []models.RankingEntry{}
+		for _, e := range all {
+			if e.ArcherID == archerID {
+				positions = append(positions, e)
+			}
+		}
+
+		if results == nil {
+			results = []struct {
+				models.RankingResult
+				EventName string `json:"event_name" db:"event_name"`
+				Counted   bool   `json:"counted" db:"counted"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"positions": positions, "results": results})
+	}
+}
+
+// RecomputeNationalRankings rebuilds all ranking results from event history
+func RecomputeNationalRankings(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		events, results, err := recomputeRankingResults(db, time.Time{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute rankings", "details": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Rankings recomputed", "events": events, "results": results})
+	}
+}
+
+// CreateRankingSnapshot stores the ranking snapshot of a month (YYYY-MM, default this month)
+func CreateRankingSnapshot(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Month string `json:"month"`
+		}
+		c.ShouldBindJSON(&req)
+
+		month := time.Now()
+		if req.Month != "" {
+			var err error
+			month, err = time.ParseInLocation("2006-01", req.Month, time.Local)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+				return
+			}
+		}
+
+		n, err := takeRankingSnapshot(db, month)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store snapshot", "details": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Snapshot stored", "month": month.Format("2006-01"), "entries": n})
+	}
+}
+
+// refreshEventRanking recomputes an event's ranking results after its tier changed
+func refreshEventRanking(db *sqlx.DB, eventID string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := refreshEventRankingResults(tx, eventID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,709 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var errCreditNoteInvalid = errors.New("credit note is invalid, used or expired")
+
+// refundPolicyPercent returns the refund percentage that applies to a cancellation made now,
+// based on the event's policy rules. Events without a policy refund nothing.
+func refundPolicyPercent(q sqlx.Queryer, eventID string, now time.Time) float64 {
+	var startDate *time.Time
+	if err := sqlx.Get(q, &startDate, "SELECT start_date FROM events WHERE uuid = ?", eventID); err != nil || startDate == nil {
+		return 0
+	}
+
+	var rules []models.RefundPolicyRule
+	sqlx.Select(q, &rules, `
+		SELECT uuid, event_id, days_before, refund_percent, created_at
+		FROM event_refund_policies
+		WHERE event_id = ?
+		ORDER BY days_before DESC
+	`, eventID)
+
+	daysBefore := int(math.Floor(startDate.Sub(now).Hours() / 24))
+	for _, rule := range rules {
+		if daysBefore >= rule.DaysBefore {
+			return rule.RefundPercent
+		}
+	}
+	return 0
+}
+
+// createParticipantRefund records a refund request for a paid participant and restores credit
+// notes redeemed for the entry. When percent is nil the event's refund policy decides the amount.
+// It returns an empty ID if nothing was paid. The archer is told with notifyRefundRequested once
+// the caller's transaction is committed.
+func createParticipantRefund(q sqlx.Ext, participantID, reason string, percent *float64) (string, error) {
+	var p struct {
+		EventID       string  `db:"event_id"`
+		ArcherID      string  `db:"archer_id"`
+		CategoryID    *string `db:"category_id"`
+		PaymentStatus string  `db:"payment_status"`
+		PaymentAmount float64 `db:"payment_amount"`
+	}
+	err := sqlx.Get(q, &p, `
+		SELECT event_id, archer_id, category_id, COALESCE(payment_status, '') as payment_status, COALESCE(payment_amount, 0) as payment_amount
+		FROM event_participants WHERE uuid = ?
+	`, participantID)
+	if err != nil {
+		return "", err
+	}
+
+	var existingID string
+	err = sqlx.Get(q, &existingID, "SELECT uuid FROM refunds WHERE participant_id = ? AND status != 'rejected' LIMIT 1", participantID)
+	if err == nil {
+		return existingID, nil
+	}
+
+	policyPercent := 0.0
+	if percent != nil {
+		policyPercent = *percent
+	} else {
+		policyPercent = refundPolicyPercent(q, p.EventID, time.Now())
+	}
+
+	// Credit that paid for the entry goes back to its credit notes under the same policy
+	if err := restoreCreditNotes(q, participantID, policyPercent); err != nil {
+		return "", err
+	}
+
+	// The entry fee is refunded; the gateway's customer fee on top of it is not. Online payments
+	// are linked to their transaction.
+	var transactionID *string
+	paidAmount := 0.0
+	var trxID string
+	err = sqlx.Get(q, &trxID, `
+		SELECT uuid FROM payment_transactions
+		WHERE participant_id = ? AND status = 'paid'
+		ORDER BY paid_at DESC LIMIT 1
+	`, participantID)
+	if err == nil {
+		transactionID = &trxID
+		paidAmount = p.PaymentAmount
+	} else if p.PaymentStatus == "lunas" {
+		paidAmount = p.PaymentAmount
+	}
+
+	if paidAmount <= 0 {
+		return "", nil
+	}
+
+	refundAmount := math.Round(paidAmount * policyPercent / 100)
+
+	status := "requested"
+	if refundAmount <= 0 {
+		status = "rejected"
+	}
+
+	// The category is kept on the refund because a cancelled entry's participant row is deleted
+	refundID := uuid.New().String()
+	_, err = q.Exec(`
+		INSERT INTO refunds (uuid, event_id, archer_id, participant_id, category_id, transaction_id, reason, paid_amount, policy_percent, refund_amount, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, refundID, p.EventID, p.ArcherID, participantID, p.CategoryID, transactionID, reason, paidAmount, policyPercent, refundAmount, status)
+	if err != nil {
+		return "", err
+	}
+
+	return refundID, nil
+}
+
+// notifyRefundRequested tells the archer a refund request is waiting for the organizer
+func notifyRefundRequested(db *sqlx.DB, refundID string) {
+	var refund models.Refund
+	if err := db.Get(&refund, "SELECT * FROM refunds WHERE uuid = ?", refundID); err != nil || refund.Status != "requested" {
+		return
+	}
+	utils.Notify(db, refund.ArcherID, "archer", "info", "Refund diajukan",
+		fmt.Sprintf("Pengajuan refund sebesar Rp %.0f sedang diproses oleh panitia.", refund.RefundAmount), "")
+}
+
+// redeemCreditNote deducts up to amount from an archer's credit note balance and returns the
+// amount actually covered
+func redeemCreditNote(tx *sqlx.Tx, code, archerID, participantID string, amount float64) (float64, error) {
+	var note models.CreditNote
+	err := tx.Get(&note, `
+		SELECT uuid, code, archer_id, refund_id, amount, balance, status, expires_at, created_at
+		FROM credit_notes
+		WHERE code = ? AND archer_id = ?
+		FOR UPDATE
+	`, code, archerID)
+	if err != nil {
+		return 0, errCreditNoteInvalid
+	}
+	if note.Status != "active" || note.Balance <= 0 || (note.ExpiresAt != nil && note.ExpiresAt.Before(time.Now())) {
+		return 0, errCreditNoteInvalid
+	}
+
+	applied := math.Min(note.Balance, amount)
+	newBalance := note.Balance - applied
+	newStatus := "active"
+	if newBalance <= 0 {
+		newStatus = "used"
+	}
+
+	if _, err := tx.Exec("UPDATE credit_notes SET balance = ?, status = ? WHERE uuid = ?", newBalance, newStatus, note.UUID); err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO credit_note_redemptions (uuid, credit_note_id, participant_id, amount)
+		VALUES (?, ?, ?, ?)
+	`, uuid.New().String(), note.UUID, participantID, applied)
+	if err != nil {
+		return 0, err
+	}
+
+	return applied, nil
+}
+
+// restoreCreditNotes gives back the given percent of the credit redeemed for a participant and
+// drops the redemptions, so calling it again restores nothing
+func restoreCreditNotes(q sqlx.Ext, participantID string, percent float64) error {
+	var redemptions []struct {
+		UUID         string  `db:"uuid"`
+		CreditNoteID string  `db:"credit_note_id"`
+		Amount       float64 `db:"amount"`
+	}
+	if err := sqlx.Select(q, &redemptions, "SELECT uuid, credit_note_id, amount FROM credit_note_redemptions WHERE participant_id = ?", participantID); err != nil {
+		return err
+	}
+
+	for _, r := range redemptions {
+		if back := math.Round(r.Amount * percent / 100); back > 0 {
+			if _, err := q.Exec(`
+				UPDATE credit_notes SET balance = balance + ?, status = IF(status = 'used', 'active', status)
+				WHERE uuid = ?
+			`, back, r.CreditNoteID); err != nil {
+				return err
+			}
+		}
+		if _, err := q.Exec("DELETE FROM credit_note_redemptions WHERE uuid = ?", r.UUID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadManagedRefund fetches a refund and checks that the current user organizes its event
+func loadManagedRefund(db *sqlx.DB, c *gin.Context) (models.Refund, bool) {
+	var refund models.Refund
+	err := db.Get(&refund, "SELECT * FROM refunds WHERE uuid = ?", c.Param("refundId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Refund not found"})
+		return refund, false
+	}
+
+	if _, ok := loadManagedEvent(db, c, refund.EventID); !ok {
+		return refund, false
+	}
+
+	return refund, true
+}
+
+// GetEventRefundPolicy returns the refund policy rules of an event
+func GetEventRefundPolicy(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := c.Param("id")
+
+		var actualEventID string
+		err := db.Get(&actualEventID, `SELECT uuid FROM events WHERE uuid = ? OR slug = ?`, eventID, eventID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+
+		var rules []models.RefundPolicyRule
+		err = db.Select(&rules, `
+			SELECT uuid, event_id, days_before, refund_percent, created_at
+			FROM event_refund_policies
+			WHERE event_id = ?
+			ORDER BY days_before DESC
+		`, actualEventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refund policy"})
+			return
+		}
+
+		if rules == nil {
+			rules = []models.RefundPolicyRule{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": rules})
+	}
+}
+
+// refundPolicySnapshot reads the refund policy rules of an event for the activity log
+func refundPolicySnapshot(db *sqlx.DB, eventID string) map[string]interface{} {
+	rules, _ := exportRows(db, `
+		SELECT days_before, refund_percent FROM event_refund_policies
+		WHERE event_id = ? ORDER BY days_before DESC
+	`, eventID)
+	return map[string]interface{}{"rules": rules}
+}
+
+// UpdateEventRefundPolicy replaces the refund policy rules of an event
+func UpdateEventRefundPolicy(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req models.RefundPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		before := refundPolicySnapshot(db, actualEventID)
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec("DELETE FROM event_refund_policies WHERE event_id = ?", actualEventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear refund policy"})
+			return
+		}
+
+		for _, rule := range req.Rules {
+			_, err := tx.Exec(`
+				INSERT INTO event_refund_policies (uuid, event_id, days_before, refund_percent)
+				VALUES (?, ?, ?, ?)
+			`, uuid.New().String(), actualEventID, rule.DaysBefore, rule.RefundPercent)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save refund policy"})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit refund policy"})
+			return
+		}
+
+		logEventActivity(db, c, actualEventID, "refund_policy_updated", "event", actualEventID, "Updated refund policy", before, refundPolicySnapshot(db, actualEventID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Refund policy updated successfully"})
+	}
+}
+
+// CancelEvent cancels an event and opens a full refund for every paid participant
+func CancelEvent(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		c.ShouldBindJSON(&req)
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		var event struct {
+			Name   string `db:"name"`
+			Status string `db:"status"`
+		}
+		if err := tx.Get(&event, "SELECT name, COALESCE(status, '') as status FROM events WHERE uuid = ? FOR UPDATE", actualEventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load event"})
+			return
+		}
+		if event.Status == "cancelled" || event.Status == "completed" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Event is already " + event.Status})
+			return
+		}
+
+		before := rowSnapshot(db, "events", actualEventID)
+		if _, err := tx.Exec("UPDATE events SET status = 'cancelled', updated_at = NOW() WHERE uuid = ?", actualEventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel event"})
+			return
+		}
+
+		var participants []struct {
+			UUID     string `db:"uuid"`
+			ArcherID string `db:"archer_id"`
+		}
+		if err := tx.Select(&participants, "SELECT uuid, archer_id FROM event_participants WHERE event_id = ?", actualEventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load participants"})
+			return
+		}
+
+		fullRefund := 100.0
+		var refundIDs []string
+		for _, p := range participants {
+			refundID, err := createParticipantRefund(tx, p.UUID, "event_cancelled", &fullRefund)
+			if err != nil {
+				log.Printf("[refund] failed to create refund for participant %s: %v", p.UUID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create refunds"})
+				return
+			}
+			if refundID != "" {
+				refundIDs = append(refundIDs, refundID)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit event cancellation"})
+			return
+		}
+
+		for _, refundID := range refundIDs {
+			notifyRefundRequested(db, refundID)
+		}
+		for _, p := range participants {
+			utils.Notify(db, p.ArcherID, "archer", "warning", "Event dibatalkan",
+				fmt.Sprintf("Event %s dibatalkan oleh panitia. %s", event.Name, req.Reason), "")
+		}
+
+		logEventActivity(db, c, actualEventID, "event_cancelled", "event", actualEventID, "Cancelled event: "+req.Reason, before, rowSnapshot(db, "events", actualEventID))
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":         "Event cancelled successfully",
+			"refunds_created": len(refundIDs),
+		})
+	}
+}
+
+// GetEventRefunds lists refund requests of an event for its organizer
+func GetEventRefunds(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actualEventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		query := `
+			SELECT r.*, a.full_name as archer_name
+			FROM refunds r
+			LEFT JOIN archers a ON r.archer_id = a.uuid
+			WHERE r.event_id = ?
+		`
+		args := []interface{}{actualEventID}
+		if status := c.Query("status"); status != "" {
+			query += " AND r.status = ?"
+			args = append(args, status)
+		}
+		query += " ORDER BY r.created_at DESC"
+
+		var refunds []struct {
+			models.Refund
+			ArcherName *string `json:"archer_name" db:"archer_name"`
+		}
+		if err := db.Select(&refunds, query, args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refunds"})
+			return
+		}
+
+		if refunds == nil {
+			refunds = []struct {
+				models.Refund
+				ArcherName *string `json:"archer_name" db:"archer_name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": refunds})
+	}
+}
+
+// ApproveRefund approves a refund request, either as a bank transfer payout or as a credit note
+func ApproveRefund(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refund, ok := loadManagedRefund(db, c)
+		if !ok {
+			return
+		}
+
+		if refund.Status != "requested" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Refund is already " + refund.Status})
+			return
+		}
+
+		var req models.ApproveRefundRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		amount := refund.RefundAmount
+		if req.RefundAmount != nil {
+			amount = *req.RefundAmount
+		}
+		if amount <= 0 || amount > refund.PaidAmount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Refund amount must be between 0 and the paid amount"})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		before := rowSnapshot(db, "refunds", refund.UUID)
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		status := "approved"
+		if req.PayoutMethod == "credit_note" {
+			status = "credited"
+		}
+
+		// Only one approval can move the refund out of requested; a concurrent one finds it gone
+		result, err := tx.Exec(`
+			UPDATE refunds
+			SET status = ?, refund_amount = ?, payout_method = ?, review_note = ?, processed_by = ?, processed_at = NOW(), updated_at = NOW()
+			WHERE uuid = ? AND status = 'requested'
+		`, status, amount, req.PayoutMethod, req.Note, userID, refund.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve refund"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Refund is no longer awaiting approval"})
+			return
+		}
+
+		// A refunded entry doesn't count as a promo code use (a cancelled one was released already)
+		if err := releasePromoCode(tx, refund.ParticipantID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release promo code"})
+			return
+		}
+
+		var creditCode string
+		if req.PayoutMethod == "credit_note" {
+			creditCode = "CN-" + strings.ToUpper(uuid.New().String()[:8])
+			expiresAt := time.Now().AddDate(1, 0, 0)
+			_, err = tx.Exec(`
+				INSERT INTO credit_notes (uuid, code, archer_id, refund_id, amount, balance, status, expires_at)
+				VALUES (?, ?, ?, ?, ?, ?, 'active', ?)
+			`, uuid.New().String(), creditCode, refund.ArcherID, refund.UUID, amount, amount, expiresAt)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue credit note"})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit refund"})
+			return
+		}
+
+		message := fmt.Sprintf("Refund sebesar Rp %.0f disetujui dan akan ditransfer ke rekening Anda.", amount)
+		if creditCode != "" {
+			message = fmt.Sprintf("Refund sebesar Rp %.0f diberikan sebagai kredit dengan kode %s untuk event berikutnya.", amount, creditCode)
+		}
+		utils.Notify(db, refund.ArcherID, "archer", "success", "Refund disetujui", message, "")
+		logEventActivity(db, c, refund.EventID, "refund_approved", "refund", refund.UUID, "Approved refund via "+req.PayoutMethod, before, rowSnapshot(db, "refunds", refund.UUID))
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":          "Refund approved successfully",
+			"status":           status,
+			"credit_note_code": creditCode,
+		})
+	}
+}
+
+// RejectRefund rejects a refund request with a reason
+func RejectRefund(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refund, ok := loadManagedRefund(db, c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Reason string `json:"reason" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		before := rowSnapshot(db, "refunds", refund.UUID)
+		result, err := db.Exec(`
+			UPDATE refunds SET status = 'rejected', review_note = ?, processed_by = ?, processed_at = NOW(), updated_at = NOW()
+			WHERE uuid = ? AND status = 'requested'
+		`, req.Reason, userID, refund.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject refund"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Refund is already " + refund.Status})
+			return
+		}
+
+		utils.Notify(db, refund.ArcherID, "archer", "danger", "Refund ditolak", "Pengajuan refund Anda ditolak: "+req.Reason, "")
+		logEventActivity(db, c, refund.EventID, "refund_rejected", "refund", refund.UUID, "Rejected refund: "+req.Reason, before, rowSnapshot(db, "refunds", refund.UUID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Refund rejected"})
+	}
+}
+
+// MarkRefundPaid records that an approved bank transfer refund has been paid out
+func MarkRefundPaid(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refund, ok := loadManagedRefund(db, c)
+		if !ok {
+			return
+		}
+
+		if refund.Status != "approved" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only approved refunds can be marked as paid"})
+			return
+		}
+
+		var req struct {
+			PayoutReference string `json:"payout_reference" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		before := rowSnapshot(db, "refunds", refund.UUID)
+		result, err := db.Exec(`
+			UPDATE refunds SET status = 'paid', payout_reference = ?, updated_at = NOW()
+			WHERE uuid = ? AND status = 'approved'
+		`, req.PayoutReference, refund.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update refund"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Refund is no longer awaiting payout"})
+			return
+		}
+
+		// A full refund closes the original transaction through the payment ledger
+		if refund.TransactionID != nil && refund.RefundAmount >= refund.PaidAmount {
+			var reference string
+			if err := db.Get(&reference, "SELECT reference FROM payment_transactions WHERE uuid = ?", *refund.TransactionID); err == nil {
+				payload := []byte(models.ToJSON(gin.H{"refund_id": refund.UUID, "payout_reference": req.PayoutReference}))
+				if _, err := applyPaymentSignal(db, reference, "manual", "REFUND", payload); err != nil {
+					fmt.Printf("[ERROR] Failed to mark transaction %s refunded: %v\n", reference, err)
+				}
+			}
+		}
+
+		utils.Notify(db, refund.ArcherID, "archer", "success", "Refund ditransfer",
+			fmt.Sprintf("Refund sebesar Rp %.0f telah ditransfer. Referensi: %s", refund.RefundAmount, req.PayoutReference), "")
+		logEventActivity(db, c, refund.EventID, "refund_paid", "refund", refund.UUID, "Refund paid out: "+req.PayoutReference, before, rowSnapshot(db, "refunds", refund.UUID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Refund marked as paid"})
+	}
+}
+
+// UpdateRefundBankAccount lets the archer provide the bank account for a refund payout
+func UpdateRefundBankAccount(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var req struct {
+			BankName          string `json:"bank_name" binding:"required"`
+			BankAccountName   string `json:"bank_account_name" binding:"required"`
+			BankAccountNumber string `json:"bank_account_number" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := db.Exec(`
+			UPDATE refunds SET bank_name = ?, bank_account_name = ?, bank_account_number = ?, updated_at = NOW()
+			WHERE uuid = ? AND archer_id = ? AND status IN ('requested', 'approved')
+		`, req.BankName, req.BankAccountName, req.BankAccountNumber, c.Param("refundId"), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update bank account"})
+			return
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Refund not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Bank account saved"})
+	}
+}
+
+// GetMyRefunds returns the refunds of the authenticated archer
+func GetMyRefunds(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var refunds []struct {
+			models.Refund
+			EventName *string `json:"event_name" db:"event_name"`
+		}
+		err := db.Select(&refunds, `
+			SELECT r.*, e.name as event_name
+			FROM refunds r
+			LEFT JOIN events e ON r.event_id = e.uuid
+			WHERE r.archer_id = ?
+			ORDER BY r.created_at DESC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refunds"})
+			return
+		}
+
+		if refunds == nil {
+			refunds = []struct {
+				models.Refund
+				EventName *string `json:"event_name" db:"event_name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": refunds})
+	}
+}
+
+// GetMyCreditNotes returns the credit notes of the authenticated archer
+func GetMyCreditNotes(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var notes []models.CreditNote
+		err := db.Select(&notes, `
+			SELECT uuid, code, archer_id, refund_id, amount, balance, status, expires_at, created_at
+			FROM credit_notes
+			WHERE archer_id = ?
+			ORDER BY created_at DESC
+		`, userID)
+		if err != nil && err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch credit notes"})
+			return
+		}
+
+		if notes == nil {
+			notes = []models.CreditNote{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": notes})
+	}
+}
@@ -14,6 +14,9 @@ import (
 func GetPublicQualificationResults(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
+		if !apiKeyCoversEvent(db, c, eventID) {
+			return
+		}
 		categoryID := c.Query("category_id")
 		
 		if eventID == "" {
@@ -199,6 +202,9 @@ func GetPublicQualificationResults(db *sqlx.DB) gin.HandlerFunc {
 func GetPublicEliminationResults(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
+		if !apiKeyCoversEvent(db, c, eventID) {
+			return
+		}
 		categoryID := c.Query("category_id")
 
 		if eventID == "" {
@@ -468,3 +474,131 @@ func GetPublicEliminationResults(db *sqlx.DB) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"bracket": bracket_result})
 	}
 }
+
+// categoryPlacing is an archer's final placing in an event category
+type categoryPlacing struct {
+	ArcherID   string  `db:"archer_id"`
+	ArcherName string  `db:"archer_name"`
+	ClubID     *string `db:"club_id"`
+	Place      int     `db:"-"`
+}
+
+// categoryFinalPlacings returns the final placings of an individual category. Placings come from
+// the elimination bracket once its final is finished: finalists take 1st and 2nd, the bronze match
+// 3rd and 4th, and earlier losers share the place below their round (5th, 9th, 17th...). Archers
+// not placed by the bracket keep their qualification rank. Archers who shot no ends are left out.
+// Only completed events have placings. The club is the one the archer belonged to when the event
+// started; archers without any membership history fall back to archers.club_id.
+func categoryFinalPlacings(db sqlx.Queryer, categoryID string) ([]categoryPlacing, error) {
+	var placings []categoryPlacing
+	err := sqlx.Select(db, &placings, `
+		SELECT a.uuid as archer_id, a.full_name as archer_name,
+			CASE WHEN EXISTS (SELECT 1 FROM club_members h WHERE h.archer_id = a.uuid AND h.joined_at IS NOT NULL)
+				THEN (
+					SELECT cm.club_id FROM club_members cm
+					WHERE cm.archer_id = a.uuid
+					  AND cm.status IN ('active', 'overdue', 'suspended', 'left', 'transferred')
+					  AND DATE(cm.joined_at) <= DATE(e.start_date)
+					  AND (cm.left_at IS NULL OR DATE(cm.left_at) > DATE(e.start_date))
+					ORDER BY cm.joined_at DESC LIMIT 1
+				)
+				ELSE a.club_id
+			END as club_id
+		FROM event_participants ep
+		JOIN events e ON ep.event_id = e.uuid
+		JOIN archers a ON ep.archer_id = a.uuid
+		JOIN qualification_end_scores qes ON qes.participant_uuid = ep.uuid
+		WHERE ep.category_id = ? AND e.status = 'completed'
+		GROUP BY a.uuid, a.full_name, a.club_id, e.start_date
+		ORDER BY SUM(qes.total_score_end) DESC, SUM(qes.ten_count_end) DESC, SUM(qes.x_count_end) DESC
+	`, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range placings {
+		placings[i].Place = i + 1
+	}
+
+	var bracket struct {
+		UUID        string `db:"uuid"`
+		BracketSize int    `db:"bracket_size"`
+	}
+	err = sqlx.Get(db, &bracket, `
+		SELECT uuid, bracket_size FROM elimination_brackets
+		WHERE category_uuid = ? AND bracket_type = 'individual' AND generated_at IS NOT NULL
+		LIMIT 1
+	`, categoryID)
+	if err == sql.ErrNoRows || bracket.BracketSize < 2 {
+		return placings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []struct {
+		RoundNo int     `db:"round_no"`
+		MatchNo int     `db:"match_no"`
+		EntryA  *string `db:"entry_a"`
+		EntryB  *string `db:"entry_b"`
+		Winner  *string `db:"winner"`
+	}
+	err = sqlx.Select(db, &matches, `
+		SELECT em.round_no, em.match_no, ea.participant_uuid as entry_a, eb.participant_uuid as entry_b, ew.participant_uuid as winner
+		FROM elimination_matches em
+		LEFT JOIN elimination_entries ea ON em.entry_a_uuid = ea.uuid
+		LEFT JOIN elimination_entries eb ON em.entry_b_uuid = eb.uuid
+		LEFT JOIN elimination_entries ew ON em.winner_entry_uuid = ew.uuid
+		WHERE em.bracket_uuid = ? AND em.status = 'finished' AND em.winner_entry_uuid IS NOT NULL
+		ORDER BY em.round_no ASC, em.match_no ASC
+	`, bracket.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	numRounds := 0
+	for size := bracket.BracketSize; size > 1; size /= 2 {
+		numRounds++
+	}
+
+	bracketPlace := map[string]int{}
+	finalFinished := false
+	for _, m := range matches {
+		if m.EntryA == nil || m.EntryB == nil {
+			continue // bye
+		}
+		loser := *m.EntryA
+		if loser == *m.Winner {
+			loser = *m.EntryB
+		}
+
+		switch {
+		case m.RoundNo == numRounds && m.MatchNo == 1:
+			bracketPlace[*m.Winner] = 1
+			bracketPlace[loser] = 2
+			finalFinished = true
+		case m.RoundNo == numRounds && m.MatchNo == 2:
+			// Rounds are ordered, so the bronze match overrides the shared semifinal place
+			bracketPlace[*m.Winner] = 3
+			bracketPlace[loser] = 4
+		case m.RoundNo == numRounds-1:
+			bracketPlace[loser] = 3
+		default:
+			bracketPlace[loser] = bracket.BracketSize/(1<<uint(m.RoundNo)) + 1
+		}
+	}
+	if !finalFinished {
+		return placings, nil
+	}
+
+	for i := range placings {
+		if place, ok := bracketPlace[placings[i].ArcherID]; ok {
+			placings[i].Place = place
+		} else if placings[i].Place <= bracket.BracketSize {
+			// Qualified for the bracket but no result recorded; place after the bracket
+			placings[i].Place = bracket.BracketSize + 1
+		}
+	}
+	sort.SliceStable(placings, func(i, j int) bool { return placings[i].Place < placings[j].Place })
+
+	return placings, nil
+}
@@ -0,0 +1,489 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// accessTokenTTL is the lifetime of an access token; clients refresh after that
+	accessTokenTTL = 15 * time.Minute
+	// refreshTokenTTL is how long a session survives without being refreshed
+	refreshTokenTTL = 30 * 24 * time.Hour
+	// impersonationTTL caps support sessions an admin starts as another user; they aren't extended
+	impersonationTTL = time.Hour
+	// sessionCheckTTL is how long a session lookup is cached by the revocation check. Revocations
+	// made by this process take effect at once, those made by other instances within this window.
+	sessionCheckTTL = 30 * time.Second
+)
+
+// sessionTokens is what a client receives on login and on every refresh
+type sessionTokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+}
+
+type sessionCheckEntry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+// sessionCache holds recent revocation lookups keyed by session id
+var sessionCache sync.Map
+
+// hashToken is the form refresh and single-use auth tokens are stored in
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// setRefreshCookie stores the refresh token in a cookie only sent to the auth endpoints
+func setRefreshCookie(c *gin.Context, token string, maxAge int) {
+	isProduction := os.Getenv("ENV") == "production"
+	host := c.Request.Host
+	isLocal := strings.HasPrefix(host, "localhost") || strings.HasPrefix(host, "127.0.0.1") || strings.HasPrefix(host, "0.0.0.0")
+
+	domain := ""
+	secure := false
+	if isProduction && !isLocal {
+		domain = ".archeryhub.id"
+		secure = true
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("refresh_token", token, maxAge, "/api/v1/auth", domain, secure, true)
+}
+
+// setSessionCookies sets the access and refresh cookies for a session
+func setSessionCookies(c *gin.Context, tokens *sessionTokens) {
+	setAuthCookie(c, tokens.AccessToken, int(accessTokenTTL.Seconds()))
+	setRefreshCookie(c, tokens.RefreshToken, int(refreshTokenTTL.Seconds()))
+}
+
+// clearSessionCookies removes both session cookies
+func clearSessionCookies(c *gin.Context) {
+	setAuthCookie(c, "", -1)
+	setRefreshCookie(c, "", -1)
+}
+
+// insertRefreshToken creates a new refresh token for the session and returns it in plain text
+func insertRefreshToken(q sqlx.Execer, sessionID string) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	_, err = q.Exec(`
+		INSERT INTO auth_refresh_tokens (uuid, session_id, token_hash, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, uuid.New().String(), sessionID, hashToken(token), time.Now().Add(refreshTokenTTL))
+	return token, err
+}
+
+// issueSession starts a session of the profile for a user who just authenticated, sets the cookies
+// and returns the tokens. Impersonation sessions don't touch the admin's own cookies.
+func issueSession(db *sqlx.DB, c *gin.Context, p sessionProfile) (*sessionTokens, error) {
+	sessionID := uuid.New().String()
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var identityID, impersonatedBy *string
+	if p.IdentityID != "" {
+		identityID = &p.IdentityID
+	}
+	expiresAt := time.Now().Add(refreshTokenTTL)
+	if p.ImpersonatorID != "" {
+		impersonatedBy = &p.ImpersonatorID
+		expiresAt = time.Now().Add(impersonationTTL)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO auth_sessions (uuid, identity_id, user_id, user_type, role, email, name, avatar, two_factor, impersonated_by, user_agent, ip_address, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), ?)
+	`, sessionID, identityID, p.UserID, p.UserType, p.Role, p.Email, p.Name, p.Avatar, p.TwoFactor, impersonatedBy, userAgent, c.ClientIP(), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := insertRefreshToken(tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := generateJWT(p, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := &sessionTokens{SessionID: sessionID, AccessToken: accessToken, RefreshToken: refreshToken}
+	if p.ImpersonatorID == "" {
+		setSessionCookies(c, tokens)
+	}
+	return tokens, nil
+}
+
+// revokeSession ends one session
+func revokeSession(db *sqlx.DB, sessionID, reason string) error {
+	_, err := db.Exec(`
+		UPDATE auth_sessions SET revoked_at = NOW(), revoke_reason = ?
+		WHERE uuid = ? AND revoked_at IS NULL
+	`, reason, sessionID)
+	sessionCache.Delete(sessionID)
+	return err
+}
+
+// revokeUserSessions ends every session of a user and returns how many were active. userID may be
+// a profile or an identity; a profile's sessions include those of the other profiles of its
+// identity, since they share the credentials.
+func revokeUserSessions(db *sqlx.DB, userID, reason string) (int64, error) {
+	return revokeOtherUserSessions(db, userID, "", reason)
+}
+
+// revokeOtherUserSessions is revokeUserSessions that keeps one session, the one making the request
+func revokeOtherUserSessions(db *sqlx.DB, userID, keepSessionID, reason string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE auth_sessions SET revoked_at = NOW(), revoke_reason = ?
+		WHERE revoked_at IS NULL AND uuid != ? AND (
+			user_id = ? OR identity_id = ?
+			OR identity_id IN (SELECT identity_id FROM identity_profiles WHERE profile_id = ?)
+		)
+	`, reason, keepSessionID, userID, userID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// The cache isn't keyed by user, so drop it entirely
+	sessionCache.Range(func(key, _ interface{}) bool {
+		sessionCache.Delete(key)
+		return true
+	})
+
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// SessionActive returns the check AuthMiddleware uses to reject tokens of revoked or expired
+// sessions
+func SessionActive(db *sqlx.DB) func(sessionID string) bool {
+	return func(sessionID string) bool {
+		if entry, ok := sessionCache.Load(sessionID); ok {
+			e := entry.(sessionCheckEntry)
+			if time.Since(e.checkedAt) < sessionCheckTTL {
+				return e.active
+			}
+		}
+
+		var active bool
+		err := db.Get(&active, `
+			SELECT EXISTS(SELECT 1 FROM auth_sessions WHERE uuid = ? AND revoked_at IS NULL AND expires_at > NOW())
+		`, sessionID)
+		if err != nil {
+			// Don't lock everyone out while the database hiccups; the token itself was valid
+			return true
+		}
+
+		sessionCache.Store(sessionID, sessionCheckEntry{active: active, checkedAt: time.Now()})
+		return active
+	}
+}
+
+// currentSessionProfile rebuilds who the session acts as from the identity and profile as they are
+// now, so a refresh picks up suspensions and platform role changes. ok is false when the login or
+// the profile may no longer be used.
+func currentSessionProfile(db *sqlx.DB, session models.AuthSession) (profile sessionProfile, ok bool, err error) {
+	profile = sessionProfile{
+		UserID:    session.UserID,
+		Email:     session.Email,
+		Role:      session.Role,
+		UserType:  session.UserType,
+		Name:      session.Name,
+		Avatar:    session.Avatar,
+		TwoFactor: session.TwoFactor,
+	}
+	if session.ImpersonatedBy != nil {
+		profile.ImpersonatorID = *session.ImpersonatedBy
+	}
+
+	if session.IdentityID == nil {
+		// Older sessions predate identities; only the profile's own status can be checked
+		table, known := accountTable(session.UserType)
+		if !known {
+			return profile, true, nil
+		}
+		var status string
+		err := db.Get(&status, "SELECT COALESCE(status, 'active') FROM "+table+" WHERE uuid = ?", session.UserID)
+		if err == sql.ErrNoRows {
+			return profile, false, nil
+		}
+		return profile, err == nil && status == "active", err
+	}
+
+	identity, err := loadIdentity(db, *session.IdentityID)
+	if err == sql.ErrNoRows {
+		return profile, false, nil
+	}
+	if err != nil {
+		return profile, false, err
+	}
+	if identity.Status != "" && identity.Status != "active" {
+		return profile, false, nil
+	}
+
+	var current models.IdentityProfile
+	err = db.Get(&current, identityProfileSelect+" WHERE ip.identity_id = ? AND ip.profile_id = ?", identity.UUID, session.UserID)
+	if err == sql.ErrNoRows {
+		return profile, false, nil
+	}
+	if err != nil {
+		return profile, false, err
+	}
+	if current.Status != "active" {
+		return profile, false, nil
+	}
+
+	fresh := profileSession(identity, current)
+	fresh.TwoFactor = profile.TwoFactor
+	fresh.ImpersonatorID = profile.ImpersonatorID
+	// A support session never turns into an admin one
+	if fresh.ImpersonatorID != "" && fresh.Role == "admin" {
+		return profile, false, nil
+	}
+	return fresh, true, nil
+}
+
+// RefreshSession swaps a refresh token for a new access token and a new refresh token. Presenting
+// a refresh token that was already used means it leaked, so the whole session is revoked.
+func RefreshSession(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RefreshTokenRequest
+		c.ShouldBindJSON(&req)
+		if req.RefreshToken == "" {
+			req.RefreshToken, _ = c.Cookie("refresh_token")
+		}
+		if req.RefreshToken == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token required", "code": "refresh_token_missing"})
+			return
+		}
+
+		var token struct {
+			UUID      string     `db:"uuid"`
+			SessionID string     `db:"session_id"`
+			ExpiresAt time.Time  `db:"expires_at"`
+			UsedAt    *time.Time `db:"used_at"`
+		}
+		err := db.Get(&token, "SELECT uuid, session_id, expires_at, used_at FROM auth_refresh_tokens WHERE token_hash = ?", hashToken(req.RefreshToken))
+		if err == sql.ErrNoRows {
+			clearSessionCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token", "code": "invalid_refresh_token"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check refresh token"})
+			return
+		}
+
+		var session models.AuthSession
+		if err := db.Get(&session, "SELECT * FROM auth_sessions WHERE uuid = ?", token.SessionID); err != nil {
+			clearSessionCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token", "code": "invalid_refresh_token"})
+			return
+		}
+
+		if token.UsedAt != nil {
+			revokeSession(db, session.UUID, "refresh_token_reuse")
+			utils.LogActivity(db, session.UserID, "", "refresh_token_reused", session.UserType, session.UUID,
+				"Refresh token reused; session revoked", c.ClientIP(), c.Request.UserAgent())
+			clearSessionCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used; please log in again", "code": "refresh_token_reused"})
+			return
+		}
+		if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) || time.Now().After(token.ExpiresAt) {
+			clearSessionCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired; please log in again", "code": "session_expired"})
+			return
+		}
+
+		// The access token carries the role and profile, so they are reloaded rather than copied
+		profile, active, err := currentSessionProfile(db, session)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load account"})
+			return
+		}
+		if !active {
+			revokeSession(db, session.UUID, "suspended")
+			clearSessionCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "This account is no longer active", "code": "account_inactive"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		// A concurrent refresh with the same token loses the race and is treated as reuse
+		result, err := tx.Exec("UPDATE auth_refresh_tokens SET used_at = NOW() WHERE uuid = ? AND used_at IS NULL", token.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate refresh token"})
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			tx.Rollback()
+			revokeSession(db, session.UUID, "refresh_token_reuse")
+			clearSessionCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used; please log in again", "code": "refresh_token_reused"})
+			return
+		}
+
+		refreshToken, err := insertRefreshToken(tx, session.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate refresh token"})
+			return
+		}
+		if _, err := tx.Exec(`
+			UPDATE auth_sessions SET last_used_at = NOW(), expires_at = IF(impersonated_by IS NULL, ?, expires_at),
+				role = ?, email = ?, name = ?, avatar = ?
+			WHERE uuid = ?
+		`, time.Now().Add(refreshTokenTTL), profile.Role, profile.Email, profile.Name, profile.Avatar, session.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit refresh"})
+			return
+		}
+
+		accessToken, err := generateJWT(profile, session.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		tokens := &sessionTokens{SessionID: session.UUID, AccessToken: accessToken, RefreshToken: refreshToken}
+		if session.ImpersonatedBy == nil {
+			setSessionCookies(c, tokens)
+		}
+
+		c.JSON(http.StatusOK, AuthResponse{
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int(accessTokenTTL.Seconds()),
+		})
+	}
+}
+
+// LogoutEverywhere revokes every session of the current user, including this one
+func LogoutEverywhere(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+
+		count, err := revokeUserSessions(db, userID, "logout_all")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+		clearSessionCookies(c)
+
+		utils.LogActivity(db, userID, "", "user_logged_out_everywhere", c.GetString("user_type"), userID,
+			"Logged out of all sessions", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions", "revoked": count})
+	}
+}
+
+// GetMySessions lists the current user's active sessions
+func GetMySessions(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var sessions []models.AuthSession
+		err := db.Select(&sessions, `
+			SELECT * FROM auth_sessions
+			WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+			ORDER BY last_used_at DESC
+		`, c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+			return
+		}
+
+		current := c.GetString("session_id")
+		for i := range sessions {
+			sessions[i].Current = sessions[i].UUID == current
+		}
+
+		if sessions == nil {
+			sessions = []models.AuthSession{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": sessions})
+	}
+}
+
+// RevokeMySession ends one of the current user's sessions, e.g. a lost phone
+func RevokeMySession(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("sessionId")
+
+		var owner string
+		if err := db.Get(&owner, "SELECT user_id FROM auth_sessions WHERE uuid = ?", sessionID); err != nil || owner != c.GetString("user_id") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+
+		if err := revokeSession(db, sessionID, "logout"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+	}
+}
+
+// AdminRevokeUserSessions signs a user out everywhere, e.g. a suspended archer or club
+func AdminRevokeUserSessions(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		c.ShouldBindJSON(&req)
+		reason := "admin"
+		if req.Reason == "suspended" {
+			reason = "suspended"
+		}
+
+		targetID := c.Param("userId")
+		count, err := revokeUserSessions(db, targetID, reason)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+
+		utils.LogActivity(db, c.GetString("user_id"), "", "user_sessions_revoked", "user", targetID,
+			"Admin revoked all sessions ("+reason+")", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Sessions revoked", "revoked": count})
+	}
+}
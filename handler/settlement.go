@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// manualChannel is the channel used for entries paid by manual bank transfer (payment proof upload)
+const manualChannel = "MANUAL_TRANSFER"
+
+// settlementLine is a single paid entry or refund that feeds the settlement report
+type settlementLine struct {
+	Channel      string  `db:"channel"`
+	CategoryID   string  `db:"category_id"`
+	CategoryName string  `db:"category_name"`
+	Amount       float64 `db:"amount"`
+	FeeMerchant  float64 `db:"fee_merchant"`
+	FeeCustomer  float64 `db:"fee_customer"`
+}
+
+// buildSettlementReport collects the paid entries, refunds and platform fee of an event
+func buildSettlementReport(db *sqlx.DB, eventID string) (models.SettlementReport, error) {
+	report := models.SettlementReport{
+		EventID:     eventID,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := db.Get(&report.EventName, "SELECT name FROM events WHERE uuid = ?", eventID); err != nil {
+		return report, err
+	}
+
+	categoryName := `COALESCE(NULLIF(TRIM(CONCAT(COALESCE(rbt.name, ''), ' ', COALESCE(rag.name, ''))), ''), 'Unknown Category')`
+
+	// Cancelled entries lose their participant row, so their category comes from the refund
+	// that was recorded when they were cancelled
+	refundCategory := `(SELECT r.category_id FROM refunds r WHERE r.participant_id = %s ORDER BY r.created_at DESC LIMIT 1)`
+	entryCategory := `COALESCE(ep.category_id, ` + fmt.Sprintf(refundCategory, "pt.participant_id") + `, '')`
+
+	// Online payments (including ones refunded later, which still count as gross), manual
+	// transfers that were confirmed without a Tripay transaction, and manual transfers of
+	// cancelled entries that only their refund remembers
+	var entries []settlementLine
+	err := db.Select(&entries, `
+		SELECT channel, category_id, `+categoryName+` as category_name, amount, fee_merchant, fee_customer
+		FROM (
+			SELECT COALESCE(pt.payment_method, 'UNKNOWN') as channel,
+				`+entryCategory+` as category_id,
+				pt.amount, COALESCE(pt.fee_merchant, 0) as fee_merchant, COALESCE(pt.fee_customer, 0) as fee_customer
+			FROM payment_transactions pt
+			LEFT JOIN event_participants ep ON ep.uuid = pt.participant_id
+			WHERE pt.event_id = ? AND pt.status IN ('paid', 'refunded')
+			  AND (pt.participant_id IS NOT NULL OR pt.registration_id IS NOT NULL)
+
+			UNION ALL
+
+			SELECT ? as channel, COALESCE(ep.category_id, '') as category_id,
+				COALESCE(ep.payment_amount, 0) as amount, 0 as fee_merchant, 0 as fee_customer
+			FROM event_participants ep
+			WHERE ep.event_id = ? AND ep.payment_status = 'lunas'
+			  AND NOT EXISTS (
+				SELECT 1 FROM payment_transactions pt
+				WHERE pt.participant_id = ep.uuid AND pt.status IN ('paid', 'refunded')
+			  )
+
+			UNION ALL
+
+			SELECT ? as channel, COALESCE(r.category_id, '') as category_id,
+				r.paid_amount as amount, 0 as fee_merchant, 0 as fee_customer
+			FROM refunds r
+			WHERE r.event_id = ? AND r.transaction_id IS NULL AND r.paid_amount > 0
+			  AND NOT EXISTS (SELECT 1 FROM event_participants ep WHERE ep.uuid = r.participant_id)
+			  AND NOT EXISTS (
+				SELECT 1 FROM refunds newer
+				WHERE newer.participant_id = r.participant_id AND newer.created_at > r.created_at
+			  )
+		) e
+		LEFT JOIN event_categories ec ON ec.uuid = e.category_id
+		LEFT JOIN ref_bow_types rbt ON ec.division_uuid = rbt.uuid
+		LEFT JOIN ref_age_groups rag ON ec.category_uuid = rag.uuid
+	`, eventID, manualChannel, eventID, manualChannel, eventID)
+	if err != nil {
+		return report, err
+	}
+
+	// Refunds that actually left the organizer's pocket (cash or store credit)
+	var refunds []settlementLine
+	err = db.Select(&refunds, `
+		SELECT COALESCE(pt.payment_method, ?) as channel,
+			COALESCE(r.category_id, ep.category_id, '') as category_id,
+			`+categoryName+` as category_name,
+			r.refund_amount as amount, 0 as fee_merchant, 0 as fee_customer
+		FROM refunds r
+		LEFT JOIN payment_transactions pt ON pt.uuid = r.transaction_id
+		LEFT JOIN event_participants ep ON ep.uuid = r.participant_id
+		LEFT JOIN event_categories ec ON ec.uuid = COALESCE(r.category_id, ep.category_id)
+		LEFT JOIN ref_bow_types rbt ON ec.division_uuid = rbt.uuid
+		LEFT JOIN ref_age_groups rag ON ec.category_uuid = rag.uuid
+		WHERE r.event_id = ? AND r.status IN ('paid', 'credited')
+	`, manualChannel, eventID)
+	if err != nil {
+		return report, err
+	}
+
+	err = db.Get(&report.Totals.PlatformFee, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM payment_transactions
+		WHERE event_id = ? AND status = 'paid' AND participant_id IS NULL AND registration_id IS NULL
+	`, eventID)
+	if err != nil {
+		return report, err
+	}
+
+	byChannel := map[string]*models.SettlementBreakdown{}
+	byCategory := map[string]*models.SettlementBreakdown{}
+	row := func(groups map[string]*models.SettlementBreakdown, key, label string) *models.SettlementBreakdown {
+		if groups[key] == nil {
+			groups[key] = &models.SettlementBreakdown{Key: key, Label: label}
+		}
+		return groups[key]
+	}
+
+	for _, e := range entries {
+		report.Totals.Entries++
+		report.Totals.GrossAmount += e.Amount
+		report.Totals.FeeMerchant += e.FeeMerchant
+		report.Totals.FeeCustomer += e.FeeCustomer
+		for _, b := range []*models.SettlementBreakdown{
+			row(byChannel, e.Channel, e.Channel),
+			row(byCategory, e.CategoryID, e.CategoryName),
+		} {
+			b.Entries++
+			b.GrossAmount += e.Amount
+			b.FeeMerchant += e.FeeMerchant
+			b.FeeCustomer += e.FeeCustomer
+		}
+	}
+	for _, r := range refunds {
+		report.Totals.Refunds += r.Amount
+		row(byChannel, r.Channel, r.Channel).Refunds += r.Amount
+		row(byCategory, r.CategoryID, r.CategoryName).Refunds += r.Amount
+	}
+
+	// The platform fee is paid by the organizer up front, so it is not taken out of the payout again
+	report.Totals.NetAmount = settlementNet(report.Totals.GrossAmount, report.Totals.FeeMerchant, report.Totals.Refunds)
+	report.ByChannel = sortedBreakdown(byChannel)
+	report.ByCategory = sortedBreakdown(byCategory)
+
+	return report, nil
+}
+
+// settlementNet is what reaches the organizer: gross entry fees minus merchant fees and refunds
+func settlementNet(gross, feeMerchant, refunds float64) float64 {
+	return gross - feeMerchant - refunds
+}
+
+// csvSafe stops spreadsheet apps from evaluating a cell as a formula
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// sortedBreakdown computes net per group and returns the groups ordered by label
+func sortedBreakdown(groups map[string]*models.SettlementBreakdown) []models.SettlementBreakdown {
+	result := make([]models.SettlementBreakdown, 0, len(groups))
+	for _, b := range groups {
+		b.NetAmount = settlementNet(b.GrossAmount, b.FeeMerchant, b.Refunds)
+		result = append(result, *b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Label < result[j].Label })
+	return result
+}
+
+// settlementRows flattens a report into spreadsheet rows: summary, then channel and category tables
+func settlementRows(report models.SettlementReport) [][]interface{} {
+	t := report.Totals
+	rows := [][]interface{}{
+		{"Event", report.EventName},
+		{"Generated At", report.GeneratedAt},
+		{},
+		{"Entries", t.Entries},
+		{"Gross Entry Fees", t.GrossAmount},
+		{"Tripay Fee (Merchant)", t.FeeMerchant},
+		{"Tripay Fee (Customer)", t.FeeCustomer},
+		{"Refunds", t.Refunds},
+		{"Platform Fee", t.PlatformFee},
+		{"Net Payout", t.NetAmount},
+	}
+
+	header := []interface{}{"", "Entries", "Gross", "Fee Merchant", "Fee Customer", "Refunds", "Net"}
+	for _, section := range []struct {
+		title string
+		items []models.SettlementBreakdown
+	}{
+		{"By Payment Channel", report.ByChannel},
+		{"By Category", report.ByCategory},
+	} {
+		h := append([]interface{}{}, header...)
+		h[0] = section.title
+		rows = append(rows, []interface{}{}, h)
+		for _, b := range section.items {
+			rows = append(rows, []interface{}{b.Label, b.Entries, b.GrossAmount, b.FeeMerchant, b.FeeCustomer, b.Refunds, b.NetAmount})
+		}
+	}
+
+	return rows
+}
+
+// GetEventSettlement returns the settlement report of an event (organizer or admin).
+// Pass ?format=csv or ?format=xlsx to download it as a file.
+func GetEventSettlement(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		report, err := buildSettlementReport(db, eventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build settlement report: " + err.Error()})
+			return
+		}
+
+		filename := fmt.Sprintf("settlement-%s-%s", eventID, time.Now().Format("20060102"))
+
+		switch c.Query("format") {
+		case "csv":
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			for _, row := range settlementRows(report) {
+				record := make([]string, len(row))
+				for i, cell := range row {
+					switch v := cell.(type) {
+					case float64:
+						record[i] = strconv.FormatFloat(v, 'f', 2, 64)
+					case string:
+						record[i] = csvSafe(v)
+					default:
+						record[i] = fmt.Sprintf("%v", v)
+					}
+				}
+				w.Write(record)
+			}
+			w.Flush()
+
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+			c.Data(http.StatusOK, "text/csv", buf.Bytes())
+		case "xlsx":
+			var buf bytes.Buffer
+			err := utils.WriteXLSX(&buf, []utils.XLSXSheet{{Name: "Settlement", Rows: settlementRows(report)}})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate XLSX"})
+				return
+			}
+
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
+			c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+		default:
+			c.JSON(http.StatusOK, gin.H{"data": report})
+		}
+	}
+}
@@ -204,6 +204,9 @@ func UpdateQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		var eventUUID string
+		db.Get(&eventUUID, `SELECT event_uuid FROM qualification_sessions WHERE uuid = ?`, req.SessionUUID)
+
 		// Check if participant already has an assignment in this session
 		var existingParticipantAssignment string
 		err = db.Get(&existingParticipantAssignment, `
@@ -213,6 +216,7 @@ func UpdateQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 
 		if err == nil && existingParticipantAssignment != "" {
 			// Update existing assignment for this participant
+			before := rowSnapshot(db, "qualification_target_assignments", existingParticipantAssignment)
 			_, err = db.Exec(`
 					UPDATE qualification_target_assignments 
 					SET target_uuid = ?, updated_at = NOW()
@@ -223,6 +227,9 @@ func UpdateQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 				return
 			}
 
+			logEventActivity(db, c, eventUUID, "target_assignment_updated", "target_assignment", existingParticipantAssignment,
+				"Moved participant to another target", before, rowSnapshot(db, "qualification_target_assignments", existingParticipantAssignment))
+
 			c.JSON(http.StatusOK, gin.H{
 				"message":       "Assignment updated successfully",
 				"assignment_id": existingParticipantAssignment,
@@ -232,6 +239,7 @@ func UpdateQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 
 		if req.AssignmentUUID != nil && *req.AssignmentUUID != "" {
 			// Update existing assignment
+			before := rowSnapshot(db, "qualification_target_assignments", *req.AssignmentUUID)
 			_, err = db.Exec(`
 				UPDATE qualification_target_assignments 
 				SET target_uuid = ?, updated_at = NOW()
@@ -243,6 +251,9 @@ func UpdateQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 				return
 			}
 
+			logEventActivity(db, c, eventUUID, "target_assignment_updated", "target_assignment", *req.AssignmentUUID,
+				"Moved participant to another target", before, rowSnapshot(db, "qualification_target_assignments", *req.AssignmentUUID))
+
 			c.JSON(http.StatusOK, gin.H{
 				"message":       "Assignment updated successfully",
 				"assignment_id": *req.AssignmentUUID,
@@ -260,6 +271,9 @@ func UpdateQualificationAssignment(db *sqlx.DB) gin.HandlerFunc {
 				return
 			}
 
+			logEventActivity(db, c, eventUUID, "target_assignment_created", "target_assignment", newUUID,
+				"Assigned participant to a target", nil, rowSnapshot(db, "qualification_target_assignments", newUUID))
+
 			c.JSON(http.StatusCreated, gin.H{
 				"message":       "Assignment created successfully",
 				"assignment_id": newUUID,
@@ -467,6 +481,9 @@ func CreateEventTarget(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "targets_created", "event", eventUUID,
+			fmt.Sprintf("Created target %s%s", req.TargetName, strings.Join(clean, "")), nil, gin.H{"target_ids": createdIDs, "target_name": req.TargetName, "target_numbers": clean})
+
 		c.JSON(http.StatusCreated, gin.H{
 			"message":        "Targets created successfully",
 			"created_count":  len(createdIDs),
@@ -543,12 +560,15 @@ func UpdateEventTarget(db *sqlx.DB) gin.HandlerFunc {
 		query := fmt.Sprintf("UPDATE event_targets SET %s WHERE uuid = ?",
 			joinStrings(updateFields, ", "))
 
+		before := rowSnapshot(db, "event_targets", targetID)
 		_, err = db.Exec(query, updateValues...)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update target", "details": err.Error()})
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "target_updated", "event_target", targetID, "Updated target", before, rowSnapshot(db, "event_targets", targetID))
+
 		c.JSON(http.StatusOK, gin.H{"message": "Target updated successfully"})
 	}
 }
@@ -573,12 +593,17 @@ func DeleteEventTarget(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := rowSnapshot(db, "event_targets", targetID)
 		_, err = db.Exec(`DELETE FROM event_targets WHERE uuid = ?`, targetID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete target"})
 			return
 		}
 
+		if eventUUID, ok := before["event_uuid"].(string); ok {
+			logEventActivity(db, c, eventUUID, "target_deleted", "event_target", targetID, "Deleted target", before, nil)
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "Target deleted successfully"})
 	}
 }
@@ -669,6 +694,18 @@ func BatchUpdateTargets(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		names := func() map[string]interface{} {
+			rows, _ := exportRows(db, `SELECT uuid, target_name FROM event_targets WHERE event_uuid = ?`, eventUUID)
+			byID := map[string]interface{}{}
+			for _, r := range rows {
+				if id, ok := r["uuid"].(string); ok {
+					byID[id] = r["target_name"]
+				}
+			}
+			return byID
+		}
+		before := names()
+
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -726,6 +763,9 @@ func BatchUpdateTargets(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		logEventActivity(db, c, eventUUID, "targets_renamed", "event", eventUUID,
+			fmt.Sprintf("Renamed %d targets", len(req.Updates)), before, names())
+
 		c.JSON(http.StatusOK, gin.H{"message": "Targets updated successfully"})
 	}
 }
@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	"archeryhub-api/models"
-	"archeryhub-api/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -18,7 +17,6 @@ import (
 func CreateTeam(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("eventId")
-		userID, _ := c.Get("user_id")
 
 		var req models.CreateTeamRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -67,8 +65,8 @@ func CreateTeam(db *sqlx.DB) gin.HandlerFunc {
 			}
 		}
 
-		utils.LogActivity(db, userID.(string), eventID, "team_created", "team", teamID, 
-			fmt.Sprintf("Created team: %s", req.TeamName), c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, eventID, "team_created", "team", teamID,
+			fmt.Sprintf("Created team: %s", req.TeamName), nil, teamSnapshot(db, teamID))
 
 		c.JSON(http.StatusCreated, gin.H{
 			"id":      teamID,
@@ -286,6 +284,9 @@ func SubmitTeamScore(db *sqlx.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if !canScoreEvent(db, c, req.EventID) {
+			return
+		}
 
 		// Calculate team total
 		endTotal := 0
@@ -317,6 +318,11 @@ func SubmitTeamScore(db *sqlx.DB) gin.HandlerFunc {
 
 		// Store member scores as JSON
 		memberScoresJSON, _ := json.Marshal(req.MemberScores)
+		endQuery := `
+			SELECT member_scores, end_total, x_count FROM team_scores
+			WHERE team_id = ? AND session = ? AND distance_order = ? AND end_number = ?
+		`
+		before := activitySnapshot(db, endQuery, req.TeamID, req.Session, req.DistanceOrder, req.EndNumber)
 
 		scoreID := uuid.New().String()
 		_, err := db.Exec(`
@@ -342,6 +348,10 @@ func SubmitTeamScore(db *sqlx.DB) gin.HandlerFunc {
 			WHERE id = ?
 		`, req.TeamID, req.TeamID, req.TeamID)
 
+		logEventActivity(db, c, req.EventID, "team_score_submitted", "team", req.TeamID,
+			fmt.Sprintf("Team score for session %d distance %d end %d", req.Session, req.DistanceOrder, req.EndNumber),
+			before, activitySnapshot(db, endQuery, req.TeamID, req.Session, req.DistanceOrder, req.EndNumber))
+
 		// Broadcast update
 		// BroadcastEventUpdate(req.EventID, gin.H{
 		// 	"type": "team_score_update",
@@ -600,7 +610,6 @@ func GetMixedTeamQualificationRankings(db *sqlx.DB) gin.HandlerFunc {
 func AutoCreateTeams(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tournamentID := c.Param("eventId") // The main event UUID
-		userID, _ := c.Get("user_id")
 
 		var req struct {
 			CategoryID string `json:"category_id" binding:"required"`
@@ -671,8 +680,9 @@ func AutoCreateTeams(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		utils.LogActivity(db, userID.(string), tournamentID, "teams_regenerated", "event", tournamentID, 
-			fmt.Sprintf("Regenerated %d teams for category %s", len(req.Teams), req.CategoryID), c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, tournamentID, "teams_regenerated", "event", tournamentID,
+			fmt.Sprintf("Regenerated %d teams for category %s", len(req.Teams), req.CategoryID),
+			gin.H{"team_ids": teamUUIDs}, gin.H{"teams": req.Teams})
 
 		c.JSON(http.StatusOK, gin.H{"message": "Teams synchronized successfully", "count": len(req.Teams)})
 	}
@@ -681,7 +691,6 @@ func AutoCreateTeams(db *sqlx.DB) gin.HandlerFunc {
 func SyncTeams(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("eventId")
-		userID, _ := c.Get("user_id")
 
 		// Resolve event UUID (allow slug)
 		var eventUUID string
@@ -892,18 +901,35 @@ func SyncTeams(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		utils.LogActivity(db, userID.(string), eventUUID, "teams_synced_directly", "event", eventUUID, 
-			fmt.Sprintf("Directly synced %d teams for category %s", syncCount, req.CategoryID), c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, eventUUID, "teams_synced_directly", "event", eventUUID,
+			fmt.Sprintf("Directly synced %d teams for category %s", syncCount, req.CategoryID), nil, nil)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Sync completed", "count": syncCount})
 	}
 }
 
+// teamSnapshot reads a team with its members for the activity log
+func teamSnapshot(db *sqlx.DB, teamID string) map[string]interface{} {
+	team := rowSnapshot(db, "teams", teamID)
+	if team == nil {
+		return nil
+	}
+	members := []string{}
+	db.Select(&members, "SELECT participant_id FROM team_members WHERE team_id = ? ORDER BY member_order", teamID)
+	team["member_ids"] = members
+	return team
+}
+
+// teamEventID is the event a team snapshot belongs to (its tournament_id)
+func teamEventID(team map[string]interface{}) string {
+	eventID, _ := team["tournament_id"].(string)
+	return eventID
+}
+
 // UpdateTeam updates a team's details and members
 func UpdateTeam(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		teamID := c.Param("teamId")
-		userID, _ := c.Get("user_id")
 
 		var req models.CreateTeamRequest // Reuse CreateTeamRequest for update
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -911,6 +937,8 @@ func UpdateTeam(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		before := teamSnapshot(db, teamID)
+
 		tx, err := db.Beginx()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
@@ -970,8 +998,9 @@ func UpdateTeam(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		utils.LogActivity(db, userID.(string), "", "team_updated", "team", teamID, 
-			fmt.Sprintf("Updated team: %s", req.TeamName), c.ClientIP(), c.Request.UserAgent())
+		after := teamSnapshot(db, teamID)
+		logEventActivity(db, c, teamEventID(after), "team_updated", "team", teamID,
+			fmt.Sprintf("Updated team: %s", req.TeamName), before, after)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Team updated successfully"})
 	}
@@ -981,7 +1010,7 @@ func UpdateTeam(db *sqlx.DB) gin.HandlerFunc {
 func DeleteTeam(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		teamID := c.Param("teamId")
-		userID, _ := c.Get("user_id")
+		before := teamSnapshot(db, teamID)
 
 		tx, err := db.Beginx()
 		if err != nil {
@@ -1009,8 +1038,8 @@ func DeleteTeam(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		utils.LogActivity(db, userID.(string), "", "team_deleted", "team", teamID, 
-			"Deleted a team", c.ClientIP(), c.Request.UserAgent())
+		logEventActivity(db, c, teamEventID(before), "team_deleted", "team", teamID,
+			"Deleted a team", before, nil)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
 	}
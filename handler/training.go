@@ -0,0 +1,775 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// loadClubTrainingSession fetches a training session of the club, writing 404 when it doesn't exist
+func loadClubTrainingSession(db *sqlx.DB, c *gin.Context, sessionID, clubID string) (*models.TrainingSession, bool) {
+	var session models.TrainingSession
+	err := db.Get(&session, "SELECT * FROM club_training_sessions WHERE uuid = ? AND club_id = ?", sessionID, clubID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Training session not found"})
+		return nil, false
+	}
+	return &session, true
+}
+
+// GetClubTrainingSessions lists the training sessions of the current club with attendance counts
+func GetClubTrainingSessions(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageTraining)
+		if !ok {
+			return
+		}
+
+		query := `
+			SELECT s.*, (SELECT COUNT(*) FROM club_training_attendance a WHERE a.session_id = s.uuid) as attendee_count
+			FROM club_training_sessions s
+			WHERE s.club_id = ?
+		`
+		args := []interface{}{clubID}
+		if from := c.Query("from"); from != "" {
+			query += " AND s.session_date >= ?"
+			args = append(args, from)
+		}
+		if to := c.Query("to"); to != "" {
+			query += " AND s.session_date <= ?"
+			args = append(args, to)
+		}
+		query += " ORDER BY s.session_date DESC, s.start_time DESC"
+
+		var sessions []struct {
+			models.TrainingSession
+			AttendeeCount int `json:"attendee_count" db:"attendee_count"`
+		}
+		if err := db.Select(&sessions, query, args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch training sessions"})
+			return
+		}
+
+		if sessions == nil {
+			sessions = []struct {
+				models.TrainingSession
+				AttendeeCount int `json:"attendee_count" db:"attendee_count"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": sessions})
+	}
+}
+
+// CreateClubTrainingSession schedules a training session for the current club
+func CreateClubTrainingSession(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageTraining)
+		if !ok {
+			return
+		}
+
+		var req models.TrainingSessionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := time.Parse("2006-01-02", req.SessionDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session_date must be YYYY-MM-DD"})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		sessionID := uuid.New().String()
+		_, err := db.Exec(`
+			INSERT INTO club_training_sessions (uuid, club_id, title, session_date, start_time, end_time, location, notes, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, sessionID, clubID, req.Title, req.SessionDate, req.StartTime, req.EndTime, req.Location, req.Notes, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create training session"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Training session created", "id": sessionID})
+	}
+}
+
+// UpdateClubTrainingSession changes a training session of the current club
+func UpdateClubTrainingSession(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageTraining)
+		if !ok {
+			return
+		}
+		session, ok := loadClubTrainingSession(db, c, c.Param("sessionId"), clubID)
+		if !ok {
+			return
+		}
+
+		var req models.TrainingSessionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := time.Parse("2006-01-02", req.SessionDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session_date must be YYYY-MM-DD"})
+			return
+		}
+
+		_, err := db.Exec(`
+			UPDATE club_training_sessions
+			SET title = ?, session_date = ?, start_time = ?, end_time = ?, location = ?, notes = ?, updated_at = NOW()
+			WHERE uuid = ?
+		`, req.Title, req.SessionDate, req.StartTime, req.EndTime, req.Location, req.Notes, session.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update training session"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Training session updated"})
+	}
+}
+
+// DeleteClubTrainingSession removes a training session and its attendance
+func DeleteClubTrainingSession(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageTraining)
+		if !ok {
+			return
+		}
+		session, ok := loadClubTrainingSession(db, c, c.Param("sessionId"), clubID)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		_, err = tx.Exec("DELETE FROM club_training_attendance WHERE session_id = ?", session.UUID)
+		if err == nil {
+			// Practice rounds logged at the session are kept, just detached
+			_, err = tx.Exec("UPDATE practice_rounds SET training_session_id = NULL WHERE training_session_id = ?", session.UUID)
+		}
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM club_training_sessions WHERE uuid = ?", session.UUID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete training session"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Training session deleted"})
+	}
+}
+
+// CheckInTrainingSession records attendance by scanning an archer's membership QR code, or by
+// archer ID for manual check-in
+func CheckInTrainingSession(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageTraining)
+		if !ok {
+			return
+		}
+		session, ok := loadClubTrainingSession(db, c, c.Param("sessionId"), clubID)
+		if !ok {
+			return
+		}
+
+		var req models.TrainingCheckInRequest
+		if err := c.ShouldBindJSON(&req); err != nil || (req.QRRaw == "" && req.ArcherID == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "QR code or archer_id is required"})
+			return
+		}
+
+		var member struct {
+			ArcherID string `db:"archer_id"`
+			FullName string `db:"full_name"`
+		}
+		method := "qr"
+		query := `
+			SELECT cm.archer_id, a.full_name
+			FROM club_members cm
+			JOIN archers a ON cm.archer_id = a.uuid
+			WHERE cm.club_id = ? AND cm.status IN ('active', 'overdue') AND `
+		var err error
+		if req.QRRaw != "" {
+			err = db.Get(&member, query+"cm.qr_raw = ? LIMIT 1", clubID, req.QRRaw)
+		} else {
+			method = "manual"
+			err = db.Get(&member, query+"cm.archer_id = ? LIMIT 1", clubID, req.ArcherID)
+		}
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Anggota tidak ditemukan. QR Code tidak valid."})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
+			return
+		}
+
+		var alreadyCheckedIn bool
+		db.Get(&alreadyCheckedIn, "SELECT EXISTS(SELECT 1 FROM club_training_attendance WHERE session_id = ? AND archer_id = ?)", session.UUID, member.ArcherID)
+		if alreadyCheckedIn {
+			c.JSON(http.StatusOK, gin.H{
+				"success":     true,
+				"message":     "Sudah tercatat hadir",
+				"archer_id":   member.ArcherID,
+				"archer_name": member.FullName,
+			})
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		_, err = db.Exec(`
+			INSERT INTO club_training_attendance (uuid, session_id, archer_id, method, checked_in_by)
+			VALUES (?, ?, ?, ?, ?)
+		`, uuid.New().String(), session.UUID, member.ArcherID, method, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record attendance"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":     true,
+			"message":     "Kehadiran tercatat",
+			"archer_id":   member.ArcherID,
+			"archer_name": member.FullName,
+		})
+	}
+}
+
+// GetTrainingSessionAttendance lists who checked in at a training session
+func GetTrainingSessionAttendance(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermManageTraining)
+		if !ok {
+			return
+		}
+		session, ok := loadClubTrainingSession(db, c, c.Param("sessionId"), clubID)
+		if !ok {
+			return
+		}
+
+		var attendance []struct {
+			models.TrainingAttendance
+			ArcherName string `json:"archer_name" db:"archer_name"`
+		}
+		err := db.Select(&attendance, `
+			SELECT ta.*, a.full_name as archer_name
+			FROM club_training_attendance ta
+			JOIN archers a ON ta.archer_id = a.uuid
+			WHERE ta.session_id = ?
+			ORDER BY ta.checked_in_at ASC
+		`, session.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attendance"})
+			return
+		}
+
+		if attendance == nil {
+			attendance = []struct {
+				models.TrainingAttendance
+				ArcherName string `json:"archer_name" db:"archer_name"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"session": session, "data": attendance})
+	}
+}
+
+// GetMyClubQRCode returns the membership QR code archers show at training check-in.
+// Pass ?format=png for the image.
+func GetMyClubQRCode(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var memberID string
+		err := db.Get(&memberID, "SELECT uuid FROM club_members WHERE archer_id = ? AND status IN ('active', 'overdue') LIMIT 1", userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No active membership found"})
+			return
+		}
+
+		// Generated on first use, like the participant QR on payment
+		db.Exec("UPDATE club_members SET qr_raw = COALESCE(qr_raw, ?) WHERE uuid = ?", uuid.New().String(), memberID)
+
+		var qrRaw string
+		if err := db.Get(&qrRaw, "SELECT qr_raw FROM club_members WHERE uuid = ?", memberID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load QR code"})
+			return
+		}
+
+		if c.Query("format") == "png" {
+			png, err := utils.GenerateQRCode(qrRaw, 256)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+				return
+			}
+			c.Data(http.StatusOK, "image/png", png)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"qr_raw": qrRaw})
+	}
+}
+
+// maxPracticeEnds and maxPracticeArrowsPerEnd bound a practice round; a full 1440 round is 36 ends of 6
+const (
+	maxPracticeEnds         = 36
+	maxPracticeArrowsPerEnd = 6
+)
+
+// isValidArrow reports whether a is a scoring value: X, M or 1 to 10
+func isValidArrow(a string) bool {
+	if a == "X" || a == "M" {
+		return true
+	}
+	v, err := strconv.Atoi(a)
+	return err == nil && v >= 1 && v <= 10 && strconv.Itoa(v) == a
+}
+
+// practiceRoundColumns selects a practice round with its running totals
+const practiceRoundColumns = `
+	r.uuid, r.archer_id, r.club_id, r.training_session_id, DATE_FORMAT(r.practice_date, '%Y-%m-%d') as practice_date,
+	r.distance, r.arrows_per_end, r.notes, r.created_at,
+	COALESCE(SUM(e.total_score_end), 0) as total_score,
+	COALESCE(SUM(e.x_count_end), 0) as total_x,
+	COUNT(e.uuid) as ends
+`
+
+// CreatePracticeRound starts a practice round for the current archer
+func CreatePracticeRound(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var req models.PracticeRoundRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.PracticeDate == "" {
+			req.PracticeDate = time.Now().Format("2006-01-02")
+		}
+		if _, err := time.Parse("2006-01-02", req.PracticeDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "practice_date must be YYYY-MM-DD"})
+			return
+		}
+		if req.ArrowsPerEnd <= 0 {
+			req.ArrowsPerEnd = 6
+		}
+		if req.ArrowsPerEnd > maxPracticeArrowsPerEnd {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("arrows_per_end must be at most %d", maxPracticeArrowsPerEnd)})
+			return
+		}
+
+		// Rounds are attributed to the archer's club, or the club of the training session
+		var clubID *string
+		db.Get(&clubID, "SELECT club_id FROM club_members WHERE archer_id = ? AND status IN ('active', 'overdue') LIMIT 1", userID)
+		if req.TrainingSessionID != nil {
+			var sessionClubID string
+			err := db.Get(&sessionClubID, "SELECT club_id FROM club_training_sessions WHERE uuid = ?", *req.TrainingSessionID)
+			if err != nil || clubID == nil || *clubID != sessionClubID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Training session not found in your club"})
+				return
+			}
+		}
+
+		roundID := uuid.New().String()
+		_, err := db.Exec(`
+			INSERT INTO practice_rounds (uuid, archer_id, club_id, training_session_id, practice_date, distance, arrows_per_end, notes)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, roundID, userID, clubID, req.TrainingSessionID, req.PracticeDate, req.Distance, req.ArrowsPerEnd, req.Notes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create practice round"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Practice round created", "id": roundID})
+	}
+}
+
+// UpdatePracticeEnds saves practice ends of a round. The body uses the same arrow format as
+// qualification scoring: {"ends": [{"end_number": 1, "arrows": ["X", "10", "9", "M"]}]}.
+func UpdatePracticeEnds(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roundID := c.Param("roundId")
+		userID, _ := c.Get("user_id")
+
+		var arrowsPerEnd int
+		if err := db.Get(&arrowsPerEnd, "SELECT arrows_per_end FROM practice_rounds WHERE uuid = ? AND archer_id = ?", roundID, userID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Practice round not found"})
+			return
+		}
+
+		ends, ok := bindEndScores(c)
+		if !ok {
+			return
+		}
+		for _, end := range ends {
+			if end.EndNumber < 1 || end.EndNumber > maxPracticeEnds {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("end_number must be between 1 and %d", maxPracticeEnds)})
+				return
+			}
+			if len(end.Arrows) > arrowsPerEnd {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("End %d has more than %d arrows", end.EndNumber, arrowsPerEnd)})
+				return
+			}
+			for _, arrow := range end.Arrows {
+				if !isValidArrow(arrow) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid arrow value %q in end %d", arrow, end.EndNumber)})
+					return
+				}
+			}
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		for _, end := range ends {
+			total, xCount, tenCount := 0, 0, 0
+			for _, arrow := range end.Arrows {
+				val, x, ten := calculateArrowValue(arrow)
+				total += val
+				xCount += x
+				tenCount += ten
+			}
+
+			var endScoreUUID string
+			err := tx.Get(&endScoreUUID, "SELECT uuid FROM practice_end_scores WHERE round_uuid = ? AND end_number = ?", roundID, end.EndNumber)
+			if err == sql.ErrNoRows {
+				endScoreUUID = uuid.New().String()
+				_, err = tx.Exec(`
+					INSERT INTO practice_end_scores (uuid, round_uuid, end_number, total_score_end, x_count_end, ten_count_end)
+					VALUES (?, ?, ?, ?, ?, ?)
+				`, endScoreUUID, roundID, end.EndNumber, total, xCount, tenCount)
+			} else if err == nil {
+				_, err = tx.Exec(`
+					UPDATE practice_end_scores SET total_score_end = ?, x_count_end = ?, ten_count_end = ? WHERE uuid = ?
+				`, total, xCount, tenCount, endScoreUUID)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save end score"})
+				return
+			}
+
+			if _, err := tx.Exec("DELETE FROM practice_arrow_scores WHERE end_score_uuid = ?", endScoreUUID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear old arrow scores"})
+				return
+			}
+			if len(end.Arrows) == 0 {
+				continue
+			}
+
+			valueStrings := make([]string, 0, len(end.Arrows))
+			arrowValues := make([]interface{}, 0, len(end.Arrows)*5)
+			for i, arrow := range end.Arrows {
+				val, _, _ := calculateArrowValue(arrow)
+				isX := 0
+				if arrow == "X" {
+					isX = 1
+				}
+				valueStrings = append(valueStrings, "(?, ?, ?, ?, ?)")
+				arrowValues = append(arrowValues, uuid.New().String(), endScoreUUID, i+1, val, isX)
+			}
+			_, err = tx.Exec(fmt.Sprintf("INSERT INTO practice_arrow_scores (uuid, end_score_uuid, arrow_number, score, is_x) VALUES %s",
+				strings.Join(valueStrings, ",")), arrowValues...)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save arrow scores"})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit scores"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Practice scores saved"})
+	}
+}
+
+// GetMyPracticeRounds lists the current archer's practice rounds with totals
+func GetMyPracticeRounds(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var rounds []models.PracticeRound
+		err := db.Select(&rounds, `
+			SELECT `+practiceRoundColumns+`
+			FROM practice_rounds r
+			LEFT JOIN practice_end_scores e ON e.round_uuid = r.uuid
+			WHERE r.archer_id = ?
+			GROUP BY r.uuid
+			ORDER BY r.practice_date DESC, r.created_at DESC
+		`, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch practice rounds"})
+			return
+		}
+
+		if rounds == nil {
+			rounds = []models.PracticeRound{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": rounds})
+	}
+}
+
+// GetMyPracticeRound returns one practice round of the current archer with its ends and arrows
+func GetMyPracticeRound(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roundID := c.Param("roundId")
+		userID, _ := c.Get("user_id")
+
+		var round models.PracticeRound
+		err := db.Get(&round, `
+			SELECT `+practiceRoundColumns+`
+			FROM practice_rounds r
+			LEFT JOIN practice_end_scores e ON e.round_uuid = r.uuid
+			WHERE r.uuid = ? AND r.archer_id = ?
+			GROUP BY r.uuid
+		`, roundID, userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Practice round not found"})
+			return
+		}
+
+		var arrows []struct {
+			EndNumber   int `db:"end_number"`
+			ArrowNumber int `db:"arrow_number"`
+			Score       int `db:"score"`
+			IsX         int `db:"is_x"`
+		}
+		db.Select(&arrows, `
+			SELECT e.end_number, a.arrow_number, a.score, a.is_x
+			FROM practice_end_scores e
+			JOIN practice_arrow_scores a ON a.end_score_uuid = e.uuid
+			WHERE e.round_uuid = ?
+			ORDER BY e.end_number ASC, a.arrow_number ASC
+		`, roundID)
+
+		ends := map[int][]string{}
+		for _, a := range arrows {
+			value := strconv.Itoa(a.Score)
+			if a.IsX == 1 {
+				value = "X"
+			} else if a.Score == 0 {
+				value = "M"
+			}
+			ends[a.EndNumber] = append(ends[a.EndNumber], value)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": round, "ends": ends})
+	}
+}
+
+// GetClubAthleteTraining returns an athlete's attendance rate and practice score trend per week
+func GetClubAthleteTraining(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermViewScores)
+		if !ok {
+			return
+		}
+		archerID := c.Param("archerId")
+
+		var joinedAt *time.Time
+		err := db.Get(&joinedAt, `
+			SELECT joined_at FROM club_members
+			WHERE club_id = ? AND archer_id = ? AND status IN ('active', 'overdue')
+			ORDER BY joined_at DESC LIMIT 1
+		`, clubID, archerID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Athlete is not an active member of this club"})
+			return
+		}
+
+		weeks, _ := strconv.Atoi(c.DefaultQuery("weeks", "12"))
+		if weeks <= 0 || weeks > 104 {
+			weeks = 12
+		}
+		// The club only sees the athlete's time as its member
+		from := time.Now().AddDate(0, 0, -7*weeks)
+		if joinedAt != nil && joinedAt.After(from) {
+			from = *joinedAt
+		}
+		since := from.Format("2006-01-02")
+
+		var attendance []struct {
+			Week     string `json:"week" db:"week"`
+			Sessions int    `json:"sessions" db:"sessions"`
+			Attended int    `json:"attended" db:"attended"`
+		}
+		err = db.Select(&attendance, `
+			SELECT DATE_FORMAT(s.session_date, '%x-W%v') as week,
+				COUNT(*) as sessions,
+				SUM(EXISTS(SELECT 1 FROM club_training_attendance ta WHERE ta.session_id = s.uuid AND ta.archer_id = ?)) as attended
+			FROM club_training_sessions s
+			WHERE s.club_id = ? AND s.session_date >= ? AND s.session_date <= CURDATE()
+			GROUP BY week
+			ORDER BY week ASC
+		`, archerID, clubID, since)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attendance", "details": err.Error()})
+			return
+		}
+
+		var practice []struct {
+			Week        string  `json:"week" db:"week"`
+			Rounds      int     `json:"rounds" db:"rounds"`
+			Arrows      int     `json:"arrows" db:"arrows"`
+			TotalScore  int     `json:"total_score" db:"total_score"`
+			AvgPerArrow float64 `json:"avg_per_arrow" db:"avg_per_arrow"`
+			XCount      int     `json:"x_count" db:"x_count"`
+		}
+		err = db.Select(&practice, `
+			SELECT DATE_FORMAT(r.practice_date, '%x-W%v') as week,
+				COUNT(DISTINCT r.uuid) as rounds,
+				COUNT(a.uuid) as arrows,
+				COALESCE(SUM(a.score), 0) as total_score,
+				COALESCE(AVG(a.score), 0) as avg_per_arrow,
+				COALESCE(SUM(a.is_x), 0) as x_count
+			FROM practice_rounds r
+			JOIN practice_end_scores e ON e.round_uuid = r.uuid
+			JOIN practice_arrow_scores a ON a.end_score_uuid = e.uuid
+			WHERE r.archer_id = ? AND r.club_id = ? AND r.practice_date >= ?
+			GROUP BY week
+			ORDER BY week ASC
+		`, archerID, clubID, since)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch practice scores", "details": err.Error()})
+			return
+		}
+
+		totalSessions, attended := 0, 0
+		for _, a := range attendance {
+			totalSessions += a.Sessions
+			attended += a.Attended
+		}
+		rate := 0.0
+		if totalSessions > 0 {
+			rate = float64(attended) / float64(totalSessions) * 100
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"archer_id":       archerID,
+			"archer_name":     archerName(db, archerID),
+			"weeks":           weeks,
+			"attendance_rate": rate,
+			"sessions":        totalSessions,
+			"attended":        attended,
+			"attendance":      attendance,
+			"practice":        practice,
+		})
+	}
+}
+
+// GetClubAttendanceSummary returns per-member attendance for the current club over a window
+func GetClubAttendanceSummary(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clubID, ok := requireClubPermission(db, c, clubPermViewScores)
+		if !ok {
+			return
+		}
+
+		weeks, _ := strconv.Atoi(c.DefaultQuery("weeks", "12"))
+		if weeks <= 0 || weeks > 104 {
+			weeks = 12
+		}
+		since := time.Now().AddDate(0, 0, -7*weeks).Format("2006-01-02")
+
+		var totalSessions int
+		db.Get(&totalSessions, "SELECT COUNT(*) FROM club_training_sessions WHERE club_id = ? AND session_date >= ? AND session_date <= CURDATE()", clubID, since)
+
+		var members []struct {
+			ArcherID   string     `json:"archer_id" db:"archer_id"`
+			ArcherName string     `json:"archer_name" db:"archer_name"`
+			Attended   int        `json:"attended" db:"attended"`
+			LastSeen   *time.Time `json:"last_seen" db:"last_seen"`
+		}
+		err := db.Select(&members, `
+			SELECT cm.archer_id, a.full_name as archer_name,
+				COUNT(ta.uuid) as attended, MAX(ta.checked_in_at) as last_seen
+			FROM club_members cm
+			JOIN archers a ON cm.archer_id = a.uuid
+			LEFT JOIN club_training_sessions s ON s.club_id = cm.club_id AND s.session_date >= ? AND s.session_date <= CURDATE()
+			LEFT JOIN club_training_attendance ta ON ta.session_id = s.uuid AND ta.archer_id = cm.archer_id
+			WHERE cm.club_id = ? AND cm.status IN ('active', 'overdue')
+			GROUP BY cm.archer_id, a.full_name
+			ORDER BY attended DESC, a.full_name ASC
+		`, since, clubID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attendance summary", "details": err.Error()})
+			return
+		}
+
+		result := make([]gin.H, 0, len(members))
+		for _, m := range members {
+			rate := 0.0
+			if totalSessions > 0 {
+				rate = float64(m.Attended) / float64(totalSessions) * 100
+			}
+			result = append(result, gin.H{
+				"archer_id":       m.ArcherID,
+				"archer_name":     m.ArcherName,
+				"attended":        m.Attended,
+				"attendance_rate": rate,
+				"last_seen":       m.LastSeen,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"sessions": totalSessions, "weeks": weeks, "data": result})
+	}
+}
+
+// GetMyClubTrainingSessions lists the upcoming training sessions of the current archer's club
+// along with whether the archer already checked in
+func GetMyClubTrainingSessions(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var sessions []struct {
+			models.TrainingSession
+			CheckedIn bool `json:"checked_in" db:"checked_in"`
+		}
+		err := db.Select(&sessions, `
+			SELECT s.*, EXISTS(SELECT 1 FROM club_training_attendance ta WHERE ta.session_id = s.uuid AND ta.archer_id = ?) as checked_in
+			FROM club_training_sessions s
+			JOIN club_members cm ON cm.club_id = s.club_id AND cm.archer_id = ? AND cm.status IN ('active', 'overdue')
+			WHERE s.session_date >= CURDATE() - INTERVAL 7 DAY
+			ORDER BY s.session_date ASC, s.start_time ASC
+		`, userID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch training sessions"})
+			return
+		}
+
+		if sessions == nil {
+			sessions = []struct {
+				models.TrainingSession
+				CheckedIn bool `json:"checked_in" db:"checked_in"`
+			}{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": sessions})
+	}
+}
@@ -0,0 +1,523 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	authTokenTwoFactor = "two_factor_challenge"
+
+	// twoFactorChallengeTTL is how long a login waits for its second factor
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts is how many wrong codes a challenge takes before it's burned
+	twoFactorMaxAttempts = 5
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+
+	totpIssuer = "Archery Hub"
+)
+
+// twoFactorAttempts counts wrong codes per challenge, keyed by the challenge token hash
+var twoFactorAttempts sync.Map
+
+// twoFactorEnabled reports whether logins of the identity need a second factor
+func twoFactorEnabled(identity *models.Identity) bool {
+	return identity.TOTPEnabledAt != nil && identity.TOTPSecret != nil
+}
+
+// startTwoFactorChallenge issues the token a login trades for a session once the second factor
+// checks out. It remembers which profile the login picked.
+func startTwoFactorChallenge(db *sqlx.DB, identity *models.Identity, profile models.IdentityProfile) (string, error) {
+	return issueAuthToken(db, profile.ProfileID, profile.UserType, authTokenTwoFactor, identity.Email, twoFactorChallengeTTL)
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes the way users type codes back
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// checkSecondFactor accepts a current authenticator code that wasn't used before, or an unused
+// recovery code, which is then spent
+func checkSecondFactor(q sqlx.Ext, identity *models.Identity, code, recoveryCode string) bool {
+	if identity.TOTPSecret == nil {
+		return false
+	}
+
+	if code != "" {
+		step, ok := utils.ValidateTOTP(*identity.TOTPSecret, code, time.Now())
+		if !ok {
+			return false
+		}
+		result, err := q.Exec(`
+			UPDATE identities SET totp_last_step = ?
+			WHERE uuid = ? AND (totp_last_step IS NULL OR totp_last_step < ?)
+		`, step, identity.UUID, step)
+		if err != nil {
+			return false
+		}
+		n, _ := result.RowsAffected()
+		return n == 1
+	}
+
+	if recoveryCode != "" {
+		result, err := q.Exec(`
+			UPDATE two_factor_recovery_codes SET used_at = NOW()
+			WHERE identity_id = ? AND code_hash = ? AND used_at IS NULL
+		`, identity.UUID, hashToken(normalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return false
+		}
+		n, _ := result.RowsAffected()
+		return n == 1
+	}
+
+	return false
+}
+
+// replaceRecoveryCodes drops the identity's recovery codes and returns a fresh set in plain text
+func replaceRecoveryCodes(tx *sqlx.Tx, identityID string) ([]string, error) {
+	if _, err := tx.Exec("DELETE FROM two_factor_recovery_codes WHERE identity_id = ?", identityID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := generateRandomToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		if _, err := tx.Exec(`
+			INSERT INTO two_factor_recovery_codes (uuid, identity_id, code_hash)
+			VALUES (?, ?, ?)
+		`, uuid.New().String(), identityID, hashToken(normalizeRecoveryCode(code))); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// requireEventTwoFactor stops score edits on events that demand 2FA from sessions that didn't
+// sign in with a second factor. It writes the error response and returns false.
+func requireEventTwoFactor(db sqlx.Queryer, c *gin.Context, eventID string) bool {
+	var required bool
+	sqlx.Get(db, &required, "SELECT COALESCE(require_two_factor, false) FROM events WHERE uuid = ? OR slug = ?", eventID, eventID)
+	if required && !c.GetBool("two_factor") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "This event requires two-factor authentication for scoring. Enable 2FA and sign in again.",
+			"code":  "two_factor_required",
+		})
+		return false
+	}
+	return true
+}
+
+// GetTwoFactorStatus reports whether 2FA is on for the current login
+func GetTwoFactorStatus(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+
+		var remaining int
+		db.Get(&remaining, "SELECT COUNT(*) FROM two_factor_recovery_codes WHERE identity_id = ? AND used_at IS NULL", identity.UUID)
+
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"enabled":                  twoFactorEnabled(identity),
+			"enabled_at":               identity.TOTPEnabledAt,
+			"recovery_codes_remaining": remaining,
+			"session_verified":         c.GetBool("two_factor"),
+		}})
+	}
+}
+
+// SetupTwoFactor creates a new authenticator secret and returns it with a QR code to scan. 2FA
+// stays off until EnableTwoFactor confirms a code from the app.
+func SetupTwoFactor(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+		if twoFactorEnabled(identity) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+		if _, err := db.Exec("UPDATE identities SET totp_secret = ?, totp_last_step = NULL, updated_at = NOW() WHERE uuid = ?", secret, identity.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+			return
+		}
+
+		uri := utils.TOTPProvisioningURI(totpIssuer, identity.Email, secret)
+		png, err := utils.GenerateQRCode(uri, 256)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"secret":      secret,
+			"otpauth_uri": uri,
+			"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		}})
+	}
+}
+
+// EnableTwoFactor turns 2FA on after the first code from the app checks out, and returns the
+// recovery codes. They are shown only this once.
+func EnableTwoFactor(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TwoFactorCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+		if twoFactorEnabled(identity) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		if identity.TOTPSecret == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Start the setup first"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if !checkSecondFactor(tx, identity, req.Code, "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code", "code": "invalid_two_factor_code"})
+			return
+		}
+		if _, err := tx.Exec("UPDATE identities SET totp_enabled_at = NOW(), updated_at = NOW() WHERE uuid = ?", identity.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+			return
+		}
+		codes, err := replaceRecoveryCodes(tx, identity.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+			return
+		}
+
+		// The session that just proved the code counts as verified from its next refresh on
+		if sessionID := c.GetString("session_id"); sessionID != "" {
+			tx.Exec("UPDATE auth_sessions SET two_factor = true WHERE uuid = ?", sessionID)
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit two-factor authentication"})
+			return
+		}
+
+		utils.LogActivity(db, c.GetString("user_id"), "", "two_factor_enabled", c.GetString("user_type"), identity.UUID,
+			"Two-factor authentication enabled", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe.",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// DisableTwoFactor turns 2FA off; it takes the password and a current second factor
+func DisableTwoFactor(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TwoFactorDisableRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+		if !twoFactorEnabled(identity) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+		if identity.Password == nil || *identity.Password != req.Password {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Password saat ini salah"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if !checkSecondFactor(tx, identity, req.Code, req.RecoveryCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code", "code": "invalid_two_factor_code"})
+			return
+		}
+		if _, err := tx.Exec(`
+			UPDATE identities SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+			WHERE uuid = ?
+		`, identity.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM two_factor_recovery_codes WHERE identity_id = ?", identity.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit two-factor authentication"})
+			return
+		}
+
+		utils.LogActivity(db, c.GetString("user_id"), "", "two_factor_disabled", c.GetString("user_type"), identity.UUID,
+			"Two-factor authentication disabled", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, e.g. after most were used up
+func RegenerateRecoveryCodes(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TwoFactorCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		identity, ok := currentIdentity(db, c)
+		if !ok {
+			return
+		}
+		if !twoFactorEnabled(identity) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		if !checkSecondFactor(tx, identity, req.Code, "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code", "code": "invalid_two_factor_code"})
+			return
+		}
+		codes, err := replaceRecoveryCodes(tx, identity.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit recovery codes"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
+
+// VerifyTwoFactorChallenge finishes a login that stopped at the second factor and starts the
+// session. A challenge survives a few wrong codes before the user has to log in again, and wrong
+// codes across challenges lock the identity out for a while.
+func VerifyTwoFactorChallenge(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TwoFactorChallengeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Code == "" && req.RecoveryCode == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		owner, err := consumeAuthToken(tx, req.ChallengeToken, authTokenTwoFactor)
+		if err == errAuthTokenInvalid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired; please log in again", "code": "invalid_challenge"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check challenge"})
+			return
+		}
+
+		identity, err := loadIdentity(tx, identityOf(tx, owner.UserID))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login expired; please log in again", "code": "invalid_challenge"})
+			return
+		}
+
+		if !checkTwoFactorThrottle(c, identity.UUID) {
+			return
+		}
+
+		challengeKey := hashToken(req.ChallengeToken)
+		if !checkSecondFactor(tx, identity, req.Code, req.RecoveryCode) {
+			tx.Rollback()
+			attempts := 1
+			if n, ok := twoFactorAttempts.Load(challengeKey); ok {
+				attempts = n.(int) + 1
+			}
+			recordTwoFactorFailure(db, c, identity)
+			if attempts >= twoFactorMaxAttempts {
+				twoFactorAttempts.Delete(challengeKey)
+				db.Exec("UPDATE auth_tokens SET used_at = NOW() WHERE token_hash = ?", challengeKey)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Too many wrong codes; please log in again", "code": "invalid_challenge"})
+				return
+			}
+			twoFactorAttempts.Store(challengeKey, attempts)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code", "code": "invalid_two_factor_code"})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit login"})
+			return
+		}
+		twoFactorAttempts.Delete(challengeKey)
+		clearTwoFactorFailures(identity.UUID)
+
+		profiles, err := identityProfiles(db, identity.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profiles"})
+			return
+		}
+		var profile *models.IdentityProfile
+		for i := range profiles {
+			if profiles[i].ProfileID == owner.UserID && profiles[i].Status == "active" {
+				profile = &profiles[i]
+				break
+			}
+		}
+		if profile == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is not active", "code": "account_inactive"})
+			return
+		}
+
+		session := profileSession(identity, *profile)
+		session.TwoFactor = true
+		tokens, err := issueSession(db, c, session)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		touchProfile(db, profile.UUID)
+		recordLoginSuccess(db, c, identity, profile.ProfileID, profile.UserType, "two_factor")
+
+		method := "authenticator code"
+		if req.Code == "" {
+			method = "recovery code"
+		}
+		utils.LogActivity(db, profile.ProfileID, "", "user_logged_in", profile.UserType, profile.ProfileID,
+			"User logged in with "+method, c.ClientIP(), c.Request.UserAgent())
+
+		username := ""
+		if profile.Username != nil {
+			username = *profile.Username
+		}
+
+		c.JSON(http.StatusOK, AuthResponse{
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    int(accessTokenTTL.Seconds()),
+			User: gin.H{
+				"uuid":       profile.ProfileID,
+				"id":         profile.Code,
+				"username":   username,
+				"full_name":  profile.Name,
+				"email":      identity.Email,
+				"avatar_url": session.Avatar,
+				"role":       profile.UserType,
+				"user_type":  profile.UserType,
+			},
+			Profiles: profiles,
+		})
+	}
+}
+
+// SetEventTwoFactorRequirement lets the organizer require 2FA from everyone who edits the event's
+// scores
+func SetEventTwoFactorRequirement(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, ok := loadManagedEvent(db, c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		var req models.EventTwoFactorRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		before := rowSnapshot(db, "events", eventID)
+		if _, err := db.Exec("UPDATE events SET require_two_factor = ?, updated_at = NOW() WHERE uuid = ?", *req.RequireTwoFactor, eventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+			return
+		}
+
+		state := "disabled"
+		if *req.RequireTwoFactor {
+			state = "enabled"
+		}
+		logEventActivity(db, c, eventID, "event_two_factor_"+state, "event", eventID,
+			"Two-factor requirement for scoring "+state, before, rowSnapshot(db, "events", eventID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Event updated", "require_two_factor": *req.RequireTwoFactor})
+	}
+}
+
+// twoFactorChallengeBody is the response of a login that still needs its second factor
+func twoFactorChallengeBody(challenge string) gin.H {
+	return gin.H{
+		"two_factor_required": true,
+		"challenge_token":     challenge,
+		"expires_in":          int(twoFactorChallengeTTL.Seconds()),
+	}
+}
+
+// loadIdentity fetches an identity by id
+func loadIdentity(q sqlx.Queryer, identityID string) (*models.Identity, error) {
+	var identity models.Identity
+	if err := sqlx.Get(q, &identity, "SELECT * FROM identities WHERE uuid = ?", identityID); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
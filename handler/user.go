@@ -2,7 +2,9 @@ package handler
 
 import (
 	"archeryhub-api/models"
+	"database/sql"
 	"net/http"
+	"strings"
 	"time"
 
 	"archeryhub-api/utils"
@@ -59,6 +61,12 @@ func UpdatePassword(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
+		// The identity holds the login password shared by all its profiles
+		identityID := identityOf(db, userID.(string))
+		if identityID != "" {
+			db.Get(&user, "SELECT password, CASE WHEN password IS NOT NULL AND password != '' THEN true ELSE false END as has_password FROM identities WHERE uuid = ?", identityID)
+		}
+
 		// If user has a password, verify the current password (plain text comparison)
 		if user.HasPassword && user.Password != nil {
 			if req.CurrentPassword == "" {
@@ -79,6 +87,12 @@ func UpdatePassword(db *sqlx.DB) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 			return
 		}
+		if identityID != "" {
+			db.Exec("UPDATE identities SET password = ?, updated_at = NOW() WHERE uuid = ?", req.NewPassword, identityID)
+		}
+
+		// Anyone signed in with the old password is logged out; this device stays signed in
+		revokeOtherUserSessions(db, userID.(string), c.GetString("session_id"), "password_changed")
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":      "Password berhasil diperbarui",
@@ -194,9 +208,29 @@ func UpdateUserProfile(db *sqlx.DB) gin.HandlerFunc {
 				query += ", phone = ?"
 				args = append(args, *req.Phone)
 			}
+			// Replacing an existing address has to be approved from that address first
+			emailChangePending := false
 			if req.Email != nil {
-				query += ", email = ?"
-				args = append(args, *req.Email)
+				var current struct {
+					Email string `db:"email"`
+					Name  string `db:"name"`
+				}
+				db.Get(&current, "SELECT COALESCE(email, '') as email, COALESCE(name, '') as name FROM clubs WHERE uuid = ?", userID)
+				if current.Email != "" && !strings.EqualFold(*req.Email, current.Email) {
+					if *req.Email == "" || identityEmailTaken(db, userID.(string), *req.Email) {
+						c.JSON(http.StatusConflict, gin.H{"error": "Email is already used by another account"})
+						return
+					}
+					if err := requestEmailChange(db, userID.(string), "club", current.Email, *req.Email, current.Name); err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request email change: " + err.Error()})
+						return
+					}
+					emailChangePending = true
+				} else {
+					// A new address has to be verified again
+					query += ", email_verified_at = IF(email <=> ?, email_verified_at, NULL), email = ?"
+					args = append(args, *req.Email, *req.Email)
+				}
 			}
 			if req.Website != nil {
 				query += ", website = ?"
@@ -240,10 +274,14 @@ func UpdateUserProfile(db *sqlx.DB) gin.HandlerFunc {
 				}
 			}
 
-			if len(args) == 0 {
+			if len(args) == 0 && !emailChangePending {
 				c.JSON(http.StatusOK, gin.H{"message": "No changes to save"})
 				return
 			}
+			if req.Email != nil && !emailChangePending && *req.Email != "" && identityEmailTaken(db, userID.(string), *req.Email) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Email is already used by another account"})
+				return
+			}
 
 			query += " WHERE uuid = ?"
 			args = append(args, userID)
@@ -253,8 +291,14 @@ func UpdateUserProfile(db *sqlx.DB) gin.HandlerFunc {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update club profile: " + err.Error()})
 				return
 			}
+			if req.Email != nil && !emailChangePending {
+				if err := syncIdentityEmail(db, userID.(string), *req.Email); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update login email: " + err.Error()})
+					return
+				}
+			}
 
-			c.JSON(http.StatusOK, gin.H{"message": "Profil klub berhasil diperbarui"})
+			c.JSON(http.StatusOK, gin.H{"message": "Profil klub berhasil diperbarui", "email_change_pending": emailChangePending})
 			return
 		}
 
@@ -284,9 +328,29 @@ func UpdateUserProfile(db *sqlx.DB) gin.HandlerFunc {
 				query += ", phone = ?"
 				args = append(args, *req.Phone)
 			}
+			// Replacing an existing address has to be approved from that address first
+			emailChangePending := false
 			if req.Email != nil {
-				query += ", email = ?"
-				args = append(args, *req.Email)
+				var current struct {
+					Email string `db:"email"`
+					Name  string `db:"name"`
+				}
+				db.Get(&current, "SELECT COALESCE(email, '') as email, COALESCE(store_name, '') as name FROM sellers WHERE uuid = ?", userID)
+				if current.Email != "" && !strings.EqualFold(*req.Email, current.Email) {
+					if *req.Email == "" || identityEmailTaken(db, userID.(string), *req.Email) {
+						c.JSON(http.StatusConflict, gin.H{"error": "Email is already used by another account"})
+						return
+					}
+					if err := requestEmailChange(db, userID.(string), "seller", current.Email, *req.Email, current.Name); err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request email change: " + err.Error()})
+						return
+					}
+					emailChangePending = true
+				} else {
+					// A new address has to be verified again
+					query += ", email_verified_at = IF(email <=> ?, email_verified_at, NULL), email = ?"
+					args = append(args, *req.Email, *req.Email)
+				}
 			}
 			if req.Address != nil {
 				query += ", address = ?"
@@ -309,10 +373,14 @@ func UpdateUserProfile(db *sqlx.DB) gin.HandlerFunc {
 				args = append(args, utils.ExtractFilename(*req.BannerURL))
 			}
 
-			if len(args) == 0 {
+			if len(args) == 0 && !emailChangePending {
 				c.JSON(http.StatusOK, gin.H{"message": "No changes to save"})
 				return
 			}
+			if req.Email != nil && !emailChangePending && *req.Email != "" && identityEmailTaken(db, userID.(string), *req.Email) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Email is already used by another account"})
+				return
+			}
 
 			query += " WHERE uuid = ?"
 			args = append(args, userID)
@@ -322,8 +390,14 @@ func UpdateUserProfile(db *sqlx.DB) gin.HandlerFunc {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update seller profile: " + err.Error()})
 				return
 			}
+			if req.Email != nil && !emailChangePending {
+				if err := syncIdentityEmail(db, userID.(string), *req.Email); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update login email: " + err.Error()})
+					return
+				}
+			}
 
-			c.JSON(http.StatusOK, gin.H{"message": "Profil toko berhasil diperbarui"})
+			c.JSON(http.StatusOK, gin.H{"message": "Profil toko berhasil diperbarui", "email_change_pending": emailChangePending})
 			return
 		}
 
@@ -366,9 +440,16 @@ func UpdateUserProfile(db *sqlx.DB) gin.HandlerFunc {
 			query += ", bio = ?"
 			args = append(args, *req.Bio)
 		}
+		// club_id follows the club membership, which the archer changes by joining or transferring
 		if req.ClubID != nil {
-			query += ", club_id = ?"
-			args = append(args, *req.ClubID)
+			var current sql.NullString
+			if userType == "archer" {
+				db.Get(&current, "SELECT club_id FROM archers WHERE uuid = ?", userID)
+			}
+			if userType != "archer" || current.String != *req.ClubID {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Club changes must go through a membership or transfer request"})
+				return
+			}
 		}
 		if req.City != nil {
 			query += ", city = ?"
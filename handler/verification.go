@@ -0,0 +1,471 @@
+package handler
+
+import (
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
+	"database/sql"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// verifiableTables maps the account types that can be verified to their tables
+var verifiableTables = map[string]string{
+	"club":         "clubs",
+	"organization": "organizations",
+}
+
+// isVerified reports whether a verification status earns the verified badge
+func isVerified(status *string) bool {
+	return status != nil && *status == models.VerificationVerified
+}
+
+// organizerVerificationRequired reports whether events of unverified organizations and clubs
+// are kept from going live
+func organizerVerificationRequired() bool {
+	return os.Getenv("REQUIRE_ORGANIZER_VERIFICATION") == "true"
+}
+
+// organizerMayPublish reports whether events of the organizer may go live: always when
+// verification isn't required, otherwise only for verified organizers
+func organizerMayPublish(q sqlx.Queryer, organizerID string) bool {
+	return !organizerVerificationRequired() || organizerVerified(q, organizerID)
+}
+
+// isLiveEventStatus reports whether an event in the status is shown to the public
+func isLiveEventStatus(status string) bool {
+	return status == "published" || status == "ongoing"
+}
+
+// organizerVerified reports whether the organizer is a verified organization or club
+func organizerVerified(q sqlx.Queryer, organizerID string) bool {
+	var verified bool
+	sqlx.Get(q, &verified, `
+		SELECT EXISTS(
+			SELECT 1 FROM organizations WHERE uuid = ? AND verification_status = 'verified'
+			UNION ALL
+			SELECT 1 FROM clubs WHERE uuid = ? AND verification_status = 'verified'
+		)
+	`, organizerID, organizerID)
+	return verified
+}
+
+// openSubmission returns the submission of the account that is waiting for or under review
+func openSubmission(q sqlx.Queryer, entityType, entityID string) (*models.VerificationSubmission, error) {
+	var s models.VerificationSubmission
+	err := sqlx.Get(q, &s, `
+		SELECT * FROM verification_submissions
+		WHERE entity_type = ? AND entity_id = ? AND status IN ('submitted', 'under_review')
+		ORDER BY submitted_at DESC
+		LIMIT 1
+	`, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// verificationHistory lists the account's submissions, newest first, with their documents and
+// reviewer comments
+func verificationHistory(db *sqlx.DB, entityType, entityID string) ([]models.VerificationSubmission, error) {
+	var submissions []models.VerificationSubmission
+	err := db.Select(&submissions, `
+		SELECT * FROM verification_submissions
+		WHERE entity_type = ? AND entity_id = ?
+		ORDER BY submitted_at DESC
+	`, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range submissions {
+		s := &submissions[i]
+		db.Select(&s.Documents, "SELECT * FROM verification_documents WHERE submission_id = ? ORDER BY created_at ASC", s.UUID)
+		db.Select(&s.Reviews, "SELECT * FROM verification_reviews WHERE submission_id = ? ORDER BY created_at ASC", s.UUID)
+		if s.Documents == nil {
+			s.Documents = []models.VerificationDocument{}
+		}
+		if s.Reviews == nil {
+			s.Reviews = []models.VerificationReview{}
+		}
+		for j := range s.Documents {
+			s.Documents[j].URL = utils.MaskMediaURL(s.Documents[j].URL)
+		}
+	}
+
+	if submissions == nil {
+		submissions = []models.VerificationSubmission{}
+	}
+	return submissions, nil
+}
+
+// verificationStatus reads the account's current verification status ("" when it never submitted)
+func verificationStatus(q sqlx.Queryer, entityType, entityID string) (string, error) {
+	var status string
+	err := sqlx.Get(q, &status, "SELECT COALESCE(verification_status, '') FROM "+verifiableTables[entityType]+" WHERE uuid = ?", entityID)
+	return status, err
+}
+
+// GetMyVerification returns the caller's verification status and submission history
+func GetMyVerification(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		userType := c.GetString("user_type")
+		if _, ok := verifiableTables[userType]; !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only organizations and clubs can be verified"})
+			return
+		}
+
+		status, err := verificationStatus(db, userType, userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+
+		submissions, err := verificationHistory(db, userType, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch verification"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"verification_status": status,
+			"is_verified":         status == models.VerificationVerified,
+			"submissions":         submissions,
+		}})
+	}
+}
+
+// SubmitVerification sends the caller's documents, uploaded through /media/upload, in for review
+func SubmitVerification(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		userType := c.GetString("user_type")
+		table, ok := verifiableTables[userType]
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only organizations and clubs can be verified"})
+			return
+		}
+
+		var req models.SubmitVerificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		status, err := verificationStatus(db, userType, userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		switch status {
+		case models.VerificationVerified:
+			c.JSON(http.StatusConflict, gin.H{"error": "Account is already verified"})
+			return
+		case models.VerificationSubmitted, models.VerificationUnderReview:
+			c.JSON(http.StatusConflict, gin.H{"error": "A verification request is already waiting for review"})
+			return
+		}
+
+		// Documents must be the caller's own uploads, and PDFs or images
+		mediaIDs := make([]string, len(req.Documents))
+		for i, d := range req.Documents {
+			mediaIDs[i] = d.MediaID
+		}
+		query, args, err := sqlx.In("SELECT uuid, url, mime_type FROM media WHERE uuid IN (?) AND user_id = ?", mediaIDs, userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid documents"})
+			return
+		}
+		var files []struct {
+			UUID     string  `db:"uuid"`
+			URL      string  `db:"url"`
+			MimeType *string `db:"mime_type"`
+		}
+		if err := db.Select(&files, db.Rebind(query), args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+			return
+		}
+		byID := make(map[string]int, len(files))
+		for i, f := range files {
+			if f.MimeType == nil || (*f.MimeType != "application/pdf" && !strings.HasPrefix(*f.MimeType, "image/")) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Documents must be PDF files or images", "media_id": f.UUID})
+				return
+			}
+			byID[f.UUID] = i
+		}
+		for _, id := range mediaIDs {
+			if _, ok := byID[id]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Document not found among your uploads", "media_id": id})
+				return
+			}
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+			return
+		}
+		defer tx.Rollback()
+
+		// Lock the account row so two submissions racing past the check above can't both land
+		var locked string
+		if err := tx.Get(&locked, "SELECT COALESCE(verification_status, '') FROM "+table+" WHERE uuid = ? FOR UPDATE", userID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		switch locked {
+		case models.VerificationVerified:
+			c.JSON(http.StatusConflict, gin.H{"error": "Account is already verified"})
+			return
+		case models.VerificationSubmitted, models.VerificationUnderReview:
+			c.JSON(http.StatusConflict, gin.H{"error": "A verification request is already waiting for review"})
+			return
+		}
+
+		var note interface{}
+		if req.Note != "" {
+			note = req.Note
+		}
+		submissionID := uuid.New().String()
+		if _, err := tx.Exec(`
+			INSERT INTO verification_submissions (uuid, entity_type, entity_id, status, note, submitted_at)
+			VALUES (?, ?, ?, ?, ?, NOW())
+		`, submissionID, userType, userID, models.VerificationSubmitted, note); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit verification"})
+			return
+		}
+		for _, d := range req.Documents {
+			f := files[byID[d.MediaID]]
+			if _, err := tx.Exec(`
+				INSERT INTO verification_documents (uuid, submission_id, document_type, media_id, url, mime_type)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, uuid.New().String(), submissionID, d.DocumentType, f.UUID, f.URL, f.MimeType); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach documents"})
+				return
+			}
+		}
+		if _, err := tx.Exec("UPDATE "+table+" SET verification_status = ?, updated_at = NOW() WHERE uuid = ?", models.VerificationSubmitted, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update verification status"})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit verification"})
+			return
+		}
+
+		utils.LogActivity(db, userID, "", "verification_submitted", userType, userID,
+			"Submitted verification documents", c.ClientIP(), c.Request.UserAgent())
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Verification submitted", "submission_id": submissionID, "verification_status": models.VerificationSubmitted})
+	}
+}
+
+// GetAdminVerifications lists verification submissions, by default those still waiting for a
+// decision, oldest first
+func GetAdminVerifications(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entityType := c.Query("type")
+		if _, ok := verifiableTables[entityType]; entityType != "" && !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be club or organization"})
+			return
+		}
+
+		where := "WHERE s.status IN ('submitted', 'under_review')"
+		var args []interface{}
+		if status := c.Query("status"); status != "" {
+			where = "WHERE s.status = ?"
+			args = append(args, status)
+		}
+		if entityType != "" {
+			where += " AND s.entity_type = ?"
+			args = append(args, entityType)
+		}
+
+		var list []models.PendingVerification
+		err := db.Select(&list, `
+			SELECT s.uuid as submission_id, s.entity_type, s.entity_id as uuid,
+			       COALESCE(o.name, cl.name, '') as name, COALESCE(o.slug, cl.slug) as slug,
+			       COALESCE(o.email, cl.email) as email, COALESCE(o.city, cl.city) as city,
+			       s.status, s.submitted_at,
+			       (SELECT COUNT(*) FROM verification_documents d WHERE d.submission_id = s.uuid) as document_count
+			FROM verification_submissions s
+			LEFT JOIN organizations o ON s.entity_type = 'organization' AND o.uuid = s.entity_id
+			LEFT JOIN clubs cl ON s.entity_type = 'club' AND cl.uuid = s.entity_id
+			`+where+`
+			ORDER BY s.submitted_at ASC
+		`, args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch verifications"})
+			return
+		}
+
+		if list == nil {
+			list = []models.PendingVerification{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": list})
+	}
+}
+
+// GetAdminVerificationDetail returns a club's or organization's verification status with every
+// submission, its documents and the reviewer comments
+func GetAdminVerificationDetail(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entityType := c.Param("type")
+		entityID := c.Param("entityId")
+		if _, ok := verifiableTables[entityType]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be club or organization"})
+			return
+		}
+
+		status, err := verificationStatus(db, entityType, entityID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+
+		submissions, err := verificationHistory(db, entityType, entityID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch verification"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"entity_type":         entityType,
+			"entity_id":           entityID,
+			"verification_status": status,
+			"submissions":         submissions,
+		}})
+	}
+}
+
+// moveVerification records a reviewer's step on the account's open submission and mirrors the
+// new status on the account. It writes the error response and returns false.
+func moveVerification(db *sqlx.DB, c *gin.Context, entityType, entityID, status, note string) (*models.VerificationSubmission, bool) {
+	table, ok := verifiableTables[entityType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be club or organization"})
+		return nil, false
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return nil, false
+	}
+	defer tx.Rollback()
+
+	submission, err := openSubmission(tx, entityType, entityID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"error": "No verification request is waiting for review"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch verification request"})
+		return nil, false
+	}
+	if status == models.VerificationUnderReview && submission.Status == models.VerificationUnderReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "Verification request is already under review"})
+		return nil, false
+	}
+
+	var comment interface{}
+	if note != "" {
+		comment = note
+	}
+	reviewerID := c.GetString("user_id")
+	reviewedAt := "NULL"
+	if status != models.VerificationUnderReview {
+		reviewedAt = "NOW()"
+	}
+	if _, err := tx.Exec(`
+		UPDATE verification_submissions
+		SET status = ?, reviewer_id = ?, review_comment = COALESCE(?, review_comment), reviewed_at = `+reviewedAt+`
+		WHERE uuid = ?
+	`, status, reviewerID, comment, submission.UUID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update verification request"})
+		return nil, false
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO verification_reviews (uuid, submission_id, entity_type, entity_id, status, note, reviewed_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), submission.UUID, entityType, entityID, status, comment, reviewerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review"})
+		return nil, false
+	}
+	if _, err := tx.Exec("UPDATE "+table+" SET verification_status = ?, updated_at = NOW() WHERE uuid = ?", status, entityID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update verification status"})
+		return nil, false
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit verification"})
+		return nil, false
+	}
+	return submission, true
+}
+
+// StartVerificationReview takes a submitted verification request into review
+func StartVerificationReview(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AdminVerificationReviewRequest
+		c.ShouldBindJSON(&req)
+
+		entityType := c.Param("type")
+		entityID := c.Param("entityId")
+		submission, ok := moveVerification(db, c, entityType, entityID, models.VerificationUnderReview, req.Note)
+		if !ok {
+			return
+		}
+
+		auditAdmin(db, c, "verification_under_review", entityType, entityID, req.Note, gin.H{"submission_id": submission.UUID})
+
+		message := "Dokumen verifikasi Anda sedang ditinjau oleh tim Archery Hub."
+		if req.Note != "" {
+			message += " Catatan: " + req.Note
+		}
+		utils.Notify(db, entityID, entityType, "info", "Verifikasi sedang ditinjau", message, "/settings/verification")
+
+		c.JSON(http.StatusOK, gin.H{"message": "Verification under review", "verification_status": models.VerificationUnderReview})
+	}
+}
+
+// DecideVerification verifies or rejects the open verification request of a club or organization
+func DecideVerification(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.AdminVerificationDecision
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Status == models.VerificationRejected && strings.TrimSpace(req.Note) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A note is required when rejecting"})
+			return
+		}
+
+		entityType := c.Param("type")
+		entityID := c.Param("entityId")
+		submission, ok := moveVerification(db, c, entityType, entityID, req.Status, req.Note)
+		if !ok {
+			return
+		}
+
+		auditAdmin(db, c, "verification_"+req.Status, entityType, entityID, req.Note, gin.H{"submission_id": submission.UUID})
+
+		if req.Status == models.VerificationVerified {
+			utils.Notify(db, entityID, entityType, "success", "Akun terverifikasi",
+				"Selamat! Akun Anda telah diverifikasi oleh tim Archery Hub.", "/settings/verification")
+		} else {
+			utils.Notify(db, entityID, entityType, "danger", "Verifikasi ditolak",
+				"Verifikasi akun Anda ditolak: "+req.Note+". Anda dapat mengirim ulang dokumen yang diperbaiki.", "/settings/verification")
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Verification updated", "verification_status": req.Status})
+	}
+}
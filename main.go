@@ -4,10 +4,14 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"archeryhub-api/database"
 	"archeryhub-api/handler"
 	"archeryhub-api/middleware"
+	"archeryhub-api/models"
+	"archeryhub-api/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -75,9 +79,42 @@ func main() {
 	defer db.Close()
 	logger.Info("Database connection established successfully")
 
+	// Reconcile payments stuck in pending against Tripay
+	handler.StartPaymentReconciler(db, 15*time.Minute)
+	handler.StartClubDuesScheduler(db, 6*time.Hour)
+	handler.StartRankingScheduler(db, 12*time.Hour)
+	handler.StartAccountDeletionScheduler(db, time.Hour)
+
+	// Outgoing mail (SMTP, or an outbox during development)
+	utils.SetMailer(utils.NewMailerFromEnv(db))
+
+	// Login throttling counters; swap in a shared store when running several instances
+	utils.SetAttemptStore(utils.NewMemoryAttemptStore())
+
+	// Access tokens of revoked sessions are rejected by the auth middlewares
+	middleware.SetSessionCheck(handler.SessionActive(db))
+	middleware.SetAPIKeyLookup(handler.LookupAPIKey(db))
+
+	// Link accounts created before identities existed to one login per email
+	handler.MigrateIdentities(db)
+	handler.PromoteAdminsFromEnv(db)
+
 	// Initialize Gin router
 	r := gin.Default()
 
+	// Only trust X-Forwarded-For from our own proxies, so ClientIP can't be spoofed to dodge
+	// the login throttle. TRUSTED_PROXIES is a comma-separated list of IPs or CIDRs; when unset
+	// the client IP is the connection's remote address.
+	var trustedProxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			trustedProxies = append(trustedProxies, p)
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		logger.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
@@ -163,7 +200,27 @@ func main() {
 			// Traditional auth
 			auth.POST("/register", handler.Register(db))
 			auth.POST("/login", handler.Login(db))
-			auth.POST("/logout", handler.Logout())
+			auth.POST("/logout", middleware.OptionalAuthMiddleware(), handler.Logout(db))
+			auth.POST("/refresh", handler.RefreshSession(db))
+			auth.POST("/forgot-password", handler.ForgotPassword(db))
+			auth.POST("/reset-password", handler.ResetPassword(db))
+			auth.POST("/verify-email", handler.VerifyEmail(db))
+			auth.POST("/resend-verification", handler.ResendVerificationEmail(db))
+			auth.POST("/confirm-email-change", handler.ConfirmEmailChange(db))
+			auth.POST("/logout-all", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.LogoutEverywhere(db))
+			auth.GET("/sessions", middleware.AuthMiddleware(), handler.GetMySessions(db))
+			auth.DELETE("/sessions/:sessionId", middleware.AuthMiddleware(), handler.RevokeMySession(db))
+			auth.GET("/login-history", middleware.AuthMiddleware(), handler.GetMyLoginHistory(db))
+			auth.GET("/profiles", middleware.AuthMiddleware(), handler.GetMyProfiles(db))
+			auth.POST("/profiles", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.CreateLinkedProfile(db))
+			auth.POST("/profiles/link", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.LinkExistingProfile(db))
+			auth.POST("/switch-profile", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.SwitchProfile(db))
+			auth.POST("/2fa/verify", handler.VerifyTwoFactorChallenge(db))
+			auth.GET("/2fa", middleware.AuthMiddleware(), handler.GetTwoFactorStatus(db))
+			auth.POST("/2fa/setup", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.SetupTwoFactor(db))
+			auth.POST("/2fa/enable", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.EnableTwoFactor(db))
+			auth.POST("/2fa/disable", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.DisableTwoFactor(db))
+			auth.POST("/2fa/recovery-codes", middleware.AuthMiddleware(), middleware.ForbidImpersonation(), handler.RegenerateRecoveryCodes(db))
 			auth.GET("/check-name", handler.CheckNameExists(db))
 
 			// Google OAuth
@@ -180,8 +237,14 @@ func main() {
 		{
 
 			user.GET("/profile", handler.GetUserProfile(db))
-			user.PUT("/profile", handler.UpdateUserProfile(db)) // Generic profile update handler
-			user.PUT("/password", handler.UpdatePassword(db))
+			user.PUT("/profile", middleware.ForbidImpersonation(), handler.UpdateUserProfile(db)) // Generic profile update handler
+			user.PUT("/password", middleware.ForbidImpersonation(), handler.UpdatePassword(db))
+
+			// Personal data export and account deletion
+			user.GET("/data-export", middleware.ForbidImpersonation(), handler.ExportMyData(db))
+			user.GET("/deletion", handler.GetMyDeletionRequest(db))
+			user.POST("/deletion", middleware.ForbidImpersonation(), handler.RequestAccountDeletion(db))
+			user.DELETE("/deletion", middleware.ForbidImpersonation(), handler.CancelAccountDeletion(db))
 		}
 
 		// Event routes
@@ -192,21 +255,24 @@ func main() {
 			events.GET("", handler.GetEvents(db))
 			events.GET("/:id", handler.GetEventByID(db))
 			events.GET("/:id/categories", handler.GetEventEvents(db))
-			events.GET("/:id/participants", handler.GetEventParticipants(db))
+			events.GET("/:id/participants", middleware.OptionalAuthOrAPIKeyMiddleware(models.ScopeParticipantsRead), handler.GetEventParticipants(db))
 			events.GET("/:id/participants/:participantId", handler.GetEventParticipant(db))
 			events.PUT("/:id/participants/:participantId", middleware.AuthMiddleware(), handler.UpdateEventParticipant(db))
 			events.DELETE("/:id/participants/:participantId", middleware.AuthMiddleware(), handler.DeleteEventParticipant(db))
 			events.DELETE("/participants/:participantId", middleware.AuthMiddleware(), handler.CancelParticipantRegistration(db))
+			events.PUT("/participants/:participantId/payment-proof", middleware.AuthMiddleware(), handler.SubmitPaymentProof(db))
 			events.GET("/:id/teams", handler.GetEventTeams(db))
 			events.GET("/:id/images", handler.GetEventImages(db))
 			events.GET("/:id/schedule", handler.GetEventSchedule(db))
 			events.GET("/:id/target-names", handler.GetTargetNames(db))
 			events.GET("/:id/payment-methods", handler.GetEventPaymentMethods(db))
+			events.GET("/:id/refund-policy", handler.GetEventRefundPolicy(db))
+			events.GET("/:id/price-quote", handler.GetEntryPriceQuote(db))
 			events.POST("/participants/reregister", handler.ReregisterParticipant(db))
 
 			// Public Results endpoints
-			events.GET("/:id/results/qualification", handler.GetPublicQualificationResults(db))
-			events.GET("/:id/results/elimination", handler.GetPublicEliminationResults(db))
+			events.GET("/:id/results/qualification", middleware.OptionalAuthOrAPIKeyMiddleware(models.ScopeResultsRead), handler.GetPublicQualificationResults(db))
+			events.GET("/:id/results/elimination", middleware.OptionalAuthOrAPIKeyMiddleware(models.ScopeResultsRead), handler.GetPublicEliminationResults(db))
 
 			// Protected Event routes (require authentication)
 			protected := events.Group("")
@@ -224,9 +290,25 @@ func main() {
 				protected.POST("/:id/participants", handler.RegisterParticipant(db))
 				protected.PUT("/:id/images", handler.UpdateEventImages(db))
 				protected.PUT("/:id/schedule", handler.UpdateEventSchedule(db))
+				protected.PUT("/:id/two-factor", handler.SetEventTwoFactorRequirement(db))
 				protected.POST("/:id/payment-methods", handler.CreateEventPaymentMethod(db))
 				protected.PUT("/:id/payment-methods/:methodId", handler.UpdateEventPaymentMethod(db))
 				protected.DELETE("/:id/payment-methods/:methodId", handler.DeleteEventPaymentMethod(db))
+				protected.POST("/:id/cancel", handler.CancelEvent(db))
+				protected.PUT("/:id/refund-policy", handler.UpdateEventRefundPolicy(db))
+				protected.GET("/:id/refunds", handler.GetEventRefunds(db))
+				protected.GET("/:id/settlement", handler.GetEventSettlement(db))
+				protected.GET("/:id/payment-proofs", handler.GetPaymentProofQueue(db))
+				protected.GET("/:id/payment-proofs/reviews", handler.GetPaymentProofReviews(db))
+				protected.POST("/:id/payment-proofs/review", handler.ReviewPaymentProofs(db))
+				protected.POST("/:id/payment-proofs/:participantId/review", handler.ReviewPaymentProofs(db))
+				protected.GET("/:id/pricing", handler.GetEventPricing(db))
+				protected.PUT("/:id/pricing", handler.UpdateEventPricing(db))
+				protected.GET("/:id/promo-codes", handler.GetPromoCodes(db))
+				protected.POST("/:id/promo-codes", handler.CreatePromoCode(db))
+				protected.PUT("/:id/promo-codes/:codeId", handler.UpdatePromoCode(db))
+				protected.DELETE("/:id/promo-codes/:codeId", handler.DeletePromoCode(db))
+				protected.GET("/:id/activity", handler.GetEventActivity(db))
 
 				// Qualification target assignments - nested under events/:id/qualification/sessions/:sessionId
 				protected.POST("/:id/qualification/sessions/:sessionId/assignments", handler.CreateBulkTargetAssignments(db))
@@ -261,9 +343,9 @@ func main() {
 			elimination.GET("/brackets/:bracketId/scores", handler.GetBracketScores(db))
 			elimination.PUT("/brackets/:bracketId/targets", middleware.AuthMiddleware(), handler.UpdateMatchTargets(db))
 			elimination.GET("/brackets/:bracketId/matches/:matchId", handler.GetMatch(db))
-			elimination.POST("/brackets/:bracketId/matches/:matchId/score", middleware.AuthMiddleware(), handler.UpdateMatchScore(db))
-			elimination.POST("/brackets/:bracketId/matches/:matchId/finish", middleware.AuthMiddleware(), handler.FinishMatch(db))
-			elimination.POST("/brackets/:bracketId/matches/:matchId/end", middleware.AuthMiddleware(), handler.EndMatch(db))
+			elimination.POST("/brackets/:bracketId/matches/:matchId/score", middleware.AuthOrAPIKeyMiddleware(models.ScopeScoresWrite), handler.UpdateMatchScore(db))
+			elimination.POST("/brackets/:bracketId/matches/:matchId/finish", middleware.AuthOrAPIKeyMiddleware(models.ScopeScoresWrite), handler.FinishMatch(db))
+			elimination.POST("/brackets/:bracketId/matches/:matchId/end", middleware.AuthOrAPIKeyMiddleware(models.ScopeScoresWrite), handler.EndMatch(db))
 		}
 
 		qualSessions := api.Group("/qualification/sessions/:sessionId")
@@ -274,14 +356,15 @@ func main() {
 			qualSessions.POST("/auto-assign", handler.AutoAssignParticipants(db))
 			qualSessions.POST("/reset-assignments", handler.ResetSessionAssignments(db))
 			qualSessions.POST("/swap-assignments", handler.SwapTargetAssignments(db))
+			qualSessions.POST("/achievements/evaluate", handler.EvaluateSessionAchievements(db))
 		}
 
+		// Score entry also accepts API keys (scoring tablets, venue systems)
 		qualAssignments := api.Group("/qualification/assignments/:assignmentId")
-		qualAssignments.Use(middleware.AuthMiddleware())
 		{
-			qualAssignments.GET("/scores", handler.GetQualificationAssignmentScores(db))
-			qualAssignments.POST("/scores", handler.UpdateQualificationScore(db))
-			qualAssignments.DELETE("", handler.DeleteQualificationAssignment(db))
+			qualAssignments.GET("/scores", middleware.AuthMiddleware(), handler.GetQualificationAssignmentScores(db))
+			qualAssignments.POST("/scores", middleware.AuthOrAPIKeyMiddleware(models.ScopeScoresWrite), handler.UpdateQualificationScore(db))
+			qualAssignments.DELETE("", middleware.AuthMiddleware(), handler.DeleteQualificationAssignment(db))
 		}
 
 		// Target routes
@@ -297,16 +380,18 @@ func main() {
 
 		// Event category reference routes
 		api.GET("/event-categories", handler.ListEventCategoryRefs(db))
-		api.POST("/event-categories", handler.CreateEventCategoryRef(db))
-		api.PUT("/event-categories/:id", handler.UpdateEventCategoryRef(db))
+		api.POST("/event-categories", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.CreateEventCategoryRef(db))
+		api.PUT("/event-categories/:id", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.UpdateEventCategoryRef(db))
 
 		// Archer routes
 		archers := api.Group("/archers")
 		{
 			// Public archer routes
-			archers.GET("", handler.GetArchers(db))
-			archers.GET("/:id", handler.GetArcherByID(db))
+			archers.GET("", middleware.OptionalAuthMiddleware(), handler.GetArchers(db))
+			archers.GET("/:id", middleware.OptionalAuthMiddleware(), handler.GetArcherByID(db))
 			archers.GET("/:id/events", handler.GetArcherEvents(db))
+			archers.GET("/:id/stats", handler.GetArcherStats(db))
+			archers.GET("/:id/achievements", handler.GetArcherAchievements(db))
 			archers.GET("/registration-profile/:uuid", handler.GetArcherRegistrationProfile(db))
 
 			// Protected archer routes
@@ -314,12 +399,89 @@ func main() {
 			protected.Use(middleware.AuthMiddleware())
 			{
 				protected.GET("/my/events", handler.GetMyArcherEvents(db))
+				protected.GET("/my/practice/rounds", handler.GetMyPracticeRounds(db))
+				protected.POST("/my/practice/rounds", handler.CreatePracticeRound(db))
+				protected.GET("/my/practice/rounds/:roundId", handler.GetMyPracticeRound(db))
+				protected.PUT("/my/practice/rounds/:roundId/ends", handler.UpdatePracticeEnds(db))
 				protected.POST("", handler.CreateArcher(db))
-				protected.PUT("/:id", handler.UpdateArcher(db))
-				protected.DELETE("/:id", handler.DeleteArcher(db))
+				protected.PUT("/:id", middleware.ForbidImpersonation(), handler.UpdateArcher(db))
+				protected.DELETE("/:id", middleware.ForbidImpersonation(), handler.DeleteArcher(db))
+			}
+
+			// Duplicate detection and merging (admin)
+			merges := archers.Group("")
+			merges.Use(middleware.AuthMiddleware(), middleware.RequireRole("admin"))
+			{
+				merges.GET("/duplicates", handler.GetArcherDuplicateCandidates(db))
+				merges.POST("/duplicates/dismiss", handler.DismissArcherDuplicate(db))
+				merges.POST("/merge", handler.MergeArchers(db))
+				merges.GET("/merges", handler.GetArcherMerges(db))
+				merges.POST("/merges/:mergeId/undo", handler.UndoArcherMerge(db))
 			}
 		}
 
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(), middleware.RequireRole("admin"))
+		{
+			admin.POST("/users/:userId/sessions/revoke", handler.AdminRevokeUserSessions(db))
+
+			// Club and organization verification
+			admin.GET("/verifications", handler.GetAdminVerifications(db))
+			admin.GET("/verifications/:type/:entityId", handler.GetAdminVerificationDetail(db))
+			admin.POST("/verifications/:type/:entityId/review", handler.StartVerificationReview(db))
+			admin.POST("/verifications/:type/:entityId", handler.DecideVerification(db))
+			admin.GET("/guardian-links", handler.GetAdminGuardianLinks(db))
+
+			// Moderation
+			admin.POST("/accounts/:type/:profileId/suspend", handler.SuspendAccount(db))
+			admin.POST("/accounts/:type/:profileId/reinstate", handler.ReinstateAccount(db))
+			admin.POST("/events/:id/unpublish", handler.AdminUnpublishEvent(db))
+			admin.POST("/news/:id/unpublish", handler.AdminUnpublishNews(db))
+
+			// Reference data
+			admin.POST("/reference/:kind", handler.CreateRefData(db))
+			admin.PUT("/reference/:kind/:refId", handler.UpdateRefData(db))
+			admin.DELETE("/reference/:kind/:refId", handler.DeleteRefData(db))
+
+			// Logs, support and admin rights
+			admin.GET("/activity-logs", handler.GetAdminActivityLogs(db))
+			admin.GET("/audit-logs", handler.GetAdminAuditLogs(db))
+			admin.POST("/impersonate", handler.ImpersonateUser(db))
+			admin.PUT("/identities/:identityId/platform-role", handler.SetPlatformRole(db))
+		}
+
+		// Organization and club verification
+		verification := api.Group("/verification")
+		verification.Use(middleware.AuthMiddleware())
+		{
+			verification.GET("", handler.GetMyVerification(db))
+			verification.POST("", handler.SubmitVerification(db))
+		}
+
+		// API keys for integrations and scoreboard displays
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(middleware.AuthMiddleware(), middleware.ForbidImpersonation())
+		{
+			apiKeys.GET("", handler.GetAPIKeys(db))
+			apiKeys.POST("", handler.CreateAPIKey(db))
+			apiKeys.POST("/:keyId/rotate", handler.RotateAPIKey(db))
+			apiKeys.DELETE("/:keyId", handler.RevokeAPIKey(db))
+		}
+
+		// Guardians of minor archers
+		guardians := api.Group("/guardians")
+		guardians.Use(middleware.AuthMiddleware())
+		{
+			guardians.GET("/wards", handler.GetMyWards(db))
+			guardians.POST("/wards", handler.RequestGuardianLink(db))
+			guardians.GET("/mine", handler.GetMyGuardians(db))
+			guardians.POST("/links/:linkId/confirm", handler.ConfirmGuardianLink(db))
+			guardians.DELETE("/links/:linkId", handler.RevokeGuardianLink(db))
+			guardians.GET("/consents", handler.GetPendingGuardianConsents(db))
+			guardians.POST("/consents/:participantId", handler.DecideGuardianConsent(db))
+		}
+
 		// Reference data routes
 		api.GET("/disciplines", handler.GetDisciplines(db))
 		api.GET("/bow-types", handler.GetBowTypes(db))
@@ -372,6 +534,20 @@ func main() {
 			payment.GET("/status/:reference", handler.GetPaymentStatus(db))
 			payment.POST("/create", middleware.AuthMiddleware(), handler.CreatePayment(db))
 			payment.POST("/tripay/callback", handler.PaymentCallback(db))
+			payment.GET("/transactions/:reference/events", middleware.AuthMiddleware(), handler.GetPaymentEvents(db))
+			payment.POST("/reconcile", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.TriggerPaymentReconciliation(db))
+		}
+
+		// Refund routes
+		refunds := api.Group("/refunds")
+		refunds.Use(middleware.AuthMiddleware())
+		{
+			refunds.GET("/my", handler.GetMyRefunds(db))
+			refunds.GET("/credit-notes", handler.GetMyCreditNotes(db))
+			refunds.PUT("/:refundId/bank-account", handler.UpdateRefundBankAccount(db))
+			refunds.POST("/:refundId/approve", handler.ApproveRefund(db))
+			refunds.POST("/:refundId/reject", handler.RejectRefund(db))
+			refunds.POST("/:refundId/paid", handler.MarkRefundPaid(db))
 		}
 
 		// Organization routes
@@ -397,6 +573,7 @@ func main() {
 			clubs.GET("", handler.GetClubs(db))
 			clubs.GET("/availability", handler.CheckSlugAvailability(db))
 			clubs.GET("/profile/:slug", handler.GetClubProfile(db))
+			clubs.GET("/:clubId/members", middleware.OptionalAuthMiddleware(), handler.GetClubMembers(db))
 
 			// Protected club routes
 			protectedClubs := clubs.Group("")
@@ -406,6 +583,88 @@ func main() {
 				protectedClubs.PUT("/me", handler.UpdateClubMe(db))
 				protectedClubs.GET("/dashboard/stats", handler.GetClubDashboardStats(db))
 				protectedClubs.PUT("/profile", handler.UpdateMyClubProfile(db))
+
+				// Membership lifecycle
+				protectedClubs.POST("/:clubId/join", handler.JoinClub(db))
+				protectedClubs.POST("/:clubId/transfer", handler.RequestClubTransfer(db))
+				protectedClubs.GET("/membership/me", handler.GetMyClubMembership(db))
+				protectedClubs.POST("/membership/leave", handler.LeaveClub(db))
+				protectedClubs.GET("/invitations/me", handler.GetMyClubInvitations(db))
+				protectedClubs.POST("/invitations/:memberId/accept", handler.AcceptClubInvitation(db))
+				protectedClubs.POST("/invitations/:memberId/decline", handler.DeclineClubInvitation(db))
+				protectedClubs.POST("/members/invite", handler.InviteToClub(db))
+				protectedClubs.POST("/members/:memberId/approve", handler.ApproveClubMember(db))
+				protectedClubs.POST("/members/:memberId/reject", handler.RejectClubMember(db))
+				protectedClubs.POST("/members/:memberId/suspend", handler.SuspendClubMember(db))
+				protectedClubs.POST("/members/:memberId/reinstate", handler.ReinstateClubMember(db))
+
+				// Staff roles
+				protectedClubs.GET("/staff", handler.GetClubStaff(db))
+				protectedClubs.POST("/staff", handler.AddClubStaff(db))
+				protectedClubs.DELETE("/staff/:staffId", handler.RemoveClubStaff(db))
+				protectedClubs.GET("/staff/me", handler.GetMyClubRoles(db))
+				protectedClubs.GET("/athletes/:archerId/scores", handler.GetClubAthleteScores(db))
+
+				// Dues billing
+				protectedClubs.GET("/dues/plan", handler.GetClubDuesPlan(db))
+				protectedClubs.PUT("/dues/plan", handler.UpdateClubDuesPlan(db))
+				protectedClubs.POST("/dues/generate", handler.GenerateClubDuesInvoices(db))
+				protectedClubs.GET("/dues/invoices", handler.GetClubDuesInvoices(db))
+				protectedClubs.POST("/dues/invoices/:invoiceId/mark-paid", handler.MarkClubDuesPaid(db))
+				protectedClubs.GET("/dues/my", handler.GetMyDuesInvoices(db))
+				protectedClubs.POST("/dues/invoices/:invoiceId/pay", handler.PayClubDuesInvoice(db))
+
+				// Training attendance
+				protectedClubs.GET("/training/sessions", handler.GetClubTrainingSessions(db))
+				protectedClubs.POST("/training/sessions", handler.CreateClubTrainingSession(db))
+				protectedClubs.PUT("/training/sessions/:sessionId", handler.UpdateClubTrainingSession(db))
+				protectedClubs.DELETE("/training/sessions/:sessionId", handler.DeleteClubTrainingSession(db))
+				protectedClubs.POST("/training/sessions/:sessionId/check-in", handler.CheckInTrainingSession(db))
+				protectedClubs.GET("/training/sessions/:sessionId/attendance", handler.GetTrainingSessionAttendance(db))
+				protectedClubs.GET("/training/my", handler.GetMyClubTrainingSessions(db))
+				protectedClubs.GET("/training/my/qr", handler.GetMyClubQRCode(db))
+				protectedClubs.GET("/training/attendance", handler.GetClubAttendanceSummary(db))
+				protectedClubs.GET("/athletes/:archerId/training", handler.GetClubAthleteTraining(db))
+			}
+		}
+
+		// Achievement badges
+		achievements := api.Group("/achievements")
+		{
+			achievements.GET("/rules", middleware.OptionalAuthMiddleware(), handler.GetAchievementRules(db))
+			achievements.POST("/rules", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.CreateAchievementRule(db))
+			achievements.PUT("/rules/:ruleId", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.UpdateAchievementRule(db))
+			achievements.DELETE("/rules/:ruleId", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.DeleteAchievementRule(db))
+		}
+
+		// National ranking
+		rankings := api.Group("/rankings")
+		{
+			rankings.GET("", handler.GetNationalRankings(db))
+			rankings.GET("/snapshots", handler.GetRankingSnapshots(db))
+			rankings.GET("/snapshots/:month", handler.GetRankingSnapshot(db))
+			rankings.GET("/archers/:archerId", handler.GetArcherRanking(db))
+			rankings.POST("/recompute", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.RecomputeNationalRankings(db))
+			rankings.POST("/snapshots", middleware.AuthMiddleware(), middleware.RequireRole("admin"), handler.CreateRankingSnapshot(db))
+		}
+
+		// Inter-club series standings
+		series := api.Group("/series")
+		{
+			series.GET("", handler.GetClubSeriesList(db))
+			series.GET("/:id", handler.GetClubSeries(db))
+			series.GET("/:id/standings", handler.GetClubSeriesStandings(db))
+			series.GET("/:id/standings/clubs/:clubId", handler.GetClubSeriesBreakdown(db))
+
+			protectedSeries := series.Group("")
+			protectedSeries.Use(middleware.AuthMiddleware())
+			{
+				protectedSeries.GET("/my", handler.GetMyClubSeries(db))
+				protectedSeries.POST("", handler.CreateClubSeries(db))
+				protectedSeries.PUT("/:id", handler.UpdateClubSeries(db))
+				protectedSeries.DELETE("/:id", handler.DeleteClubSeries(db))
+				protectedSeries.POST("/:id/events", handler.AddClubSeriesEvent(db))
+				protectedSeries.DELETE("/:id/events/:eventId", handler.RemoveClubSeriesEvent(db))
 			}
 		}
 
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"archeryhub-api/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyLookup resolves an API key to what it may do, or nil for unknown, expired and revoked
+// keys. It's installed at startup with SetAPIKeyLookup; until then API keys are refused.
+var apiKeyLookup func(key string, ip string) *models.APIKeyPrincipal
+
+// SetAPIKeyLookup installs the lookup used to authenticate API keys
+func SetAPIKeyLookup(lookup func(key string, ip string) *models.APIKeyPrincipal) {
+	apiKeyLookup = lookup
+}
+
+// apiKeyFromRequest reads an API key from the X-API-Key header or an "ApiKey <key>"
+// Authorization header
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) == 2 && parts[0] == "ApiKey" {
+		return parts[1]
+	}
+	return ""
+}
+
+// authenticateAPIKey checks the key and its scope and sets the request context. The key acts as
+// the user who created it, with role and user_type "api_key" so handlers can tell.
+func authenticateAPIKey(c *gin.Context, key, scope string) bool {
+	var principal *models.APIKeyPrincipal
+	if apiKeyLookup != nil {
+		principal = apiKeyLookup(key, c.ClientIP())
+	}
+	if principal == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key", "code": "invalid_api_key"})
+		c.Abort()
+		return false
+	}
+	if !principal.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope", "code": "insufficient_scope"})
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_id", principal.OwnerID)
+	c.Set("role", "api_key")
+	c.Set("user_type", "api_key")
+	c.Set("api_key", principal)
+	return true
+}
+
+// AuthOrAPIKeyMiddleware accepts either a user token, like AuthMiddleware, or an API key with the
+// scope
+func AuthOrAPIKeyMiddleware(scope string) gin.HandlerFunc {
+	auth := AuthMiddleware()
+	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			if authenticateAPIKey(c, key, scope) {
+				c.Next()
+			}
+			return
+		}
+		auth(c)
+	}
+}
+
+// OptionalAuthOrAPIKeyMiddleware is OptionalAuthMiddleware that also accepts an API key with the
+// scope. A key that is sent but invalid is refused rather than ignored.
+func OptionalAuthOrAPIKeyMiddleware(scope string) gin.HandlerFunc {
+	optional := OptionalAuthMiddleware()
+	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			if authenticateAPIKey(c, key, scope) {
+				c.Next()
+			}
+			return
+		}
+		optional(c)
+	}
+}
@@ -10,6 +10,25 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// sessionCheck reports whether the session an access token belongs to is still active. It's
+// installed at startup with SetSessionCheck; until then only the token itself is checked.
+var sessionCheck func(sessionID string) bool
+
+// SetSessionCheck installs the check used to reject tokens of revoked sessions
+func SetSessionCheck(check func(sessionID string) bool) {
+	sessionCheck = check
+}
+
+// sessionRevoked reports whether the token's session was revoked. Tokens issued before sessions
+// existed carry no session id and are treated as revoked.
+func sessionRevoked(claims jwt.MapClaims) bool {
+	if sessionCheck == nil {
+		return false
+	}
+	sid, _ := claims["sid"].(string)
+	return sid == "" || !sessionCheck(sid)
+}
+
 // AuthMiddleware validates JWT tokens from Authorization header or auth_token cookie
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -71,10 +90,19 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// Extract claims
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if sessionRevoked(claims) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked", "code": "session_revoked"})
+				c.Abort()
+				return
+			}
 			c.Set("user_id", claims["user_id"])
 			c.Set("email", claims["email"])
 			c.Set("role", claims["role"])
 			c.Set("user_type", claims["user_type"])
+			c.Set("session_id", claims["sid"])
+			c.Set("identity_id", claims["identity_id"])
+			c.Set("two_factor", claims["tfa"] == true)
+			c.Set("impersonated_by", claims["imp"])
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
@@ -105,6 +133,20 @@ func RequireRole(requiredRole string) gin.HandlerFunc {
 	}
 }
 
+// ForbidImpersonation refuses requests made from a support session an admin started as the
+// user, for actions only the user themselves may take (credentials, 2FA, API keys)
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("impersonated_by") != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating", "code": "impersonation_forbidden"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // OptionalAuthMiddleware attempts to validate JWT tokens but proceeds even if missing or invalid
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -153,12 +195,16 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 		})
 
 		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if claims, ok := token.Claims.(jwt.MapClaims); ok && !sessionRevoked(claims) {
                 fmt.Printf("[DEBUG OptionalAuth] Claims found. UserID: %v\n", claims["user_id"])
 				c.Set("user_id", claims["user_id"])
 				c.Set("email", claims["email"])
 				c.Set("role", claims["role"])
 				c.Set("user_type", claims["user_type"])
+				c.Set("session_id", claims["sid"])
+				c.Set("identity_id", claims["identity_id"])
+				c.Set("two_factor", claims["tfa"] == true)
+				c.Set("impersonated_by", claims["imp"])
 			}
 		} else {
             fmt.Println("[DEBUG OptionalAuth] Token invalid or parse error:", err)
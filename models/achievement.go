@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// AchievementRule defines a badge and the score condition that earns it
+type AchievementRule struct {
+	UUID        string  `json:"id" db:"uuid"`
+	Code        string  `json:"code" db:"code"`
+	Name        string  `json:"name" db:"name"`
+	Description *string `json:"description" db:"description"`
+	IconURL     *string `json:"icon_url" db:"icon_url"`
+	// RuleType is total_score (session total >= threshold), perfect_end (an end of at least
+	// threshold arrows all scoring arrow_value or more) or consecutive_x (threshold Xs in a row)
+	RuleType   string `json:"rule_type" db:"rule_type"`
+	Threshold  int    `json:"threshold" db:"threshold"`
+	ArrowValue *int   `json:"arrow_value" db:"arrow_value"` // perfect_end only, defaults to 10
+	// Optional round filters: total arrows of the session, distance in meters and bow type
+	TotalArrows *int      `json:"total_arrows" db:"total_arrows"`
+	Distance    *int      `json:"distance" db:"distance"`
+	BowTypeID   *string   `json:"bow_type_id" db:"bow_type_id"`
+	Status      string    `json:"status" db:"status"` // active, inactive
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AchievementRuleRequest creates or updates an achievement rule
+type AchievementRuleRequest struct {
+	Code        string  `json:"code" binding:"required"`
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+	IconURL     *string `json:"icon_url"`
+	RuleType    string  `json:"rule_type" binding:"required,oneof=total_score perfect_end consecutive_x"`
+	Threshold   int     `json:"threshold" binding:"required,min=1"`
+	ArrowValue  *int    `json:"arrow_value" binding:"omitempty,min=1,max=10"`
+	TotalArrows *int    `json:"total_arrows"`
+	Distance    *int    `json:"distance"`
+	BowTypeID   *string `json:"bow_type_id"`
+	Status      string  `json:"status" binding:"omitempty,oneof=active inactive"`
+}
+
+// ArcherAchievement is a badge awarded to an archer, with the session that earned it as evidence
+type ArcherAchievement struct {
+	UUID          string    `json:"id" db:"uuid"`
+	ArcherID      string    `json:"archer_id" db:"archer_id"`
+	RuleID        string    `json:"rule_id" db:"rule_id"`
+	EventID       string    `json:"event_id" db:"event_id"`
+	SessionID     string    `json:"session_id" db:"session_id"`
+	ParticipantID string    `json:"participant_id" db:"participant_id"`
+	Evidence      *string   `json:"evidence" db:"evidence"` // JSON, e.g. {"score": 1205} or {"end_number": 4}
+	AwardedAt     time.Time `json:"awarded_at" db:"awarded_at"`
+}
+
+// ArcherAchievementDetail is an awarded badge with its rule and event for the profile
+type ArcherAchievementDetail struct {
+	ArcherAchievement
+	Code      string  `json:"code" db:"code"`
+	Name      string  `json:"name" db:"name"`
+	IconURL   *string `json:"icon_url" db:"icon_url"`
+	EventName string  `json:"event_name" db:"event_name"`
+	EventSlug string  `json:"event_slug" db:"event_slug"`
+}
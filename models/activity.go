@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ActivityLog is a row of the platform-wide activity log written by utils.RecordActivity
+type ActivityLog struct {
+	ID          string          `json:"id" db:"id"`
+	UserID      *string         `json:"user_id" db:"user_id"`
+	ActorName   *string         `json:"actor_name" db:"actor_name"`
+	EventID     *string         `json:"event_id" db:"event_id"`
+	Action      string          `json:"action" db:"action"`
+	EntityType  *string         `json:"entity_type" db:"entity_type"`
+	EntityID    *string         `json:"entity_id" db:"entity_id"`
+	Description *string         `json:"description" db:"description"`
+	BeforeRaw   *string         `json:"-" db:"before_data"`
+	AfterRaw    *string         `json:"-" db:"after_data"`
+	Before      json.RawMessage `json:"before" db:"-"`
+	After       json.RawMessage `json:"after" db:"-"`
+	IPAddress   *string         `json:"ip_address" db:"ip_address"`
+	UserAgent   *string         `json:"user_agent" db:"user_agent"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// DecodePayloads exposes the stored before/after JSON as-is in the response
+func (l *ActivityLog) DecodePayloads() {
+	if l.BeforeRaw != nil && json.Valid([]byte(*l.BeforeRaw)) {
+		l.Before = json.RawMessage(*l.BeforeRaw)
+	}
+	if l.AfterRaw != nil && json.Valid([]byte(*l.AfterRaw)) {
+		l.After = json.RawMessage(*l.AfterRaw)
+	}
+}
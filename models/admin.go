@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// AdminAuditLog records one action taken through the admin back office
+type AdminAuditLog struct {
+	UUID       string    `json:"id" db:"uuid"`
+	AdminID    string    `json:"admin_id" db:"admin_id"`
+	AdminName  *string   `json:"admin_name" db:"admin_name"`
+	Action     string    `json:"action" db:"action"`
+	TargetType string    `json:"target_type" db:"target_type"`
+	TargetID   string    `json:"target_id" db:"target_id"`
+	Reason     *string   `json:"reason" db:"reason"`
+	Payload    *string   `json:"payload" db:"payload"` // JSON
+	IPAddress  string    `json:"ip_address" db:"ip_address"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// PendingVerification is a verification submission in the admin review queue
+type PendingVerification struct {
+	SubmissionID  string    `json:"submission_id" db:"submission_id"`
+	EntityType    string    `json:"entity_type" db:"entity_type"`
+	UUID          string    `json:"id" db:"uuid"`
+	Name          string    `json:"name" db:"name"`
+	Slug          *string   `json:"slug" db:"slug"`
+	Email         *string   `json:"email" db:"email"`
+	City          *string   `json:"city" db:"city"`
+	Status        string    `json:"status" db:"status"`
+	DocumentCount int       `json:"document_count" db:"document_count"`
+	SubmittedAt   time.Time `json:"submitted_at" db:"submitted_at"`
+}
+
+// AdminVerificationDecision verifies or rejects a club's or organization's open submission
+type AdminVerificationDecision struct {
+	Status string `json:"status" binding:"required,oneof=verified rejected"`
+	Note   string `json:"note"`
+}
+
+// AdminVerificationReviewRequest moves a submission to under review, optionally with a comment
+// for the submitter
+type AdminVerificationReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// AdminReasonRequest carries the reason for a moderation action, shown to the affected user
+type AdminReasonRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// AdminRefDataRequest creates or updates a reference data entry (bow type, age group, ...)
+type AdminRefDataRequest struct {
+	Code string `json:"code" binding:"required,max=20"`
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// ImpersonateRequest starts a support session as another user's profile
+type ImpersonateRequest struct {
+	UserType  string `json:"user_type" binding:"required,oneof=archer organization club seller"`
+	ProfileID string `json:"profile_id" binding:"required"`
+	Reason    string `json:"reason" binding:"required,max=500"`
+}
+
+// PlatformRoleRequest grants or removes platform admin rights of a login
+type PlatformRoleRequest struct {
+	Admin *bool `json:"admin" binding:"required"`
+}
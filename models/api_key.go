@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// API key scopes
+const (
+	ScopeResultsRead      = "results:read"
+	ScopeScoresWrite      = "scores:write"
+	ScopeParticipantsRead = "participants:read"
+)
+
+// APIKeyScopes lists every scope a key can be given
+var APIKeyScopes = []string{ScopeResultsRead, ScopeScoresWrite, ScopeParticipantsRead}
+
+// APIKey gives a machine (a scoreboard, a federation system) access to one organization's or one
+// event's data without a user session. Only a hash of the key is stored.
+type APIKey struct {
+	UUID           string     `json:"id" db:"uuid"`
+	Name           string     `json:"name" db:"name"`
+	Prefix         string     `json:"prefix" db:"prefix"` // first characters of the key, to tell keys apart
+	KeyHash        string     `json:"-" db:"key_hash"`
+	OwnerID        string     `json:"owner_id" db:"owner_id"`
+	OrganizationID *string    `json:"organization_id" db:"organization_id"`
+	EventID        *string    `json:"event_id" db:"event_id"`
+	ScopesJSON     string     `json:"-" db:"scopes"`
+	Scopes         []string   `json:"scopes" db:"-"`
+	ExpiresAt      *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at" db:"last_used_at"`
+	LastUsedIP     *string    `json:"last_used_ip" db:"last_used_ip"`
+	RevokedAt      *time.Time `json:"revoked_at" db:"revoked_at"`
+	RotatedFrom    *string    `json:"rotated_from" db:"rotated_from"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// APIKeyPrincipal is who a request authenticated with an API key acts as
+type APIKeyPrincipal struct {
+	KeyID          string
+	OwnerID        string
+	OrganizationID string
+	EventID        string
+	Scopes         []string
+}
+
+// HasScope reports whether the key was given the scope
+func (p *APIKeyPrincipal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyRequest creates a key for the caller's organization, or for one event when EventID
+// is set
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	EventID       string   `json:"event_id"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=results:read scores:write participants:read"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=730"`
+}
+
+// RotateAPIKeyRequest replaces a key; the old one keeps working for the grace period
+type RotateAPIKeyRequest struct {
+	GraceMinutes int `json:"grace_minutes"` // 0 to 1440
+}
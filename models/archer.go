@@ -29,11 +29,13 @@ type Archer struct {
 // ArcherWithStats includes statistics
 type ArcherWithStats struct {
 	Archer
-	ClubName        *string    `json:"club_name" db:"club_name"`
-	ClubSlug        *string    `json:"club_slug" db:"club_slug"`
-	TotalEvents     int        `json:"total_events" db:"total_events"`
-	CompletedEvents int        `json:"completed_events" db:"completed_events"`
-	LastEventDate   *time.Time `json:"last_event_date" db:"last_event_date"`
+	ClubName        *string                   `json:"club_name" db:"club_name"`
+	ClubSlug        *string                   `json:"club_slug" db:"club_slug"`
+	TotalEvents     int                       `json:"total_events" db:"total_events"`
+	CompletedEvents int                       `json:"completed_events" db:"completed_events"`
+	LastEventDate   *time.Time                `json:"last_event_date" db:"last_event_date"`
+	AvgPerArrow     *float64                  `json:"avg_per_arrow,omitempty" db:"avg_per_arrow"` // career qualification average
+	Achievements    []ArcherAchievementDetail `json:"achievements,omitempty" db:"-"`
 }
 
 // CreateArcherRequest represents the request payload for creating an archer
@@ -54,6 +56,9 @@ type CreateArcherRequest struct {
 	AvatarURL *string `json:"avatar_url"`
 	Address   *string `json:"address"`
 	Phone     *string `json:"phone"`
+
+	// GuardianRelationship makes the creating user the guardian of the new profile
+	GuardianRelationship *string `json:"guardian_relationship" binding:"omitempty,oneof=parent legal_guardian other"`
 }
 
 // UpdateArcherRequest represents the request payload for updating an archer
@@ -88,6 +93,9 @@ type EventParticipant struct {
 	PaymentStatus        string     `json:"payment_status" db:"payment_status"` // menunggu_acc, belum_lunas, lunas
 	PaymentAmount        float64    `json:"payment_amount" db:"payment_amount"`
 	AccreditationStatus  string     `json:"accreditation_status" db:"accreditation_status"` // pending, printed, collected
+	BaseFee              *float64   `json:"base_fee" db:"base_fee"`
+	DiscountAmount       float64    `json:"discount_amount" db:"discount_amount"`
+	PromoCodeID          *string    `json:"promo_code_id" db:"promo_code_id"`
 	Notes                *string    `json:"notes" db:"notes"`
 }
 
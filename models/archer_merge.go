@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ArcherDuplicateCandidate is a pair of archer records that look like the same person
+type ArcherDuplicateCandidate struct {
+	Archers []ArcherDuplicateProfile `json:"archers"`
+	Reasons []string                 `json:"reasons"` // name_dob, email, phone
+}
+
+// ArcherDuplicateProfile is the summary admins compare when deciding on a merge
+type ArcherDuplicateProfile struct {
+	UUID        string     `json:"uuid" db:"uuid"`
+	FullName    string     `json:"full_name" db:"full_name"`
+	Username    *string    `json:"username" db:"username"`
+	Email       *string    `json:"email" db:"email"`
+	Phone       *string    `json:"phone" db:"phone"`
+	DateOfBirth *time.Time `json:"date_of_birth" db:"date_of_birth"`
+	ClubID      *string    `json:"club_id" db:"club_id"`
+	HasGoogle   bool       `json:"has_google" db:"has_google"`
+	EventCount  int        `json:"event_count" db:"event_count"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ArcherMerge records a merge of one archer into another with the changes needed to undo it
+type ArcherMerge struct {
+	UUID           string     `json:"id" db:"uuid"`
+	SourceArcherID string     `json:"source_archer_id" db:"source_archer_id"`
+	TargetArcherID string     `json:"target_archer_id" db:"target_archer_id"`
+	MergedBy       string     `json:"merged_by" db:"merged_by"`
+	UndoLog        string     `json:"-" db:"undo_log"`    // JSON
+	Status         string     `json:"status" db:"status"` // merged, undone
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UndoneAt       *time.Time `json:"undone_at" db:"undone_at"`
+	UndoneBy       *string    `json:"undone_by" db:"undone_by"`
+}
+
+// MergeArchersRequest merges the source archer into the surviving target archer
+type MergeArchersRequest struct {
+	SourceArcherID string `json:"source_archer_id" binding:"required"`
+	TargetArcherID string `json:"target_archer_id" binding:"required,nefield=SourceArcherID"`
+}
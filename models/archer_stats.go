@@ -0,0 +1,66 @@
+package models
+
+// ArcherRound is an archer's qualification score in one session of an event
+type ArcherRound struct {
+	EventID       string  `json:"event_id" db:"event_id"`
+	EventName     string  `json:"event_name" db:"event_name"`
+	EventDate     *string `json:"event_date" db:"event_date"`
+	Season        int     `json:"season" db:"season"`
+	SessionName   string  `json:"session_name" db:"session_name"`
+	BowType       *string `json:"bow_type" db:"bow_type"`
+	LocationType  *string `json:"location_type" db:"location_type"`
+	Distance      *int    `json:"distance" db:"distance"`
+	TotalEnds     int     `json:"total_ends" db:"total_ends"`
+	ArrowsPerEnd  int     `json:"arrows_per_end" db:"arrows_per_end"`
+	EndsCompleted int     `json:"ends_completed" db:"ends_completed"`
+	ArrowsShot    int     `json:"arrows_shot" db:"arrows_shot"`
+	Score         int     `json:"score" db:"score"`
+	TenCount      int     `json:"ten_count" db:"ten_count"`
+	XCount        int     `json:"x_count" db:"x_count"`
+	Complete      bool    `json:"complete" db:"-"`
+}
+
+// ArcherPersonalBest is the best complete round an archer shot for a round type and distance
+type ArcherPersonalBest struct {
+	RoundType string       `json:"round_type"` // e.g. "72 arrows Outdoor"
+	Arrows    int          `json:"arrows"`
+	Distance  *int         `json:"distance"`
+	BowType   *string      `json:"bow_type"`
+	Round     *ArcherRound `json:"round"`
+}
+
+// ArcherSeasonStats aggregates an archer's qualification rounds in one calendar year
+type ArcherSeasonStats struct {
+	Season         int     `json:"season"`
+	Events         int     `json:"events"`
+	Rounds         int     `json:"rounds"`
+	CompleteRounds int     `json:"complete_rounds"`
+	EndsCompleted  int     `json:"ends_completed"`
+	ArrowsShot     int     `json:"arrows_shot"`
+	TotalScore     int     `json:"total_score"`
+	AvgPerArrow    float64 `json:"avg_per_arrow"`
+	AvgRoundScore  float64 `json:"avg_round_score"` // complete rounds only
+	BestRoundScore int     `json:"best_round_score"`
+	TenRate        float64 `json:"ten_rate"` // percent of arrows scoring 10 or X
+	XRate          float64 `json:"x_rate"`   // percent of arrows scoring X
+}
+
+// ArcherSeasonComparison is the change from the previous season to this one
+type ArcherSeasonComparison struct {
+	AvgPerArrow   float64 `json:"avg_per_arrow"`
+	AvgRoundScore float64 `json:"avg_round_score"`
+	TenRate       float64 `json:"ten_rate"`
+	XRate         float64 `json:"x_rate"`
+	ArrowsShot    int     `json:"arrows_shot"`
+}
+
+// ArcherStats is the profile statistics of an archer
+type ArcherStats struct {
+	ArcherID       string                  `json:"archer_id"`
+	Season         *ArcherSeasonStats      `json:"season"`
+	PreviousSeason *ArcherSeasonStats      `json:"previous_season"`
+	Change         *ArcherSeasonComparison `json:"change"`
+	Career         *ArcherSeasonStats      `json:"career"`
+	PersonalBests  []ArcherPersonalBest    `json:"personal_bests"`
+	History        []ArcherRound           `json:"history"`
+}
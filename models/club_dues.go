@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ClubDuesPlan is the membership fee a club charges its members
+type ClubDuesPlan struct {
+	UUID            string    `json:"id" db:"uuid"`
+	ClubID          string    `json:"club_id" db:"club_id"`
+	Amount          float64   `json:"amount" db:"amount"`
+	Period          string    `json:"period" db:"period"`                       // monthly, annual
+	PaymentTermDays int       `json:"payment_term_days" db:"payment_term_days"` // days to pay after an invoice is issued
+	GraceDays       int       `json:"grace_days" db:"grace_days"`
+	Status          string    `json:"status" db:"status"` // active, inactive
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ClubDuesInvoice is the dues a member owes for one period
+type ClubDuesInvoice struct {
+	UUID          string     `json:"id" db:"uuid"`
+	ClubID        string     `json:"club_id" db:"club_id"`
+	PlanID        string     `json:"plan_id" db:"plan_id"`
+	MemberID      string     `json:"member_id" db:"member_id"`
+	ArcherID      string     `json:"archer_id" db:"archer_id"`
+	PeriodStart   time.Time  `json:"period_start" db:"period_start"`
+	PeriodEnd     time.Time  `json:"period_end" db:"period_end"`
+	Amount        float64    `json:"amount" db:"amount"`
+	DueDate       time.Time  `json:"due_date" db:"due_date"`
+	GraceDays     int        `json:"grace_days" db:"grace_days"`
+	Status        string     `json:"status" db:"status"` // unpaid, paid, void
+	TransactionID *string    `json:"transaction_id" db:"transaction_id"`
+	PaidAt        *time.Time `json:"paid_at" db:"paid_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ClubDuesPlanRequest creates or replaces a club's dues plan
+type ClubDuesPlanRequest struct {
+	Amount          float64 `json:"amount" binding:"required,gt=0"`
+	Period          string  `json:"period" binding:"required,oneof=monthly annual"`
+	PaymentTermDays int     `json:"payment_term_days" binding:"min=0"` // defaults to 14
+	GraceDays       int     `json:"grace_days" binding:"min=0"`
+	Status          string  `json:"status" binding:"omitempty,oneof=active inactive"`
+}
+
+// PayDuesRequest starts a Tripay payment for a dues invoice
+type PayDuesRequest struct {
+	Method string `json:"method" binding:"required"` // Payment channel code (e.g., BRIVA, QRIS)
+}
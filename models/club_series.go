@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// ClubSeries is a season of events whose final placings score points for clubs
+type ClubSeries struct {
+	UUID            string    `json:"id" db:"uuid"`
+	Name            string    `json:"name" db:"name"`
+	Season          int       `json:"season" db:"season"`
+	Description     *string   `json:"description" db:"description"`
+	PointsTable     string    `json:"points_table" db:"points_table"` // JSON array, points by placing starting at 1st
+	BestPerCategory int       `json:"best_per_category" db:"best_per_category"`
+	Status          string    `json:"status" db:"status"` // draft, published
+	OrganizationID  *string   `json:"organization_id" db:"organization_id"`
+	CreatedBy       string    `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ClubSeriesRequest creates or updates a series
+type ClubSeriesRequest struct {
+	Name            string  `json:"name" binding:"required"`
+	Season          int     `json:"season" binding:"required,min=2000"`
+	Description     *string `json:"description"`
+	PointsTable     []int   `json:"points_table"`
+	BestPerCategory int     `json:"best_per_category" binding:"min=0"`
+	Status          string  `json:"status" binding:"omitempty,oneof=draft published"`
+}
+
+// ClubStandingPlacing is one archer's final placing that scored points for a club
+type ClubStandingPlacing struct {
+	EventID      string  `json:"event_id"`
+	EventName    string  `json:"event_name"`
+	CategoryID   string  `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	ArcherID     string  `json:"archer_id"`
+	ArcherName   string  `json:"archer_name"`
+	Place        int     `json:"place"`
+	Points       float64 `json:"points"`
+	Counted      bool    `json:"counted"` // false when beyond the best-N rule for the category
+}
+
+// ClubStanding is a club's aggregated position in a series
+type ClubStanding struct {
+	Rank          int                   `json:"rank"`
+	ClubID        string                `json:"club_id"`
+	ClubName      string                `json:"club_name"`
+	ClubAvatarURL *string               `json:"club_avatar_url"`
+	Points        float64               `json:"points"`
+	Gold          int                   `json:"gold"`
+	Silver        int                   `json:"silver"`
+	Bronze        int                   `json:"bronze"`
+	EventsScored  int                   `json:"events_scored"`
+	ArchersScored int                   `json:"archers_scored"`
+	Placings      []ClubStandingPlacing `json:"placings,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AccountDeletionRequest is an archer's request to delete their account. The account is
+// anonymised once ScheduledFor passes, unless the request is cancelled first.
+type AccountDeletionRequest struct {
+	UUID         string     `json:"id" db:"uuid"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	UserType     string     `json:"user_type" db:"user_type"`
+	IdentityID   *string    `json:"-" db:"identity_id"`
+	Email        *string    `json:"-" db:"email"` // where the completion notice goes once the account is anonymised
+	Reason       *string    `json:"reason" db:"reason"`
+	Status       string     `json:"status" db:"status"` // pending, cancelled, completed
+	ScheduledFor time.Time  `json:"scheduled_for" db:"scheduled_for"`
+	CancelledAt  *time.Time `json:"cancelled_at" db:"cancelled_at"`
+	CompletedAt  *time.Time `json:"completed_at" db:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RequestAccountDeletionRequest asks for the account to be deleted. Logins with a password must
+// confirm it.
+type RequestAccountDeletionRequest struct {
+	Password string `json:"password"`
+	Reason   string `json:"reason" binding:"max=1000"`
+}
@@ -89,6 +89,7 @@ type Event struct {
 	TechnicalGuidebookURL *string    `json:"technical_guidebook_url" db:"technical_guidebook_url"`
 	PageSettings          *string    `json:"page_settings" db:"page_settings"`
 	FAQ                   *string    `json:"faq" db:"faq"`
+	RankingTier           *string    `json:"ranking_tier" db:"ranking_tier"` // club, regional, national; nil when unranked
 }
 
 // EventWithDetails includes organizer information
@@ -98,6 +99,7 @@ type EventWithDetails struct {
 	OrganizerEmail      *string `json:"organizer_email" db:"organizer_email"`
 	OrganizerAvatarURL  *string `json:"organizer_avatar_url" db:"organizer_avatar_url"`
 	OrganizerSlug       *string `json:"organizer_slug" db:"organizer_slug"`
+	OrganizerVerified   bool    `json:"organizer_verified" db:"organizer_verified"`
 	ParticipantCount    int     `json:"participant_count" db:"participant_count"`
 	EventCount          int     `json:"event_count" db:"event_count"`
 	AccreditationStatus *string `json:"accreditation_status" db:"accreditation_status"`
@@ -166,6 +168,7 @@ type UpdateEventRequest struct {
 	TechnicalGuidebookURL *string       `json:"technical_guidebook_url"`
 	PageSettings          *string       `json:"page_settings"`
 	FAQ                   interface{}   `json:"faq"`
+	RankingTier           *string       `json:"ranking_tier"` // club, regional, national; "" removes the event from the ranking
 }
 
 // EventEvent represents an event within a Event (division + category)
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// ArcherGuardian links a guardian account to an archer profile they're responsible for
+type ArcherGuardian struct {
+	UUID         string `json:"id" db:"uuid"`
+	ArcherID     string `json:"archer_id" db:"archer_id"`
+	GuardianID   string `json:"guardian_id" db:"guardian_id"`
+	GuardianType string `json:"guardian_type" db:"guardian_type"`
+	Relationship string `json:"relationship" db:"relationship"` // parent, legal_guardian, other
+	// Status is pending until the archer (or an admin) confirms a link the guardian requested
+	Status         string     `json:"status" db:"status"` // pending, active, revoked
+	RequestedBy    string     `json:"requested_by" db:"requested_by"`
+	ConsentGivenAt *time.Time `json:"consent_given_at" db:"consent_given_at"`
+	ConsentIP      *string    `json:"-" db:"consent_ip"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ArcherGuardianDetail is a guardian link with both sides' names for listings
+type ArcherGuardianDetail struct {
+	ArcherGuardian
+	ArcherName        string     `json:"archer_name" db:"archer_name"`
+	ArcherAvatarURL   *string    `json:"archer_avatar_url" db:"archer_avatar_url"`
+	ArcherDateOfBirth *time.Time `json:"archer_date_of_birth" db:"archer_date_of_birth"`
+	GuardianName      string     `json:"guardian_name" db:"guardian_name"`
+}
+
+// GuardianLinkRequest asks to become the guardian of an existing archer profile
+type GuardianLinkRequest struct {
+	ArcherID     string `json:"archer_id" binding:"required"`
+	Relationship string `json:"relationship" binding:"required,oneof=parent legal_guardian other"`
+	// Consent confirms the guardian agrees to the archer taking part in events
+	Consent bool `json:"consent"`
+}
+
+// GuardianConsentDecision answers a pending guardian consent on an event registration
+type GuardianConsentDecision struct {
+	Approve bool `json:"approve"`
+}
+
+// PendingGuardianConsent is a ward's event registration waiting for the guardian's consent
+type PendingGuardianConsent struct {
+	ParticipantID    string     `json:"participant_id" db:"participant_id"`
+	ArcherID         string     `json:"archer_id" db:"archer_id"`
+	ArcherName       string     `json:"archer_name" db:"archer_name"`
+	EventID          string     `json:"event_id" db:"event_id"`
+	EventName        string     `json:"event_name" db:"event_name"`
+	EventStartDate   *time.Time `json:"event_start_date" db:"event_start_date"`
+	CategoryID       string     `json:"category_id" db:"category_id"`
+	RegistrationDate time.Time  `json:"registration_date" db:"registration_date"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Identity is a person's login. It owns the credentials; the archer, organization, club and
+// seller rows it's linked to are the profiles it can act as.
+type Identity struct {
+	UUID            string     `json:"id" db:"uuid"`
+	Email           string     `json:"email" db:"email"`
+	Password        *string    `json:"-" db:"password"`
+	GoogleID        *string    `json:"-" db:"google_id"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	Status          string     `json:"status" db:"status"`               // active, disabled
+	PlatformRole    *string    `json:"platform_role" db:"platform_role"` // admin for platform staff
+	TOTPSecret      *string    `json:"-" db:"totp_secret"`               // set at 2FA setup, before it's enabled
+	TOTPEnabledAt   *time.Time `json:"two_factor_enabled_at" db:"totp_enabled_at"`
+	TOTPLastStep    *int64     `json:"-" db:"totp_last_step"` // last accepted code's time step, against replay
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IdentityProfile is a profile linked to an identity, with the profile's display fields
+type IdentityProfile struct {
+	UUID       string     `json:"link_id" db:"uuid"`
+	IdentityID string     `json:"-" db:"identity_id"`
+	UserType   string     `json:"user_type" db:"user_type"` // archer, organization, club, seller
+	ProfileID  string     `json:"profile_id" db:"profile_id"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	Code       string     `json:"code" db:"code"` // archer id (ARC-0001), the uuid for other types
+	Name       string     `json:"name" db:"name"`
+	Username   *string    `json:"username" db:"username"`
+	Email      *string    `json:"email" db:"email"`
+	AvatarURL  *string    `json:"avatar_url" db:"avatar_url"`
+	Status     string     `json:"status" db:"status"`
+	Current    bool       `json:"current" db:"-"`
+}
+
+// SwitchProfileRequest picks the profile the next tokens are scoped to
+type SwitchProfileRequest struct {
+	ProfileID string `json:"profile_id" binding:"required"`
+}
+
+// CreateProfileRequest adds a new profile of another type to the current identity
+type CreateProfileRequest struct {
+	UserType string `json:"user_type" binding:"required,oneof=archer organization club seller"`
+	Name     string `json:"name" binding:"required,min=2,max=100"`
+	Slug     string `json:"slug"`
+}
+
+// LinkProfileRequest attaches another existing login to the current identity
+type LinkProfileRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	// Code or RecoveryCode is the other login's second factor, when it has 2FA on
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
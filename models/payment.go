@@ -38,8 +38,12 @@ type PaymentTransaction struct {
 	UserID           string          `json:"user_id" db:"user_id"`
 	EventID          *string         `json:"event_id" db:"event_id"`
 	RegistrationID   *string         `json:"registration_id" db:"registration_id"`
+	ParticipantID    *string         `json:"participant_id" db:"participant_id"`
+	DuesInvoiceID    *string         `json:"dues_invoice_id" db:"dues_invoice_id"`
 	Amount           float64         `json:"amount" db:"amount"`
 	FeeAmount        float64         `json:"fee_amount" db:"fee_amount"`
+	FeeMerchant      float64         `json:"fee_merchant" db:"fee_merchant"` // Tripay fee borne by the merchant
+	FeeCustomer      float64         `json:"fee_customer" db:"fee_customer"` // Tripay fee charged to the customer
 	TotalAmount      float64         `json:"total_amount" db:"total_amount"`
 	PaymentMethod    *string         `json:"payment_method" db:"payment_method"`
 	PaymentChannel   *string         `json:"payment_channel" db:"payment_channel"`
@@ -61,7 +65,9 @@ type CreatePaymentRequest struct {
 	Method         string  `json:"method" binding:"required"` // Payment channel code (e.g., BRIVA, QRIS)
 	EventID        string  `json:"event_id" binding:"required"`
 	RegistrationID *string `json:"registration_id"`
-	Type           string  `json:"type"` // e.g., "registration" (default) or "platform_fee"
+	ParticipantID  *string `json:"participant_id"`
+	PromoCode      string  `json:"promo_code"`
+	Type           string  `json:"type"` // e.g., "registration" (default), "participant" or "platform_fee"
 }
 
 // PaymentChannelFee represents the fee details for a Tripay channel
@@ -121,3 +127,18 @@ type RegisterEventRequest struct {
 	Category     string  `json:"category" binding:"required"`
 	BowType      string  `json:"bow_type" binding:"required"`
 }
+
+// PaymentEvent is an append-only ledger entry recording every status signal
+// received for a payment transaction (Tripay callbacks, reconciliation runs)
+type PaymentEvent struct {
+	UUID          string          `json:"id" db:"uuid"`
+	TransactionID string          `json:"transaction_id" db:"transaction_id"`
+	Source        string          `json:"source" db:"source"`               // callback, reconcile, manual
+	TripayStatus  *string         `json:"tripay_status" db:"tripay_status"` // UNPAID, PAID, EXPIRED, FAILED, REFUND
+	FromStatus    string          `json:"from_status" db:"from_status"`
+	ToStatus      string          `json:"to_status" db:"to_status"`
+	Outcome       string          `json:"outcome" db:"outcome"`             // applied, duplicate, rejected
+	PayloadHash   string          `json:"payload_hash" db:"payload_hash"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
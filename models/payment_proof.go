@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// PaymentProofReview records an organizer's decision on a manual payment proof
+type PaymentProofReview struct {
+	UUID           string    `json:"id" db:"uuid"`
+	EventID        string    `json:"event_id" db:"event_id"`
+	ParticipantID  string    `json:"participant_id" db:"participant_id"`
+	Decision       string    `json:"decision" db:"decision"` // approved, rejected
+	Reason         *string   `json:"reason" db:"reason"`
+	ExpectedAmount float64   `json:"expected_amount" db:"expected_amount"`
+	DeclaredAmount *float64  `json:"declared_amount" db:"declared_amount"`
+	ProofURLs      string    `json:"proof_urls" db:"proof_urls"`
+	ReviewedBy     string    `json:"reviewed_by" db:"reviewed_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// PaymentProofQueueItem is a participant waiting for their payment proof to be checked
+type PaymentProofQueueItem struct {
+	ParticipantID  string    `json:"participant_id" db:"participant_id"`
+	ArcherID       string    `json:"archer_id" db:"archer_id"`
+	ArcherName     string    `json:"archer_name" db:"archer_name"`
+	CategoryID     string    `json:"category_id" db:"category_id"`
+	CategoryName   string    `json:"category_name" db:"category_name"`
+	ExpectedAmount float64   `json:"expected_amount" db:"expected_amount"`
+	DeclaredAmount *float64  `json:"declared_amount" db:"declared_amount"`
+	ProofURLsRaw   string    `json:"-" db:"proof_urls"`
+	ProofURLs      []string  `json:"proof_urls" db:"-"`
+	AmountMatches  bool      `json:"amount_matches" db:"-"`
+	SubmittedAt    time.Time `json:"submitted_at" db:"submitted_at"`
+}
+
+// ReviewPaymentProofRequest approves or rejects one or more payment proofs
+type ReviewPaymentProofRequest struct {
+	ParticipantIDs []string `json:"participant_ids"`
+	Decision       string   `json:"decision" binding:"required,oneof=approved rejected"`
+	Reason         string   `json:"reason"`
+}
+
+// SubmitPaymentProofRequest is an archer's (re)submission of a manual transfer proof
+type SubmitPaymentProofRequest struct {
+	PaymentProofURLs []string `json:"payment_proof_urls" binding:"required,min=1"`
+	DeclaredAmount   *float64 `json:"declared_amount"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// EventPrice overrides the event entry fee, optionally per category, with an early-bird price
+// that applies until EarlyBirdUntil. A nil CategoryID applies to the whole event.
+type EventPrice struct {
+	UUID           string     `json:"id" db:"uuid"`
+	EventID        string     `json:"event_id" db:"event_id"`
+	CategoryID     *string    `json:"category_id" db:"category_id"`
+	EntryFee       float64    `json:"entry_fee" db:"entry_fee"`
+	EarlyBirdFee   *float64   `json:"early_bird_fee" db:"early_bird_fee"`
+	EarlyBirdUntil *time.Time `json:"early_bird_until" db:"early_bird_until"`
+}
+
+// EventClubDiscount gives members of a club a discount on the entry fee of an event
+type EventClubDiscount struct {
+	UUID          string  `json:"id" db:"uuid"`
+	EventID       string  `json:"event_id" db:"event_id"`
+	ClubID        string  `json:"club_id" db:"club_id"`
+	DiscountType  string  `json:"discount_type" db:"discount_type"` // percent, fixed
+	DiscountValue float64 `json:"discount_value" db:"discount_value"`
+}
+
+// PromoCode is a discount code for event entries
+type PromoCode struct {
+	UUID          string     `json:"id" db:"uuid"`
+	EventID       string     `json:"event_id" db:"event_id"`
+	Code          string     `json:"code" db:"code"`
+	DiscountType  string     `json:"discount_type" db:"discount_type"` // percent, fixed
+	DiscountValue float64    `json:"discount_value" db:"discount_value"`
+	MaxUses       *int       `json:"max_uses" db:"max_uses"`
+	UsedCount     int        `json:"used_count" db:"used_count"`
+	ValidFrom     *time.Time `json:"valid_from" db:"valid_from"`
+	ValidUntil    *time.Time `json:"valid_until" db:"valid_until"`
+	CategoryID    *string    `json:"category_id" db:"category_id"`
+	Status        string     `json:"status" db:"status"` // active, inactive
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// PriceLine is one step of a price calculation
+type PriceLine struct {
+	Label  string  `json:"label"`
+	Amount float64 `json:"amount"`
+}
+
+// PriceQuote is the entry price of a category for an archer at a point in time
+type PriceQuote struct {
+	BaseFee        float64     `json:"base_fee"`
+	EarlyBird      bool        `json:"early_bird"`
+	DiscountAmount float64     `json:"discount_amount"`
+	PromoCodeID    *string     `json:"promo_code_id"`
+	Total          float64     `json:"total"`
+	Lines          []PriceLine `json:"lines"`
+}
+
+// EventPricingRequest replaces the price table and club discounts of an event
+type EventPricingRequest struct {
+	Prices []struct {
+		CategoryID     *string       `json:"category_id"`
+		EntryFee       float64       `json:"entry_fee" binding:"min=0"`
+		EarlyBirdFee   *float64      `json:"early_bird_fee"`
+		EarlyBirdUntil *FlexibleTime `json:"early_bird_until"`
+	} `json:"prices"`
+	ClubDiscounts []struct {
+		ClubID        string  `json:"club_id" binding:"required"`
+		DiscountType  string  `json:"discount_type" binding:"required,oneof=percent fixed"`
+		DiscountValue float64 `json:"discount_value" binding:"min=0"`
+	} `json:"club_discounts"`
+}
+
+// PromoCodeRequest is the payload to create or update a promo code
+type PromoCodeRequest struct {
+	Code          string        `json:"code" binding:"required"`
+	DiscountType  string        `json:"discount_type" binding:"required,oneof=percent fixed"`
+	DiscountValue float64       `json:"discount_value" binding:"min=0"`
+	MaxUses       *int          `json:"max_uses"`
+	ValidFrom     *FlexibleTime `json:"valid_from"`
+	ValidUntil    *FlexibleTime `json:"valid_until"`
+	CategoryID    *string       `json:"category_id"`
+	Status        string        `json:"status" binding:"omitempty,oneof=active inactive"`
+}
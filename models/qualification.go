@@ -13,6 +13,7 @@ type QualificationSession struct {
 	EndTime      *time.Time `json:"end_time" db:"end_time"`
 	TotalEnds    int        `json:"total_ends" db:"total_ends"`
 	ArrowsPerEnd int        `json:"arrows_per_end" db:"arrows_per_end"`
+	Distance     *int       `json:"distance" db:"distance"` // meters
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 }
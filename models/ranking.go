@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// RankingResult is the ranking points an archer earned from one event category
+type RankingResult struct {
+	UUID             string    `json:"id" db:"uuid"`
+	ArcherID         string    `json:"archer_id" db:"archer_id"`
+	EventID          string    `json:"event_id" db:"event_id"`
+	CategoryID       string    `json:"category_id" db:"category_id"`
+	BowTypeID        string    `json:"bow_type_id" db:"bow_type_id"`
+	GenderDivisionID *string   `json:"gender_division_id" db:"gender_division_id"`
+	AgeGroupID       string    `json:"age_group_id" db:"age_group_id"`
+	Tier             string    `json:"tier" db:"tier"`
+	Place            int       `json:"place" db:"place"`
+	Points           float64   `json:"points" db:"points"`
+	EventDate        time.Time `json:"event_date" db:"event_date"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// RankingEntry is an archer's position in a ranking list
+type RankingEntry struct {
+	Rank             int     `json:"rank" db:"rank_position"`
+	ArcherID         string  `json:"archer_id" db:"archer_id"`
+	ArcherName       string  `json:"archer_name" db:"archer_name"`
+	AvatarURL        *string `json:"avatar_url" db:"avatar_url"`
+	ClubName         *string `json:"club_name" db:"club_name"`
+	BowTypeID        string  `json:"bow_type_id" db:"bow_type_id"`
+	GenderDivisionID *string `json:"gender_division_id" db:"gender_division_id"`
+	AgeGroupID       string  `json:"age_group_id" db:"age_group_id"`
+	Points           float64 `json:"points" db:"points"`
+	ResultsCounted   int     `json:"results_counted" db:"results_counted"`
+	PreviousRank     *int    `json:"previous_rank" db:"-"`
+	Movement         *int    `json:"movement" db:"-"` // positive when the archer moved up since the last snapshot
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// RefundPolicyRule gives the share of the entry fee refunded when a registration is
+// cancelled at least DaysBefore days before the event starts
+type RefundPolicyRule struct {
+	UUID          string    `json:"id" db:"uuid"`
+	EventID       string    `json:"event_id" db:"event_id"`
+	DaysBefore    int       `json:"days_before" db:"days_before"`
+	RefundPercent float64   `json:"refund_percent" db:"refund_percent"` // 100 = full, 0 = none
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// Refund is a refund request against a paid registration
+type Refund struct {
+	UUID            string     `json:"id" db:"uuid"`
+	EventID         string     `json:"event_id" db:"event_id"`
+	ArcherID        string     `json:"archer_id" db:"archer_id"`
+	ParticipantID   string     `json:"participant_id" db:"participant_id"`
+	CategoryID      *string    `json:"category_id" db:"category_id"` // category of the entry when the refund was made
+	TransactionID   *string    `json:"transaction_id" db:"transaction_id"`
+	Reason          string     `json:"reason" db:"reason"` // participant_cancelled, event_cancelled
+	PaidAmount      float64    `json:"paid_amount" db:"paid_amount"`
+	PolicyPercent   float64    `json:"policy_percent" db:"policy_percent"`
+	RefundAmount    float64    `json:"refund_amount" db:"refund_amount"`
+	Status          string     `json:"status" db:"status"`               // requested, approved, rejected, paid, credited
+	PayoutMethod    *string    `json:"payout_method" db:"payout_method"` // bank_transfer, credit_note
+	PayoutReference *string    `json:"payout_reference" db:"payout_reference"`
+	BankName        *string    `json:"bank_name" db:"bank_name"`
+	BankAccountName *string    `json:"bank_account_name" db:"bank_account_name"`
+	BankAccountNo   *string    `json:"bank_account_number" db:"bank_account_number"`
+	ReviewNote      *string    `json:"review_note" db:"review_note"`
+	ProcessedBy     *string    `json:"processed_by" db:"processed_by"`
+	ProcessedAt     *time.Time `json:"processed_at" db:"processed_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CreditNote is store credit issued to an archer in place of a cash refund
+type CreditNote struct {
+	UUID      string     `json:"id" db:"uuid"`
+	Code      string     `json:"code" db:"code"`
+	ArcherID  string     `json:"archer_id" db:"archer_id"`
+	RefundID  *string    `json:"refund_id" db:"refund_id"`
+	Amount    float64    `json:"amount" db:"amount"`
+	Balance   float64    `json:"balance" db:"balance"`
+	Status    string     `json:"status" db:"status"` // active, used, expired
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RefundPolicyRequest replaces the refund policy of an event
+type RefundPolicyRequest struct {
+	Rules []struct {
+		DaysBefore    int     `json:"days_before" binding:"min=0"`
+		RefundPercent float64 `json:"refund_percent" binding:"min=0,max=100"`
+	} `json:"rules"`
+}
+
+// ApproveRefundRequest is the organizer's decision on a refund request
+type ApproveRefundRequest struct {
+	RefundAmount *float64 `json:"refund_amount"`
+	PayoutMethod string   `json:"payout_method" binding:"required,oneof=bank_transfer credit_note"`
+	Note         *string  `json:"note"`
+}
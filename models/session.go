@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// AuthSession is a login on one device. Access tokens carry its id and stop working as soon as
+// the session is revoked; its refresh tokens rotate on every use.
+type AuthSession struct {
+	UUID           string     `json:"id" db:"uuid"`
+	IdentityID     *string    `json:"-" db:"identity_id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	UserType       string     `json:"user_type" db:"user_type"`
+	Role           string     `json:"role" db:"role"`
+	Email          string     `json:"-" db:"email"`
+	Name           string     `json:"-" db:"name"`
+	Avatar         string     `json:"-" db:"avatar"`
+	TwoFactor      bool       `json:"two_factor" db:"two_factor"`           // signed in with a second factor
+	ImpersonatedBy *string    `json:"impersonated_by" db:"impersonated_by"` // admin running a support session
+	UserAgent      string     `json:"user_agent" db:"user_agent"`
+	IPAddress      string     `json:"ip_address" db:"ip_address"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt     time.Time  `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at" db:"revoked_at"`
+	RevokeReason   *string    `json:"revoke_reason" db:"revoke_reason"` // logout, logout_all, refresh_token_reuse, admin, suspended, profile_switch, password_changed
+	Current        bool       `json:"current" db:"-"`                   // the session making the request
+}
+
+// RefreshTokenRequest carries a refresh token when it isn't sent as a cookie
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LoginAttempt is one password or Google login attempt, kept for the account's security history
+type LoginAttempt struct {
+	UUID      string    `json:"id" db:"uuid"`
+	Success   bool      `json:"success" db:"success"`
+	Reason    string    `json:"reason" db:"reason"` // invalid_credentials, locked, password, google, two_factor, ...
+	IPAddress string    `json:"ip_address" db:"ip_address"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// SettlementTotals sums the money flows of an event. Net is what the organizer should
+// receive: gross entry fees minus merchant fees and refunds. The platform fee is paid by the
+// organizer separately before publishing and is listed for reference only.
+// Customer fees are paid by archers on top of the entry fee and are shown for reconciliation only.
+type SettlementTotals struct {
+	Entries     int     `json:"entries"`
+	GrossAmount float64 `json:"gross_amount"`
+	FeeMerchant float64 `json:"fee_merchant"`
+	FeeCustomer float64 `json:"fee_customer"`
+	Refunds     float64 `json:"refunds"`
+	PlatformFee float64 `json:"platform_fee"`
+	NetAmount   float64 `json:"net_amount"`
+}
+
+// SettlementBreakdown is one row of a settlement report grouped by channel or category
+type SettlementBreakdown struct {
+	Key         string  `json:"key"`
+	Label       string  `json:"label"`
+	Entries     int     `json:"entries"`
+	GrossAmount float64 `json:"gross_amount"`
+	FeeMerchant float64 `json:"fee_merchant"`
+	FeeCustomer float64 `json:"fee_customer"`
+	Refunds     float64 `json:"refunds"`
+	NetAmount   float64 `json:"net_amount"`
+}
+
+// SettlementReport is the financial summary of an event for its organizer
+type SettlementReport struct {
+	EventID     string                `json:"event_id"`
+	EventName   string                `json:"event_name"`
+	Totals      SettlementTotals      `json:"totals"`
+	ByChannel   []SettlementBreakdown `json:"by_channel"`
+	ByCategory  []SettlementBreakdown `json:"by_category"`
+	GeneratedAt string                `json:"generated_at"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// TrainingSession is a scheduled club practice
+type TrainingSession struct {
+	UUID        string    `json:"id" db:"uuid"`
+	ClubID      string    `json:"club_id" db:"club_id"`
+	Title       string    `json:"title" db:"title"`
+	SessionDate string    `json:"session_date" db:"session_date"`
+	StartTime   *string   `json:"start_time" db:"start_time"`
+	EndTime     *string   `json:"end_time" db:"end_time"`
+	Location    *string   `json:"location" db:"location"`
+	Notes       *string   `json:"notes" db:"notes"`
+	CreatedBy   string    `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TrainingAttendance is an archer's check-in at a training session
+type TrainingAttendance struct {
+	UUID        string    `json:"id" db:"uuid"`
+	SessionID   string    `json:"session_id" db:"session_id"`
+	ArcherID    string    `json:"archer_id" db:"archer_id"`
+	Method      string    `json:"method" db:"method"` // qr, manual
+	CheckedInBy string    `json:"checked_in_by" db:"checked_in_by"`
+	CheckedInAt time.Time `json:"checked_in_at" db:"checked_in_at"`
+}
+
+// TrainingSessionRequest creates or updates a training session
+type TrainingSessionRequest struct {
+	Title       string  `json:"title" binding:"required"`
+	SessionDate string  `json:"session_date" binding:"required"` // YYYY-MM-DD
+	StartTime   *string `json:"start_time"`
+	EndTime     *string `json:"end_time"`
+	Location    *string `json:"location"`
+	Notes       *string `json:"notes"`
+}
+
+// TrainingCheckInRequest checks an archer in by membership QR code or archer ID
+type TrainingCheckInRequest struct {
+	QRRaw    string `json:"qr_raw"`
+	ArcherID string `json:"archer_id"`
+}
+
+// PracticeRound groups the practice ends an archer shot in one sitting
+type PracticeRound struct {
+	UUID              string    `json:"id" db:"uuid"`
+	ArcherID          string    `json:"archer_id" db:"archer_id"`
+	ClubID            *string   `json:"club_id" db:"club_id"`
+	TrainingSessionID *string   `json:"training_session_id" db:"training_session_id"`
+	PracticeDate      string    `json:"practice_date" db:"practice_date"`
+	Distance          *int      `json:"distance" db:"distance"` // meters
+	ArrowsPerEnd      int       `json:"arrows_per_end" db:"arrows_per_end"`
+	Notes             *string   `json:"notes" db:"notes"`
+	TotalScore        int       `json:"total_score" db:"total_score"`
+	TotalX            int       `json:"total_x" db:"total_x"`
+	Ends              int       `json:"ends" db:"ends"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+// PracticeRoundRequest starts a practice round
+type PracticeRoundRequest struct {
+	PracticeDate      string  `json:"practice_date"` // YYYY-MM-DD, defaults to today
+	TrainingSessionID *string `json:"training_session_id"`
+	Distance          *int    `json:"distance"`
+	ArrowsPerEnd      int     `json:"arrows_per_end"`
+	Notes             *string `json:"notes"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// TwoFactorCodeRequest carries a code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorChallengeRequest completes a login that needs a second factor, with either a code from
+// the authenticator app or one of the recovery codes
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorDisableRequest turns 2FA off; it takes the password and a current second factor
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// EventTwoFactorRequest sets whether scoring staff of an event must have signed in with 2FA
+type EventTwoFactorRequest struct {
+	RequireTwoFactor *bool `json:"require_two_factor" binding:"required"`
+}
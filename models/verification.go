@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Verification statuses of clubs and organizations. Accounts that never submitted have none.
+const (
+	VerificationSubmitted   = "submitted"
+	VerificationUnderReview = "under_review"
+	VerificationVerified    = "verified"
+	VerificationRejected    = "rejected"
+)
+
+// VerificationSubmission is one set of documents a club or organization sent in for verification
+type VerificationSubmission struct {
+	UUID          string                 `json:"id" db:"uuid"`
+	EntityType    string                 `json:"entity_type" db:"entity_type"` // club, organization
+	EntityID      string                 `json:"entity_id" db:"entity_id"`
+	Status        string                 `json:"status" db:"status"`
+	Note          *string                `json:"note" db:"note"`
+	ReviewerID    *string                `json:"reviewer_id" db:"reviewer_id"`
+	ReviewComment *string                `json:"review_comment" db:"review_comment"`
+	SubmittedAt   time.Time              `json:"submitted_at" db:"submitted_at"`
+	ReviewedAt    *time.Time             `json:"reviewed_at" db:"reviewed_at"`
+	Documents     []VerificationDocument `json:"documents" db:"-"`
+	Reviews       []VerificationReview   `json:"reviews" db:"-"`
+}
+
+// VerificationDocument is an uploaded file attached to a verification submission
+type VerificationDocument struct {
+	UUID         string    `json:"id" db:"uuid"`
+	SubmissionID string    `json:"submission_id" db:"submission_id"`
+	DocumentType string    `json:"document_type" db:"document_type"` // registration_certificate, federation_letter, other
+	MediaID      string    `json:"media_id" db:"media_id"`
+	URL          string    `json:"url" db:"url"`
+	MimeType     *string   `json:"mime_type" db:"mime_type"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// VerificationReview is a reviewer's step on a submission: taking it into review, or the decision
+type VerificationReview struct {
+	UUID         string    `json:"id" db:"uuid"`
+	SubmissionID *string   `json:"submission_id" db:"submission_id"`
+	EntityType   string    `json:"entity_type" db:"entity_type"` // club, organization
+	EntityID     string    `json:"entity_id" db:"entity_id"`
+	Status       string    `json:"status" db:"status"` // under_review, verified, rejected
+	Note         *string   `json:"note" db:"note"`
+	ReviewedBy   string    `json:"reviewed_by" db:"reviewed_by"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// VerificationDocumentInput attaches a file uploaded through /media/upload to a submission
+type VerificationDocumentInput struct {
+	DocumentType string `json:"document_type" binding:"required,oneof=registration_certificate federation_letter other"`
+	MediaID      string `json:"media_id" binding:"required"`
+}
+
+// SubmitVerificationRequest sends a club's or organization's documents in for verification
+type SubmitVerificationRequest struct {
+	Documents []VerificationDocumentInput `json:"documents" binding:"required,min=1,max=10,dive"`
+	Note      string                      `json:"note" binding:"max=1000"`
+}
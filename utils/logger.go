@@ -1,23 +1,72 @@
 package utils
 
 import (
+	"encoding/json"
+	"log"
+
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
-// LogActivity inserts a record into the activity_logs table
-func LogActivity(db *sqlx.DB, userID, eventID, action, entityType, entityID, description, ipAddress, userAgent string) {
-	logID := uuid.New().String()
-	query := `
-		INSERT INTO activity_logs (id, user_id, event_id, action, entity_type, entity_id, description, ip_address, user_agent)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	// Use eventID if provided, otherwise it can be empty/NULL
+// Activity is an entry of the activity log. Before and After are snapshots of the changed
+// entity, stored as JSON; leave them nil when there is nothing to show.
+type Activity struct {
+	UserID      string
+	EventID     string
+	Action      string
+	EntityType  string
+	EntityID    string
+	Description string
+	Before      interface{}
+	After       interface{}
+	IPAddress   string
+	UserAgent   string
+}
+
+// activityJSON encodes a before/after payload, or returns nil to store NULL
+func activityJSON(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil
+	}
+	return string(b)
+}
+
+// RecordActivity inserts a record into the activity_logs table. A failed insert is logged but
+// never fails the request that caused it.
+func RecordActivity(db *sqlx.DB, a Activity) {
+	// Use EventID if provided, otherwise it can be empty/NULL
 	var eID interface{}
-	eID = eventID
-	if eventID == "" {
-		eID = nil
+	if a.EventID != "" {
+		eID = a.EventID
+	}
+	if len(a.UserAgent) > 255 {
+		a.UserAgent = a.UserAgent[:255]
 	}
 
-	db.Exec(query, logID, userID, eID, action, entityType, entityID, description, ipAddress, userAgent)
+	_, err := db.Exec(`
+		INSERT INTO activity_logs (id, user_id, event_id, action, entity_type, entity_id, description, before_data, after_data, ip_address, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), a.UserID, eID, a.Action, a.EntityType, a.EntityID, a.Description,
+		activityJSON(a.Before), activityJSON(a.After), a.IPAddress, a.UserAgent)
+	if err != nil {
+		log.Printf("[activity] failed to record %s on %s %s: %v", a.Action, a.EntityType, a.EntityID, err)
+	}
+}
+
+// LogActivity inserts a record into the activity_logs table
+func LogActivity(db *sqlx.DB, userID, eventID, action, entityType, entityID, description, ipAddress, userAgent string) {
+	RecordActivity(db, Activity{
+		UserID:      userID,
+		EventID:     eventID,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Description: description,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+	})
 }
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Mail is a plain-text email
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(m Mail) error
+}
+
+// SMTPMailer sends mail through an SMTP server
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the mail over SMTP with PLAIN auth when a username is set
+func (s *SMTPMailer) Send(m Mail) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(s.Host+":"+s.Port, auth, s.From, []string{m.To}, formatMail(s.From, m))
+}
+
+// FileOutboxMailer writes each mail as an .eml file into Dir instead of sending it
+type FileOutboxMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes the mail to the outbox directory
+func (f *FileOutboxMailer) Send(m Mail) error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), uuid.New().String()[:8])
+	return os.WriteFile(filepath.Join(f.Dir, name), formatMail(f.From, m), 0644)
+}
+
+// DBOutboxMailer stores mail in the mail_outbox table instead of sending it
+type DBOutboxMailer struct {
+	DB   *sqlx.DB
+	From string
+}
+
+// Send records the mail in the outbox table
+func (d *DBOutboxMailer) Send(m Mail) error {
+	_, err := d.DB.Exec(`
+		INSERT INTO mail_outbox (uuid, from_address, to_address, subject, body)
+		VALUES (?, ?, ?, ?, ?)
+	`, uuid.New().String(), d.From, m.To, m.Subject, m.Body)
+	return err
+}
+
+// formatMail renders a mail as an RFC 822 message
+func formatMail(from string, m Mail) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + m.To + "\r\n")
+	b.WriteString("Subject: " + m.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+var (
+	mailerMu sync.RWMutex
+	mailer   Mailer
+)
+
+// NewMailerFromEnv picks the mailer from MAIL_DRIVER: smtp, file (MAIL_OUTBOX_DIR, default
+// ./data/outbox) or db. Without MAIL_DRIVER, SMTP is used when SMTP_HOST is set and the
+// database outbox otherwise.
+func NewMailerFromEnv(db *sqlx.DB) Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Archery Hub <no-reply@archeryhub.id>"
+	}
+
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" {
+		driver = "db"
+		if os.Getenv("SMTP_HOST") != "" {
+			driver = "smtp"
+		}
+	}
+
+	switch driver {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = "./data/outbox"
+		}
+		return &FileOutboxMailer{Dir: dir, From: from}
+	default:
+		return &DBOutboxMailer{DB: db, From: from}
+	}
+}
+
+// SetMailer installs the mailer used by SendMail
+func SetMailer(m Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	mailer = m
+}
+
+// SendMail delivers a mail through the installed mailer
+func SendMail(m Mail) error {
+	mailerMu.RLock()
+	current := mailer
+	mailerMu.RUnlock()
+
+	if current == nil {
+		return fmt.Errorf("no mailer configured")
+	}
+	if err := current.Send(m); err != nil {
+		log.Printf("[mailer] failed to send %q to %s: %v", m.Subject, m.To, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+// Notify inserts a record into the notifications table for a user
+func Notify(db *sqlx.DB, userID, userRole, notifType, title, message, link string) {
+	var l interface{}
+	l = link
+	if link == "" {
+		l = nil
+	}
+
+	db.Exec(`
+		INSERT INTO notifications (user_id, user_role, type, title, message, link)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, userRole, notifType, title, message, l)
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Attempt is the failure count kept for one throttled key (an account, an IP address)
+type Attempt struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// AttemptStore keeps attempt counters. Entries expire after the ttl they were set with, so a quiet
+// key starts over. The in-memory store suits a single instance; several instances behind a load
+// balancer need a shared implementation.
+type AttemptStore interface {
+	Get(key string) Attempt
+	Set(key string, a Attempt, ttl time.Duration)
+	// Incr adds one failure to the key in a single step, so concurrent failures all count.
+	// lockFor maps the new failure count to how long the key must wait (zero for no wait).
+	Incr(key string, ttl time.Duration, lockFor func(failures int) time.Duration) Attempt
+	Delete(key string)
+}
+
+type memoryAttempt struct {
+	attempt   Attempt
+	expiresAt time.Time
+}
+
+// MemoryAttemptStore keeps attempt counters in process memory
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryAttempt
+	lastPrune time.Time
+}
+
+// NewMemoryAttemptStore creates an empty in-memory store
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: make(map[string]memoryAttempt), lastPrune: time.Now()}
+}
+
+// Get returns the counter of the key, or a zero Attempt when there's none
+func (s *MemoryAttemptStore) Get(key string) Attempt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return Attempt{}
+	}
+	return e.attempt
+}
+
+// Set stores the counter of the key until the ttl passes
+func (s *MemoryAttemptStore) Set(key string, a Attempt, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	if a.LockedUntil.After(expiresAt) {
+		expiresAt = a.LockedUntil
+	}
+	s.entries[key] = memoryAttempt{attempt: a, expiresAt: expiresAt}
+	s.prune(now)
+}
+
+// prune drops expired keys now and then so scanning IPs don't grow the map forever.
+// The caller holds the lock.
+func (s *MemoryAttemptStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) <= time.Minute {
+		return
+	}
+	for k, e := range s.entries {
+		if now.After(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.lastPrune = now
+}
+
+// Incr counts one more failure against the key and returns the updated counter
+func (s *MemoryAttemptStore) Incr(key string, ttl time.Duration, lockFor func(failures int) time.Duration) Attempt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	a := Attempt{}
+	if e, ok := s.entries[key]; ok && !now.After(e.expiresAt) {
+		a = e.attempt
+	}
+	a.Failures++
+	if d := lockFor(a.Failures); d > 0 {
+		a.LockedUntil = now.Add(d)
+	}
+
+	expiresAt := now.Add(ttl)
+	if a.LockedUntil.After(expiresAt) {
+		expiresAt = a.LockedUntil
+	}
+	s.entries[key] = memoryAttempt{attempt: a, expiresAt: expiresAt}
+	s.prune(now)
+	return a
+}
+
+// Delete forgets the key
+func (s *MemoryAttemptStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+var (
+	attemptStoreMu sync.RWMutex
+	attemptStore   AttemptStore = NewMemoryAttemptStore()
+)
+
+// SetAttemptStore replaces the store used for login throttling
+func SetAttemptStore(s AttemptStore) {
+	attemptStoreMu.Lock()
+	defer attemptStoreMu.Unlock()
+	attemptStore = s
+}
+
+// Attempts returns the installed attempt store
+func Attempts() AttemptStore {
+	attemptStoreMu.RLock()
+	defer attemptStoreMu.RUnlock()
+	return attemptStore
+}